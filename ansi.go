@@ -0,0 +1,19 @@
+package logstash
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ansiSequence matches ANSI escape sequences: CSI sequences such as colors
+// and cursor movements, OSC sequences such as window titles, and the other
+// two byte escapes.
+var ansiSequence = regexp.MustCompile(`\x1b(?:\[[0-?]*[ -/]*[@-~]|\][^\x07\x1b]*(?:\x07|\x1b\\)|[@-Z\\-_])`)
+
+// stripANSI removes ANSI escape sequences from a line.
+func stripANSI(line string) string {
+	if strings.IndexByte(line, '\x1b') < 0 {
+		return line
+	}
+	return ansiSequence.ReplaceAllString(line, "")
+}
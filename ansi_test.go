@@ -0,0 +1,23 @@
+package logstash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripANSI(t *testing.T) {
+	assert := assert.New(t)
+
+	for line, expected := range map[string]string{
+		"plain":                                    "plain",
+		"\x1b[32mINFO\x1b[0m started":              "INFO started",
+		"\x1b[1;31mERROR\x1b[39;49m failed":        "ERROR failed",
+		"\x1b[2K\x1b[1Gprogress 50%":               "progress 50%",
+		"\x1b]0;title\x07done":                     "done",
+		"\x1b]8;;http://x\x1b\\link\x1b]8;;\x1b\\": "link",
+		"\x1bMreverse index":                       "reverse index",
+	} {
+		assert.Equal(expected, stripANSI(line), line)
+	}
+}
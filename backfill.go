@@ -0,0 +1,135 @@
+package logstash
+
+import (
+	"bytes"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+)
+
+// historyWriter parses the lines Docker writes with timestamps into the
+// messages of a container's stream, keeping the most recent ones logged
+// before until, of at most max bytes.
+type historyWriter struct {
+	container *docker.Container
+	source    string
+	until     time.Time
+	max       int
+	messages  []*router.Message
+	size      int
+	partial   []byte
+}
+
+func (w *historyWriter) Write(p []byte) (int, error) {
+	data := append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		w.add(string(data[:i]))
+		data = data[i+1:]
+	}
+	w.partial = append(w.partial[:0], data...)
+	return len(p), nil
+}
+
+// add keeps a line if it was logged before until, dropping the oldest lines
+// beyond max bytes.
+func (w *historyWriter) add(line string) {
+	i := strings.IndexByte(line, ' ')
+	if i < 0 {
+		return
+	}
+	t, err := time.Parse(time.RFC3339Nano, line[:i])
+	if err != nil || !t.Before(w.until) {
+		return
+	}
+	m := &router.Message{Container: w.container, Source: w.source, Data: line[i+1:], Time: t}
+	w.messages = append(w.messages, m)
+	w.size += len(m.Data) + 1
+	for w.size > w.max {
+		w.size -= len(w.messages[0].Data) + 1
+		w.messages = w.messages[1:]
+	}
+}
+
+// cut drops the lines logged at or after t.
+func (w *historyWriter) cut(t time.Time) {
+	for i, m := range w.messages {
+		if !m.Time.Before(t) {
+			for _, dropped := range w.messages[i:] {
+				w.size -= len(dropped.Data) + 1
+			}
+			w.messages = w.messages[:i]
+			return
+		}
+	}
+}
+
+// maxHeldLines is the number of lines of a container held back while its
+// history is read, beyond which they are shipped without waiting for it.
+const maxHeldLines = 10000
+
+// backfillResult is the history of a container.
+type backfillResult struct {
+	container string
+	messages  []*router.Message
+}
+
+// backfill returns the lines the container of first logged before first,
+// the first line the adapter got, at most backfillAge before it and of at
+// most backfillBytes bytes in all, the most recent ones, in the order they
+// were logged. They fill the gap between the start of the container, or the
+// last time logspout ran, and first.
+func (a *LogstashAdapter) backfill(first *router.Message) []*router.Message {
+	c, until := first.Container, first.Time
+	if until.IsZero() {
+		until = time.Now()
+	}
+	stdout := &historyWriter{container: c, source: "stdout", until: until, max: a.backfillBytes}
+	stderr := &historyWriter{container: c, source: "stderr", until: until, max: a.backfillBytes}
+	err := a.history.Logs(docker.LogsOptions{
+		Container:    c.ID,
+		OutputStream: stdout,
+		ErrorStream:  stderr,
+		Since:        until.Add(-a.backfillAge).Unix(),
+		Stdout:       true,
+		Stderr:       true,
+		Timestamps:   true,
+	})
+	if err != nil {
+		log.Println("logstash: could not backfill logs of container", c.ID+":", err)
+		return nil
+	}
+
+	// first was read after Docker logged it, so the history has it too,
+	// with an earlier timestamp. It and the lines after it already reach
+	// the adapter live.
+	live := stdout
+	if first.Source == "stderr" {
+		live = stderr
+	}
+	for i := len(live.messages) - 1; i >= 0; i-- {
+		if live.messages[i].Data == first.Data {
+			t := live.messages[i].Time
+			stdout.cut(t)
+			stderr.cut(t)
+			break
+		}
+	}
+
+	messages := append(stdout.messages, stderr.messages...)
+	sort.SliceStable(messages, func(i, j int) bool { return messages[i].Time.Before(messages[j].Time) })
+	size := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		if size += len(messages[i].Data) + 1; size > a.backfillBytes {
+			return messages[i+1:]
+		}
+	}
+	return messages
+}
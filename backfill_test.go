@@ -0,0 +1,116 @@
+package logstash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryWriter(t *testing.T) {
+	assert := assert.New(t)
+
+	until := time.Date(2016, 10, 20, 13, 25, 13, 0, time.UTC)
+	w := &historyWriter{source: "stdout", until: until, max: 16}
+	w.Write([]byte("2016-10-20T13:25:10Z first\n2016-10-20T13:25:11Z sec"))
+	w.Write([]byte("ond\nnot a line\n2016-10-20T13:25:12Z third\n"))
+	w.Write([]byte("2016-10-20T13:25:13Z live\n2016-10-20T13:25:12.5Z cut"))
+
+	var lines []string
+	for _, m := range w.messages {
+		assert.Equal("stdout", m.Source)
+		lines = append(lines, m.Data)
+	}
+	assert.Equal([]string{"second", "third"}, lines)
+	assert.Equal(13, w.size)
+
+	w.cut(time.Date(2016, 10, 20, 13, 25, 12, 0, time.UTC))
+	assert.Len(w.messages, 1)
+	assert.Equal(7, w.size)
+}
+
+func TestBackfill(t *testing.T) {
+	assert := assert.New(t)
+
+	until := time.Date(2016, 10, 20, 13, 25, 13, 0, time.UTC)
+	client := &MockDockerClient{logs: map[string][2]string{
+		"ID": {
+			"2016-10-20T13:25:10.000000000Z started\n" +
+				"2016-10-20T13:25:12.000000000Z GET / 200\n" +
+				"2016-10-20T13:25:13.000000000Z GET /cart 200\n" +
+				"2016-10-20T13:25:14.000000000Z GET /checkout 200\n",
+			"2016-10-20T13:25:11.500000000Z warning: cache cold\n",
+		},
+	}}
+	adapter := LogstashAdapter{history: client, backfillAge: time.Minute, backfillBytes: 1024}
+	container := &docker.Container{ID: "ID", Config: &docker.Config{}}
+	first := &router.Message{Container: container, Source: "stdout", Data: "GET /checkout 200", Time: until}
+
+	var lines []string
+	for _, m := range adapter.backfill(first) {
+		lines = append(lines, m.Source+": "+m.Data)
+	}
+	assert.Equal([]string{"stdout: started", "stderr: warning: cache cold", "stdout: GET / 200"}, lines)
+	assert.Equal(until.Add(-time.Minute).Unix(), client.since)
+
+	adapter.backfillBytes = 30
+	lines = nil
+	for _, m := range adapter.backfill(first) {
+		lines = append(lines, m.Data)
+	}
+	assert.Equal([]string{"warning: cache cold", "GET / 200"}, lines)
+
+	// The history has the first line, read after it was logged.
+	adapter.backfillBytes = 1024
+	first = &router.Message{Container: container, Source: "stdout", Data: "GET / 200", Time: until}
+	lines = nil
+	for _, m := range adapter.backfill(first) {
+		lines = append(lines, m.Data)
+	}
+	assert.Equal([]string{"started", "warning: cache cold"}, lines)
+
+	assert.Nil(adapter.backfill(&router.Message{Container: &docker.Container{ID: "gone"}, Time: until}))
+}
+
+func TestStreamWithBackfill(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	now := time.Now().UTC()
+	client := &MockDockerClient{logs: map[string][2]string{
+		"ID": {
+			now.Add(-2*time.Second).Format(time.RFC3339Nano) + " Exception in thread \"main\"\n" +
+				now.Add(-2*time.Second).Format(time.RFC3339Nano) + " \tat Main.main(Main.java:3)\n" +
+				now.Add(-100*time.Millisecond).Format(time.RFC3339Nano) + " live\n" +
+				now.Add(-50*time.Millisecond).Format(time.RFC3339Nano) + " next\n",
+			now.Add(-80*time.Millisecond).Format(time.RFC3339Nano) + " warning\n",
+		},
+	}}
+	adapter := LogstashAdapter{
+		route:         new(router.Route),
+		conn:          conn,
+		history:       client,
+		backfillAge:   time.Hour,
+		backfillBytes: 1024,
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Env: []string{`LOGSTASH_MULTILINE_PATTERN=^\s`}}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: "live", Source: "stdout", Time: now}
+		logstream <- &router.Message{Container: &container, Data: "next", Source: "stdout", Time: now}
+		time.Sleep(50 * time.Millisecond)
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	var messages []interface{}
+	for _, line := range conn.Lines() {
+		messages = append(messages, line["message"])
+	}
+	assert.Equal([]interface{}{"Exception in thread \"main\"\n\tat Main.main(Main.java:3)", "live", "next"}, messages)
+}
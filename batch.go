@@ -0,0 +1,46 @@
+package logstash
+
+import (
+	"net"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// packetBatchWriter sends several datagrams at once. On Linux the
+// golang.org/x/net implementations use sendmmsg, elsewhere they fall back to
+// one write per datagram.
+type packetBatchWriter interface {
+	WriteBatch(ms []ipv4.Message, flags int) (int, error)
+}
+
+// newPacketBatchWriter returns a batch writer for UDP connections, or nil if
+// conn is not a UDP socket.
+func newPacketBatchWriter(conn net.Conn) packetBatchWriter {
+	udp, ok := conn.(*net.UDPConn)
+	if !ok {
+		return nil
+	}
+	if addr, ok := udp.RemoteAddr().(*net.UDPAddr); ok && addr.IP.To4() == nil {
+		return ipv6.NewPacketConn(udp)
+	}
+	return ipv4.NewPacketConn(udp)
+}
+
+// writePacketBatch sends every buffer in batch as its own datagram, using as
+// few syscalls as the platform allows.
+func writePacketBatch(w packetBatchWriter, batch [][]byte) error {
+	ms := make([]ipv4.Message, len(batch))
+	for i, js := range batch {
+		ms[i].Buffers = [][]byte{js}
+	}
+
+	for len(ms) > 0 {
+		n, err := w.WriteBatch(ms, 0)
+		if err != nil {
+			return err
+		}
+		ms = ms[n:]
+	}
+	return nil
+}
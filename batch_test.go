@@ -0,0 +1,59 @@
+package logstash
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamBatchedUDP(t *testing.T) {
+	assert := assert.New(t)
+
+	server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Nil(err)
+	defer server.Close()
+
+	conn, err := net.DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	assert.Nil(err)
+	defer conn.Close()
+
+	adapter := LogstashAdapter{
+		route:         new(router.Route),
+		conn:          conn,
+		packets:       newPacketBatchWriter(conn),
+		batchSize:     3,
+		flushInterval: time.Hour,
+	}
+	assert.NotNil(adapter.packets)
+
+	containerConfig := docker.Config{}
+	container := docker.Container{}
+	container.ID = "ID"
+	container.Config = &containerConfig
+
+	logstream := make(chan *router.Message)
+	go func() {
+		for _, line := range []string{"one", "two", "three"} {
+			logstream <- &router.Message{Container: &container, Data: line, Time: time.Now()}
+		}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	buf := make([]byte, 65536)
+	server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for _, line := range []string{"one", "two", "three"} {
+		n, err := server.Read(buf)
+		assert.Nil(err)
+
+		var data map[string]interface{}
+		assert.Nil(json.Unmarshal(buf[:n], &data))
+		assert.Equal(line, data["message"])
+	}
+}
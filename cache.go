@@ -0,0 +1,323 @@
+package logstash
+
+import (
+	"log"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// containerMeta is everything the adapter derives from a container for its
+// events. It is built on the first message of a container and reused until
+// the container is restarted or renamed; everything else Docker fixes when a
+// container is created.
+type containerMeta struct {
+	name      string
+	startedAt time.Time
+	restarts  int
+
+	docker    DockerInfo
+	tags      []string
+	format    string
+	logType   string
+	marathon  *MarathonData
+	mesos     *MesosData
+	chronos   *ChronosData
+	dcos      *DCOSData
+	swarm     *SwarmData
+	compose   *ComposeData
+	rancher   *RancherData
+	nomad     *NomadData
+	ecs       *ECSData
+	env       map[string]string
+	imageMeta map[string]string
+	fields    map[string]string
+	metadata  map[string]string
+	parseTime timestampParser
+	extract   []*regexp.Regexp
+	levels    bool
+	multiline *multilineRule
+	redact    []*redactor
+	drop      []*regexp.Regexp
+	sample    int
+	limit     *rateLimiter
+	dedup     bool
+	priority  priority
+	blocks    map[string]interface{}
+
+	// excluded is set if the container's events are not shipped, and
+	// nothing else is then.
+	excluded bool
+
+	// used is set whenever the metadata is looked up, and cleared by every
+	// sweep of the cache.
+	used bool
+}
+
+// containerCacheSweep is how often what the adapter keeps per container is
+// swept. Containers that logged nothing since the sweep before are
+// forgotten, so containers that come and go do not pile up.
+const containerCacheSweep = 10 * time.Minute
+
+// current reports whether meta was built from the same run of c.
+func (meta *containerMeta) current(c *docker.Container) bool {
+	return meta.name == c.Name && meta.restarts == c.RestartCount && meta.startedAt.Equal(c.State.StartedAt)
+}
+
+// containerMeta returns the cached metadata of c, building it anew if c was
+// not seen before or has changed since.
+func (a *LogstashAdapter) containerMeta(c *docker.Container) *containerMeta {
+	if meta, ok := a.containers[c.ID]; ok && meta.current(c) {
+		meta.used = true
+		return meta
+	}
+
+	meta := &containerMeta{
+		name:      c.Name,
+		startedAt: c.State.StartedAt,
+		restarts:  c.RestartCount,
+		used:      true,
+	}
+	if meta.excluded = !a.shipped(c); meta.excluded {
+		return a.cacheMeta(c, meta)
+	}
+	dockerBlock := false
+	for _, e := range enrichers {
+		dockerBlock = dockerBlock || e.name == "docker"
+		if fill, ok := e.enricher.(metaEnricher); ok {
+			fill(a, c, meta)
+			continue
+		}
+		// Blocks of built-in enrichers that were replaced take the place
+		// of those of the adapter, even when there are none.
+		if block := e.enricher.Enrich(c); block != nil || builtinEnrichers[e.name] {
+			meta.setBlock(e.name, block)
+		}
+	}
+	if !dockerBlock {
+		meta.setBlock("docker", nil)
+	}
+	for _, enrich := range metaEnrichers {
+		enrich(a, c, meta)
+	}
+
+	return a.cacheMeta(c, meta)
+}
+
+// cacheMeta caches the metadata of c in place of any it had before.
+func (a *LogstashAdapter) cacheMeta(c *docker.Container, meta *containerMeta) *containerMeta {
+	if old, ok := a.containers[c.ID]; ok && meta.limit != nil && old.limit != nil {
+		meta.limit.carry(old.limit)
+	}
+	if a.containers == nil {
+		a.containers = make(map[string]*containerMeta)
+	}
+	a.containers[c.ID] = meta
+	return meta
+}
+
+// sequencer numbers the events of each container. The adapter shares it
+// with the children it fans out to, so that numbers neither restart when a
+// child is replaced nor repeat across the shards of an unordered pool.
+type sequencer struct {
+	mu   sync.Mutex
+	last map[string]uint64
+}
+
+// next returns the sequence number of the next event of the container with
+// the given ID, counting up from 1.
+func (s *sequencer) next(id string) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last == nil {
+		s.last = make(map[string]uint64)
+	}
+	s.last[id]++
+	return s.last[id]
+}
+
+// sweepContainers forgets the metadata of the containers that were not
+// looked up since the last sweep, but for those with suppressed events yet
+// to be reported.
+func (a *LogstashAdapter) sweepContainers() {
+	for id, meta := range a.containers {
+		if !meta.used && (meta.limit == nil || meta.limit.suppressed == 0) {
+			delete(a.containers, id)
+		}
+		meta.used = false
+	}
+}
+
+// containerFlags keeps a flag per container ID for as long as the container
+// logs, forgetting those not looked up for a whole containerCacheSweep.
+type containerFlags struct {
+	current, previous map[string]bool
+	swept             time.Time
+}
+
+// get returns the flag of the container with the given ID, from compute if
+// it is not known.
+func (f *containerFlags) get(id string, compute func() bool) bool {
+	if now := time.Now(); now.Sub(f.swept) >= containerCacheSweep {
+		f.previous, f.current, f.swept = f.current, make(map[string]bool), now
+	}
+	flag, ok := f.current[id]
+	if !ok {
+		if flag, ok = f.previous[id]; !ok {
+			flag = compute()
+		}
+		f.current[id] = flag
+	}
+	return flag
+}
+
+// setBlock sets the block of an enricher.
+func (meta *containerMeta) setBlock(name string, block interface{}) {
+	if meta.blocks == nil {
+		meta.blocks = make(map[string]interface{})
+	}
+	meta.blocks[name] = block
+}
+
+// metaEnricher fills in part of the metadata of a container.
+type metaEnricher func(a *LogstashAdapter, c *docker.Container, meta *containerMeta)
+
+// Enrich implements the Enricher interface. Built-in enrichers set the
+// metadata of the adapter instead of returning a block.
+func (f metaEnricher) Enrich(c *docker.Container) interface{} {
+	return nil
+}
+
+// builtinEnrichers are the names of the enrichers of the adapter's own
+// blocks.
+var builtinEnrichers = make(map[string]bool)
+
+// registerBuiltin registers an enricher of the adapter's own block name.
+func registerBuiltin(name string, fill metaEnricher) {
+	builtinEnrichers[name] = true
+	enrichers = append(enrichers, namedEnricher{name: name, enricher: fill})
+}
+
+func init() {
+	registerBuiltin("docker", enrichDocker)
+	registerBuiltin("tags", enrichTags)
+	registerBuiltin("marathon", func(a *LogstashAdapter, c *docker.Container, meta *containerMeta) {
+		meta.marathon = a.marathonData(c)
+	})
+	registerBuiltin("mesos", func(a *LogstashAdapter, c *docker.Container, meta *containerMeta) {
+		meta.mesos = a.mesosData(c)
+	})
+	registerBuiltin("chronos", func(a *LogstashAdapter, c *docker.Container, meta *containerMeta) {
+		meta.chronos = a.chronosData(c)
+	})
+	registerBuiltin("dcos", func(a *LogstashAdapter, c *docker.Container, meta *containerMeta) {
+		if a.dcosNode != nil {
+			meta.dcos = GetDCOSData(c, *a.dcosNode)
+		}
+	})
+	registerBuiltin("swarm", func(a *LogstashAdapter, c *docker.Container, meta *containerMeta) {
+		meta.swarm = GetSwarmData(c)
+	})
+	registerBuiltin("compose", func(a *LogstashAdapter, c *docker.Container, meta *containerMeta) {
+		meta.compose = GetComposeData(c)
+	})
+	registerBuiltin("rancher", func(a *LogstashAdapter, c *docker.Container, meta *containerMeta) {
+		meta.rancher = GetRancherData(c)
+	})
+	registerBuiltin("nomad", func(a *LogstashAdapter, c *docker.Container, meta *containerMeta) {
+		meta.nomad = GetNomadData(c)
+	})
+	registerBuiltin("ecs", func(a *LogstashAdapter, c *docker.Container, meta *containerMeta) {
+		meta.ecs = GetECSData(c)
+	})
+}
+
+// metaEnrichers derive how the adapter handles the events of containers,
+// in order, once the registered enrichers are done.
+var metaEnrichers = []metaEnricher{
+	enrichFields,
+	enrichDecoding,
+	enrichHints,
+}
+
+// enrichDocker sets the docker block.
+func enrichDocker(a *LogstashAdapter, c *docker.Container, meta *containerMeta) {
+	meta.docker = DockerInfo{
+		Name:        c.Name,
+		ID:          c.ID,
+		Image:       c.Config.Image,
+		Hostname:    c.Config.Hostname,
+		ImageID:     c.Image,
+		ImageDigest: a.imageDigest(c),
+		Labels:      a.dockerLabels(c),
+	}
+	meta.docker.Networks, meta.docker.IPAddresses = GetNetworkInfo(c)
+	meta.docker.Ports = GetPortMappings(c)
+	if a.command {
+		meta.docker.Entrypoint, meta.docker.Command = GetContainerCommand(c, a.commandRedact)
+	}
+	if !c.Created.IsZero() {
+		meta.docker.Created = c.Created.UTC().Format(time.RFC3339Nano)
+	}
+}
+
+// enrichTags sets the tags.
+func enrichTags(a *LogstashAdapter, c *docker.Container, meta *containerMeta) {
+	meta.tags = a.containerTags(c)
+}
+
+// enrichFields sets the environment, image metadata and static fields.
+func enrichFields(a *LogstashAdapter, c *docker.Container, meta *containerMeta) {
+	meta.env = a.containerEnvFields(c)
+	meta.imageMeta = GetImageMeta(c)
+	meta.fields = a.containerFields(c)
+}
+
+// enrichDecoding sets how the lines of the container are decoded, and
+// which of them are dropped, collapsed, sampled, rate limited or shed.
+func enrichDecoding(a *LogstashAdapter, c *docker.Container, meta *containerMeta) {
+	meta.format = a.containerFormat(c)
+	if a.parseTimestamps {
+		meta.parseTime = newTimestampParser(containerSetting(c, "LOGSTASH_TIMESTAMP_FORMAT", "logstash.timestamp_format"))
+	}
+	meta.extract = GetContainerExtractors(c)
+	meta.levels = containerBool(c, "LOGSTASH_DETECT_LEVEL", "logstash.detect_level", a.levels)
+	meta.multiline = GetMultilineRule(c)
+	meta.redact = a.containerRedactors(c)
+	meta.drop = a.containerDropRules(c)
+	meta.sample = containerSampleRate(c)
+	meta.limit = a.containerRateLimiter(c)
+	meta.dedup = a.dedupWindow > 0 && containerBool(c, "LOGSTASH_DEDUP", "logstash.dedup", true)
+	meta.priority = containerPriority(c)
+}
+
+// enrichHints sets the type, and the index, pipeline, tenant and shipper
+// hints where the adapter is configured to put them.
+func enrichHints(a *LogstashAdapter, c *docker.Container, meta *containerMeta) {
+	if a.indexTemplate != nil {
+		if index, err := executeTemplate(a.indexTemplate, c); err != nil {
+			log.Println("logstash: could not expand index template:", err)
+		} else if index != "" {
+			a.indexField.set(meta, index)
+		}
+	}
+	meta.logType = containerSetting(c, "LOGSTASH_TYPE", "logstash.type")
+	if meta.logType == "" {
+		meta.logType = a.defaultType
+	}
+	if a.typeField.key != "" && meta.logType != "" {
+		a.typeField.set(meta, meta.logType)
+		meta.logType = ""
+	}
+	if pipeline := containerSetting(c, "LOGSTASH_PIPELINE", "logstash.pipeline"); pipeline != "" {
+		a.pipelineField.set(meta, pipeline)
+	}
+	if tenant := a.containerTenant(c); tenant != "" {
+		a.tenantField.set(meta, tenant)
+	}
+	if a.shipperMetadata {
+		a.shipper.setMetadata(meta)
+	}
+}
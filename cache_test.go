@@ -0,0 +1,94 @@
+package logstash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerMetaInvalidation(t *testing.T) {
+	assert := assert.New(t)
+
+	adapter := LogstashAdapter{}
+	started := time.Date(2016, 10, 20, 13, 25, 13, 0, time.UTC)
+	container := docker.Container{ID: "ID", Name: "/shop", Config: &docker.Config{Env: []string{"LOGSTASH_TAGS=v1"}}}
+	container.State.StartedAt = started
+
+	meta := adapter.containerMeta(&container)
+	assert.Equal([]string{"v1"}, meta.tags)
+	assert.Equal("/shop", meta.docker.Name)
+
+	// A new snapshot of the same run hits the cache.
+	snapshot := container
+	snapshot.Config = &docker.Config{Env: []string{"LOGSTASH_TAGS=v2"}}
+	assert.True(meta == adapter.containerMeta(&snapshot))
+
+	renamed := snapshot
+	renamed.Name = "/shop-old"
+	meta = adapter.containerMeta(&renamed)
+	assert.Equal([]string{"v2"}, meta.tags)
+	assert.Equal("/shop-old", meta.docker.Name)
+
+	restarted := renamed
+	restarted.RestartCount = 1
+	restarted.State.StartedAt = started.Add(time.Minute)
+	restarted.Config = &docker.Config{Env: []string{"LOGSTASH_TAGS=v3"}}
+	meta = adapter.containerMeta(&restarted)
+	assert.Equal([]string{"v3"}, meta.tags)
+	assert.Len(adapter.containers, 1)
+}
+
+func TestSweepContainers(t *testing.T) {
+	assert := assert.New(t)
+
+	adapter := LogstashAdapter{}
+	busy := &docker.Container{ID: "busy", Config: &docker.Config{}}
+	gone := &docker.Container{ID: "gone", Config: &docker.Config{}}
+	limited := &docker.Container{ID: "limited", Config: &docker.Config{Env: []string{"LOGSTASH_RATE_LIMIT=1"}}}
+	adapter.containerMeta(busy)
+	adapter.containerMeta(gone)
+	adapter.containerMeta(limited).limit.suppressed = 3
+
+	adapter.sweepContainers()
+	assert.Len(adapter.containers, 3)
+
+	adapter.containerMeta(busy)
+	adapter.sweepContainers()
+	assert.Contains(adapter.containers, "busy")
+	assert.NotContains(adapter.containers, "gone")
+	// Suppressed events are reported before the container is forgotten.
+	assert.Contains(adapter.containers, "limited")
+
+	adapter.containers["limited"].limit.suppressed = 0
+	adapter.sweepContainers()
+	adapter.sweepContainers()
+	assert.Empty(adapter.containers)
+}
+
+func TestContainerFlags(t *testing.T) {
+	assert := assert.New(t)
+
+	computed := 0
+	compute := func() bool {
+		computed++
+		return true
+	}
+
+	var flags containerFlags
+	assert.True(flags.get("busy", compute))
+	assert.True(flags.get("gone", compute))
+	assert.True(flags.get("busy", compute))
+	assert.Equal(2, computed)
+
+	// Flags looked up since the last sweep survive the next one.
+	flags.swept = time.Now().Add(-containerCacheSweep)
+	flags.get("busy", compute)
+	assert.Equal(2, computed)
+	flags.swept = time.Now().Add(-containerCacheSweep)
+	flags.get("busy", compute)
+	assert.Equal(2, computed)
+	assert.NotContains(flags.current, "gone")
+	assert.NotContains(flags.previous, "gone")
+}
@@ -0,0 +1,149 @@
+package logstash
+
+import (
+	"strconv"
+	"strings"
+)
+
+// cefHeader names the fields of the header of a Common Event Format line.
+var cefHeader = []string{"version", "device_vendor", "device_product", "device_version", "signature_id", "name", "severity"}
+
+// parseCEF decodes a Common Event Format line, optionally preceded by a
+// syslog header, into its header fields and an extensions object. ok is false
+// if the line is not CEF.
+func parseCEF(line string) (fields map[string]interface{}, ok bool) {
+	i := strings.Index(line, "CEF:")
+	if i < 0 {
+		return nil, false
+	}
+	parts := splitHeader(line[i+len("CEF:"):], len(cefHeader))
+	if len(parts) <= len(cefHeader) {
+		return nil, false
+	}
+	fields = make(map[string]interface{}, len(cefHeader)+1)
+	for i, name := range cefHeader {
+		fields[name] = parts[i]
+	}
+	if extensions := parseCEFExtensions(parts[len(cefHeader)]); len(extensions) > 0 {
+		fields["extensions"] = extensions
+	}
+	return fields, true
+}
+
+// splitHeader splits s at the first n unescaped pipes, unescaping \| and \\
+// in the fields before them. It returns fewer than n+1 parts if s has fewer
+// pipes.
+func splitHeader(s string, n int) []string {
+	var parts []string
+	var field []byte
+	for i := 0; i < len(s); i++ {
+		if len(parts) == n {
+			return append(parts, s[i:])
+		}
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && (s[i+1] == '|' || s[i+1] == '\\'):
+			i++
+			field = append(field, s[i])
+		case s[i] == '|':
+			parts = append(parts, string(field))
+			field = field[:0]
+		default:
+			field = append(field, s[i])
+		}
+	}
+	if len(parts) == n {
+		parts = append(parts, "")
+	}
+	return parts
+}
+
+// parseCEFExtensions decodes the key=value pairs of CEF extensions. Values
+// may contain spaces: a value runs up to the key of the next pair.
+func parseCEFExtensions(s string) map[string]interface{} {
+	type pair struct{ key, eq int }
+	var pairs []pair
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] != '=' {
+			continue
+		}
+		key := strings.LastIndexByte(s[:i], ' ') + 1
+		if key < i && (len(pairs) == 0 || key > pairs[len(pairs)-1].eq) {
+			pairs = append(pairs, pair{key, i})
+		}
+	}
+
+	extensions := make(map[string]interface{}, len(pairs))
+	for n, p := range pairs {
+		end := len(s)
+		if n+1 < len(pairs) {
+			end = pairs[n+1].key
+		}
+		extensions[s[p.key:p.eq]] = cefUnescaper.Replace(strings.TrimSpace(s[p.eq+1 : end]))
+	}
+	return extensions
+}
+
+// cefUnescaper undoes the escapes of CEF extension values.
+var cefUnescaper = strings.NewReplacer(`\=`, `=`, `\\`, `\`, `\n`, "\n", `\r`, "\r")
+
+// leefHeader names the fields of the header of a Log Event Extended Format
+// line.
+var leefHeader = []string{"version", "vendor", "product", "product_version", "event_id"}
+
+// parseLEEF decodes a LEEF 1.0 or 2.0 line, optionally preceded by a syslog
+// header, into its header fields and an attributes object. ok is false if the
+// line is not LEEF.
+func parseLEEF(line string) (fields map[string]interface{}, ok bool) {
+	i := strings.Index(line, "LEEF:")
+	if i < 0 {
+		return nil, false
+	}
+	parts := splitHeader(line[i+len("LEEF:"):], len(leefHeader))
+	if len(parts) <= len(leefHeader) {
+		return nil, false
+	}
+	fields = make(map[string]interface{}, len(leefHeader)+1)
+	for i, name := range leefHeader {
+		fields[name] = parts[i]
+	}
+
+	// LEEF 2.0 names the delimiter of the attributes in a sixth header
+	// field, as a character or in hex such as x09 or 0x09.
+	rest, delimiter := parts[len(leefHeader)], "\t"
+	if strings.HasPrefix(parts[0], "2") {
+		if j := strings.IndexByte(rest, '|'); j >= 0 {
+			delimiter, rest = leefDelimiter(rest[:j]), rest[j+1:]
+		}
+	}
+
+	attributes := make(map[string]interface{})
+	for _, pair := range strings.Split(rest, delimiter) {
+		if j := strings.IndexByte(pair, '='); j > 0 {
+			attributes[pair[:j]] = pair[j+1:]
+		}
+	}
+	if len(attributes) > 0 {
+		fields["attributes"] = attributes
+	}
+	return fields, true
+}
+
+// leefDelimiter returns the delimiter a LEEF 2.0 header names, or a tab if
+// it names none.
+func leefDelimiter(s string) string {
+	for _, prefix := range []string{"0x", "x"} {
+		if hex := strings.TrimPrefix(s, prefix); hex != s && hex != "" {
+			if b, err := strconv.ParseUint(hex, 16, 8); err == nil {
+				return string([]byte{byte(b)})
+			}
+		}
+	}
+	if s == "" {
+		return "\t"
+	}
+	return s
+}
@@ -0,0 +1,99 @@
+package logstash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCEF(t *testing.T) {
+	assert := assert.New(t)
+
+	fields, ok := parseCEF(`Oct 20 13:25:13 fw1 CEF:0|Security|threat\|manager|1.0|100|worm successfully stopped|10|src=10.0.0.1 dst=2.1.2.2 msg=Detected a threat. No action needed. request=http://x/?a\=b path=C:\\temp`)
+	assert.True(ok)
+	assert.Equal(map[string]interface{}{
+		"version":        "0",
+		"device_vendor":  "Security",
+		"device_product": "threat|manager",
+		"device_version": "1.0",
+		"signature_id":   "100",
+		"name":           "worm successfully stopped",
+		"severity":       "10",
+		"extensions": map[string]interface{}{
+			"src":     "10.0.0.1",
+			"dst":     "2.1.2.2",
+			"msg":     "Detected a threat. No action needed.",
+			"request": "http://x/?a=b",
+			"path":    `C:\temp`,
+		},
+	}, fields)
+
+	fields, ok = parseCEF(`CEF:0|Vendor|Product|1.0|1|Name|3|`)
+	assert.True(ok)
+	assert.NotContains(fields, "extensions")
+
+	for _, line := range []string{"plain text", "CEF:0|Vendor|Product|1.0"} {
+		_, ok := parseCEF(line)
+		assert.False(ok, line)
+	}
+}
+
+func TestParseLEEF(t *testing.T) {
+	assert := assert.New(t)
+
+	fields, ok := parseLEEF("LEEF:1.0|Microsoft|MSExchange|4.0 SP1|15345|src=192.0.2.0\tdst=172.50.123.1\tsev=5")
+	assert.True(ok)
+	assert.Equal(map[string]interface{}{
+		"version":         "1.0",
+		"vendor":          "Microsoft",
+		"product":         "MSExchange",
+		"product_version": "4.0 SP1",
+		"event_id":        "15345",
+		"attributes":      map[string]interface{}{"src": "192.0.2.0", "dst": "172.50.123.1", "sev": "5"},
+	}, fields)
+
+	for _, line := range []string{
+		"LEEF:2.0|Lancope|StealthWatch|1.0|41|^|src=10.0.1.8^dst=10.0.0.5",
+		"LEEF:2.0|Lancope|StealthWatch|1.0|41|0x5e|src=10.0.1.8^dst=10.0.0.5",
+		"LEEF:2.0|Lancope|StealthWatch|1.0|41|x5e|src=10.0.1.8^dst=10.0.0.5",
+	} {
+		fields, ok = parseLEEF(line)
+		assert.True(ok)
+		assert.Equal(map[string]interface{}{"src": "10.0.1.8", "dst": "10.0.0.5"}, fields["attributes"], line)
+	}
+
+	_, ok = parseLEEF("plain text")
+	assert.False(ok)
+}
+
+func TestStreamWithCEF(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route: new(router.Route),
+		conn:  conn,
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Env: []string{"LOGSTASH_FORMAT=cef"}}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: `CEF:0|Vendor|Product|1.0|1|Name|3|src=10.0.0.1`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `starting up`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 2) {
+		cef := lines[0]["cef"].(map[string]interface{})
+		assert.Equal("Vendor", cef["device_vendor"])
+		assert.Equal(map[string]interface{}{"src": "10.0.0.1"}, cef["extensions"])
+		assert.Nil(lines[1]["cef"])
+	}
+}
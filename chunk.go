@@ -0,0 +1,89 @@
+package logstash
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+// ChunkInfo identifies one of the events a line too large for a single
+// event was split into. Downstream, the chunks sharing an ID are joined in
+// the order of their index to restore the line.
+type ChunkInfo struct {
+	ID    string `json:"id"`
+	Index int    `json:"index"`
+	Count int    `json:"count"`
+}
+
+// appendJSON appends the JSON encoding of c to dst.
+func (c *ChunkInfo) appendJSON(dst []byte) []byte {
+	dst = append(dst, `{"id":`...)
+	dst = appendJSONString(dst, c.ID)
+	dst = append(dst, `,"index":`...)
+	dst = strconv.AppendInt(dst, int64(c.Index), 10)
+	dst = append(dst, `,"count":`...)
+	dst = strconv.AppendInt(dst, int64(c.Count), 10)
+	return append(dst, '}')
+}
+
+// chunk encodes e into d as several events that each fit the maximum event
+// size, with consecutive pieces of its line as their message. The pieces are
+// shipped as text, and cut at character boundaries. If not even a single
+// character fits next to the metadata of the event, it is truncated instead.
+func (a *LogstashAdapter) chunk(e *event, d *document) error {
+	line := e.line
+	format := e.format
+	e.format = "text"
+	// The count is not known until the line is cut, but it is no larger than
+	// the length of the line, which sizes the pieces conservatively.
+	e.chunk = &ChunkInfo{ID: newUUID(), Count: len(line)}
+	d.reset()
+
+	var ends []int
+	for start := 0; start < len(line); {
+		e.chunk.Index = len(ends)
+		end := len(line)
+		if end-start > a.maxEventSize {
+			end = start + a.maxEventSize
+		}
+		for end < len(line) && !utf8.RuneStart(line[end]) {
+			end--
+		}
+		for {
+			if end == start {
+				e.text, e.format, e.chunk = line, format, nil
+				d.reset()
+				if err := a.encode(e, d); err != nil {
+					return err
+				}
+				return a.truncate(e, d)
+			}
+			e.text = line[start:end]
+			if err := a.encode(e, d); err != nil {
+				return err
+			}
+			size := d.Len()
+			d.reset()
+			if size <= a.maxEventSize {
+				break
+			}
+			end = shorten(line, start, end, size, a.maxEventSize)
+		}
+		ends = append(ends, end)
+		start = end
+	}
+
+	e.chunk.Count = len(ends)
+	start := 0
+	for i, end := range ends {
+		e.chunk.Index = i
+		e.text = line[start:end]
+		if err := a.encode(e, d); err != nil {
+			return err
+		}
+		if i < len(ends)-1 {
+			d.ends = append(d.ends, d.Len())
+		}
+		start = end
+	}
+	return nil
+}
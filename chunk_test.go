@@ -0,0 +1,81 @@
+package logstash
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamWithChunks(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:         new(router.Route),
+		conn:          conn,
+		maxEventSize:  300,
+		chunkOversize: true,
+		batchSize:     10,
+		flushInterval: time.Hour,
+	}
+
+	container := docker.Container{ID: "ID", Name: "/web", Config: &docker.Config{Image: "nginx"}}
+	blob := `{"config":"` + strings.Repeat("é<>", 150) + `"}`
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: "before", Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: blob, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: "after", Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	for _, line := range strings.Split(strings.TrimSpace(conn.buf.String()), "\n") {
+		assert.True(len(line) < 300, line)
+	}
+
+	lines := conn.Lines()
+	if assert.True(len(lines) > 4) {
+		assert.Equal("before", lines[0]["message"])
+		assert.Nil(lines[0]["chunk"])
+		assert.Equal("after", lines[len(lines)-1]["message"])
+
+		chunks := lines[1 : len(lines)-1]
+		var joined string
+		id := chunks[0]["chunk"].(map[string]interface{})["id"]
+		for i, line := range chunks {
+			chunk := line["chunk"].(map[string]interface{})
+			assert.Equal(id, chunk["id"])
+			assert.Equal(float64(i), chunk["index"])
+			assert.Equal(float64(len(chunks)), chunk["count"])
+			assert.Nil(line["config"])
+			joined += line["message"].(string)
+		}
+		assert.Equal(blob, joined)
+	}
+}
+
+func TestChunkFallsBackToTruncate(t *testing.T) {
+	assert := assert.New(t)
+
+	adapter := LogstashAdapter{
+		route:         new(router.Route),
+		maxEventSize:  20,
+		chunkOversize: true,
+	}
+	container := docker.Container{ID: "ID", Name: "/web", Config: &docker.Config{Image: "nginx"}}
+	e := adapter.enrich(&router.Message{Container: &container, Data: "a line too long for the metadata", Time: time.Now()})
+
+	d := documentPool.Get().(*document)
+	assert.Nil(adapter.serialize(e, d))
+	assert.Empty(d.ends)
+	assert.Contains(d.String(), `"message":""`)
+	assert.Contains(d.String(), `"truncated"`)
+	assert.NotContains(d.String(), `"chunk"`)
+}
@@ -0,0 +1,177 @@
+package logstash
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// CloudData describes the cloud instance logspout runs on.
+type CloudData struct {
+	Provider         string `json:"provider,omitempty"`
+	InstanceID       string `json:"instance_id,omitempty"`
+	InstanceType     string `json:"instance_type,omitempty"`
+	Region           string `json:"region,omitempty"`
+	AvailabilityZone string `json:"availability_zone,omitempty"`
+}
+
+// The metadata service endpoints, variables so tests can replace them.
+var (
+	ec2MetadataURL   = "http://169.254.169.254/latest"
+	gceMetadataURL   = "http://metadata.google.internal/computeMetadata/v1/instance"
+	azureMetadataURL = "http://169.254.169.254/metadata/instance/compute?api-version=2021-02-01"
+)
+
+var cloudProviders = map[string]func(*http.Client) (*CloudData, error){
+	"aws":   getEC2Data,
+	"gce":   getGCEData,
+	"azure": getAzureData,
+}
+
+// GetCloudData queries the metadata service of provider, which is one of
+// "aws", "gce", "azure", or "auto" to try them all at once, so that probing
+// takes no longer than a single timeout.
+func GetCloudData(provider string, timeout time.Duration) (*CloudData, error) {
+	client := &http.Client{Timeout: timeout}
+	if provider != "auto" {
+		get, ok := cloudProviders[provider]
+		if !ok {
+			return nil, errors.New("unknown cloud provider: " + provider)
+		}
+		return get(client)
+	}
+
+	found := make(chan *CloudData, len(cloudProviders))
+	for _, get := range cloudProviders {
+		go func(get func(*http.Client) (*CloudData, error)) {
+			d, _ := get(client)
+			found <- d
+		}(get)
+	}
+	for range cloudProviders {
+		if d := <-found; d != nil {
+			return d, nil
+		}
+	}
+	return nil, errors.New("no cloud metadata service found")
+}
+
+// metadataGet fetches url with the given headers and returns the body.
+func metadataGet(client *http.Client, method, url string, headers map[string]string) (string, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New("metadata service returned " + resp.Status + " for " + url)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+func getEC2Data(client *http.Client) (*CloudData, error) {
+	// Use an IMDSv2 session token when available, IMDSv1 otherwise.
+	headers := make(map[string]string)
+	if token, err := metadataGet(client, "PUT", ec2MetadataURL+"/api/token", map[string]string{"X-aws-ec2-metadata-token-ttl-seconds": "60"}); err == nil {
+		headers["X-aws-ec2-metadata-token"] = token
+	}
+
+	d := &CloudData{Provider: "aws"}
+	for _, field := range []struct {
+		path  string
+		value *string
+	}{
+		{"instance-id", &d.InstanceID},
+		{"instance-type", &d.InstanceType},
+		{"placement/availability-zone", &d.AvailabilityZone},
+		{"placement/region", &d.Region},
+	} {
+		value, err := metadataGet(client, "GET", ec2MetadataURL+"/meta-data/"+field.path, headers)
+		if err != nil {
+			return nil, err
+		}
+		*field.value = value
+	}
+	return d, nil
+}
+
+func getGCEData(client *http.Client) (*CloudData, error) {
+	headers := map[string]string{"Metadata-Flavor": "Google"}
+
+	d := &CloudData{Provider: "gce"}
+	for _, field := range []struct {
+		path  string
+		value *string
+	}{
+		{"id", &d.InstanceID},
+		{"machine-type", &d.InstanceType},
+		{"zone", &d.AvailabilityZone},
+	} {
+		value, err := metadataGet(client, "GET", gceMetadataURL+"/"+field.path, headers)
+		if err != nil {
+			return nil, err
+		}
+		// Machine type and zone are returned as resource paths.
+		*field.value = path.Base(value)
+	}
+
+	// Zones are named after their region, as in us-central1-a.
+	if i := strings.LastIndex(d.AvailabilityZone, "-"); i > 0 {
+		d.Region = d.AvailabilityZone[:i]
+	}
+	return d, nil
+}
+
+func getAzureData(client *http.Client) (*CloudData, error) {
+	body, err := metadataGet(client, "GET", azureMetadataURL, map[string]string{"Metadata": "true"})
+	if err != nil {
+		return nil, err
+	}
+
+	var compute struct {
+		VMID     string `json:"vmId"`
+		VMSize   string `json:"vmSize"`
+		Location string `json:"location"`
+		Zone     string `json:"zone"`
+	}
+	if err := json.Unmarshal([]byte(body), &compute); err != nil {
+		return nil, err
+	}
+
+	return &CloudData{
+		Provider:         "azure",
+		InstanceID:       compute.VMID,
+		InstanceType:     compute.VMSize,
+		Region:           compute.Location,
+		AvailabilityZone: compute.Zone,
+	}, nil
+}
+
+// appendJSON appends the JSON encoding of d to dst.
+func (d *CloudData) appendJSON(dst []byte) []byte {
+	dst = append(dst, '{')
+	n := len(dst)
+	dst = appendJSONStringField(dst, n, "provider", d.Provider)
+	dst = appendJSONStringField(dst, n, "instance_id", d.InstanceID)
+	dst = appendJSONStringField(dst, n, "instance_type", d.InstanceType)
+	dst = appendJSONStringField(dst, n, "region", d.Region)
+	dst = appendJSONStringField(dst, n, "availability_zone", d.AvailabilityZone)
+	return append(dst, '}')
+}
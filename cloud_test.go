@@ -0,0 +1,150 @@
+package logstash
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCloudDataEC2(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" {
+			assert.Equal("PUT", r.Method)
+			w.Write([]byte("token"))
+			return
+		}
+		assert.Equal("token", r.Header.Get("X-aws-ec2-metadata-token"))
+		switch r.URL.Path {
+		case "/latest/meta-data/instance-id":
+			w.Write([]byte("i-0123456789abcdef0"))
+		case "/latest/meta-data/instance-type":
+			w.Write([]byte("m5.large"))
+		case "/latest/meta-data/placement/availability-zone":
+			w.Write([]byte("eu-west-1b"))
+		case "/latest/meta-data/placement/region":
+			w.Write([]byte("eu-west-1"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	defer func(url string) { ec2MetadataURL = url }(ec2MetadataURL)
+	ec2MetadataURL = server.URL + "/latest"
+
+	d, err := GetCloudData("aws", time.Second)
+	assert.Nil(err)
+	assert.Equal(&CloudData{
+		Provider:         "aws",
+		InstanceID:       "i-0123456789abcdef0",
+		InstanceType:     "m5.large",
+		Region:           "eu-west-1",
+		AvailabilityZone: "eu-west-1b",
+	}, d)
+}
+
+func TestGetCloudDataGCE(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("Google", r.Header.Get("Metadata-Flavor"))
+		switch r.URL.Path {
+		case "/instance/id":
+			w.Write([]byte("4520031799277581759"))
+		case "/instance/machine-type":
+			w.Write([]byte("projects/123456/machineTypes/n1-standard-1"))
+		case "/instance/zone":
+			w.Write([]byte("projects/123456/zones/us-central1-a"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	defer func(url string) { gceMetadataURL = url }(gceMetadataURL)
+	gceMetadataURL = server.URL + "/instance"
+
+	d, err := GetCloudData("gce", time.Second)
+	assert.Nil(err)
+	assert.Equal(&CloudData{
+		Provider:         "gce",
+		InstanceID:       "4520031799277581759",
+		InstanceType:     "n1-standard-1",
+		Region:           "us-central1",
+		AvailabilityZone: "us-central1-a",
+	}, d)
+}
+
+func TestGetCloudDataAzure(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("true", r.Header.Get("Metadata"))
+		w.Write([]byte(`{"vmId":"02aab8a4-74ef-476e-8182-f6d2ba4166a6","vmSize":"Standard_D2s_v3","location":"westeurope","zone":"2"}`))
+	}))
+	defer server.Close()
+
+	defer func(url string) { azureMetadataURL = url }(azureMetadataURL)
+	azureMetadataURL = server.URL
+
+	d, err := GetCloudData("azure", time.Second)
+	assert.Nil(err)
+	assert.Equal(&CloudData{
+		Provider:         "azure",
+		InstanceID:       "02aab8a4-74ef-476e-8182-f6d2ba4166a6",
+		InstanceType:     "Standard_D2s_v3",
+		Region:           "westeurope",
+		AvailabilityZone: "2",
+	}, d)
+}
+
+func TestGetCloudDataAuto(t *testing.T) {
+	assert := assert.New(t)
+
+	// The EC2 metadata service does not answer in time.
+	release := make(chan struct{})
+	ec2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer ec2.Close()
+	defer close(release)
+	gce := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/instance/id":
+			w.Write([]byte("4520031799277581759"))
+		case "/instance/zone":
+			w.Write([]byte("projects/123456/zones/us-central1-a"))
+		default:
+			w.Write([]byte("n1-standard-1"))
+		}
+	}))
+	defer gce.Close()
+	azure := httptest.NewServer(http.NotFoundHandler())
+	defer azure.Close()
+
+	defer func(ec2URL, gceURL, azureURL string) {
+		ec2MetadataURL, gceMetadataURL, azureMetadataURL = ec2URL, gceURL, azureURL
+	}(ec2MetadataURL, gceMetadataURL, azureMetadataURL)
+	ec2MetadataURL = ec2.URL + "/latest"
+	gceMetadataURL = gce.URL + "/instance"
+	azureMetadataURL = azure.URL
+
+	start := time.Now()
+	d, err := GetCloudData("auto", 2*time.Second)
+	assert.Nil(err)
+	if assert.NotNil(d) {
+		assert.Equal("gce", d.Provider)
+	}
+	assert.True(time.Since(start) < time.Second)
+
+	gce.Close()
+	start = time.Now()
+	_, err = GetCloudData("auto", 200*time.Millisecond)
+	assert.NotNil(err)
+	assert.True(time.Since(start) < time.Second)
+}
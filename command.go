@@ -0,0 +1,25 @@
+package logstash
+
+import (
+	"strings"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// GetContainerCommand returns the container's entrypoint and command, each
+// joined with spaces. With redact set only the executables are kept: the
+// first word of the entrypoint, and the first word of the command when there
+// is no entrypoint. A command that only supplies arguments to an entrypoint
+// is dropped entirely, since arguments are where secrets tend to end up.
+func GetContainerCommand(c *docker.Container, redact bool) (entrypoint, command string) {
+	if !redact {
+		return strings.Join(c.Config.Entrypoint, " "), strings.Join(c.Config.Cmd, " ")
+	}
+	if len(c.Config.Entrypoint) > 0 {
+		return c.Config.Entrypoint[0], ""
+	}
+	if len(c.Config.Cmd) > 0 {
+		return "", c.Config.Cmd[0]
+	}
+	return "", ""
+}
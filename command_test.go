@@ -0,0 +1,36 @@
+package logstash
+
+import (
+	"testing"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetContainerCommand(t *testing.T) {
+	assert := assert.New(t)
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{}}
+	entrypoint, command := GetContainerCommand(&container, false)
+	assert.Equal("", entrypoint)
+	assert.Equal("", command)
+
+	container.Config.Cmd = []string{"nginx", "-g", "daemon off;"}
+	entrypoint, command = GetContainerCommand(&container, false)
+	assert.Equal("", entrypoint)
+	assert.Equal("nginx -g daemon off;", command)
+
+	entrypoint, command = GetContainerCommand(&container, true)
+	assert.Equal("", entrypoint)
+	assert.Equal("nginx", command)
+
+	container.Config.Entrypoint = []string{"/app/server", "--verbose"}
+	container.Config.Cmd = []string{"--token=secret"}
+	entrypoint, command = GetContainerCommand(&container, false)
+	assert.Equal("/app/server --verbose", entrypoint)
+	assert.Equal("--token=secret", command)
+
+	entrypoint, command = GetContainerCommand(&container, true)
+	assert.Equal("/app/server", entrypoint)
+	assert.Equal("", command)
+}
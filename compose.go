@@ -0,0 +1,38 @@
+package logstash
+
+import (
+	"github.com/fsouza/go-dockerclient"
+)
+
+// ComposeData describes the Docker Compose project and service a container
+// belongs to.
+type ComposeData struct {
+	Project         string `json:"project,omitempty"`
+	Service         string `json:"service,omitempty"`
+	ContainerNumber string `json:"container_number,omitempty"`
+}
+
+// GetComposeData returns the Compose information found in the container
+// labels, or nil if the container was not created by Compose.
+func GetComposeData(c *docker.Container) *ComposeData {
+	labels := c.Config.Labels
+	d := ComposeData{
+		Project:         labels["com.docker.compose.project"],
+		Service:         labels["com.docker.compose.service"],
+		ContainerNumber: labels["com.docker.compose.container-number"],
+	}
+	if d == (ComposeData{}) {
+		return nil
+	}
+	return &d
+}
+
+// appendJSON appends the JSON encoding of d to dst.
+func (d *ComposeData) appendJSON(dst []byte) []byte {
+	dst = append(dst, '{')
+	n := len(dst)
+	dst = appendJSONStringField(dst, n, "project", d.Project)
+	dst = appendJSONStringField(dst, n, "service", d.Service)
+	dst = appendJSONStringField(dst, n, "container_number", d.ContainerNumber)
+	return append(dst, '}')
+}
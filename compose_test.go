@@ -0,0 +1,22 @@
+package logstash
+
+import (
+	"testing"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetComposeData(t *testing.T) {
+	assert := assert.New(t)
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Labels: map[string]string{
+		"com.docker.compose.project":          "shop",
+		"com.docker.compose.service":          "web",
+		"com.docker.compose.container-number": "2",
+	}}}
+	assert.Equal(&ComposeData{Project: "shop", Service: "web", ContainerNumber: "2"}, GetComposeData(&container))
+
+	container.Config.Labels = nil
+	assert.Nil(GetComposeData(&container))
+}
@@ -0,0 +1,88 @@
+package logstash
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"strconv"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressor encodes a whole batch into a single compressed frame.
+type compressor interface {
+	compress(batch [][]byte) ([]byte, error)
+}
+
+// newCompressor returns the compressor called name, or nil if name is empty
+// or "none". An empty level selects the codec's default level.
+func newCompressor(name, level string) (compressor, error) {
+	switch name {
+	case "", "none":
+		return nil, nil
+	case "gzip":
+		l := gzip.DefaultCompression
+		if level != "" {
+			var err error
+			if l, err = strconv.Atoi(level); err != nil {
+				return nil, errors.New("invalid gzip compression level: " + level)
+			}
+		}
+		w, err := gzip.NewWriterLevel(nil, l)
+		if err != nil {
+			return nil, err
+		}
+		return &gzipCompressor{w: w}, nil
+	case "zstd":
+		l := zstd.SpeedDefault
+		if level != "" {
+			n, err := strconv.Atoi(level)
+			if err != nil || n < 1 || n > 22 {
+				return nil, errors.New("invalid zstd compression level: " + level)
+			}
+			l = zstd.EncoderLevelFromZstd(n)
+		}
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(l), zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return &zstdCompressor{enc: enc}, nil
+	}
+	return nil, errors.New("unknown compression: " + name)
+}
+
+// gzipCompressor writes each batch as one gzip member. Consecutive members
+// form a valid multi-member gzip stream.
+type gzipCompressor struct {
+	buf bytes.Buffer
+	w   *gzip.Writer
+}
+
+func (c *gzipCompressor) compress(batch [][]byte) ([]byte, error) {
+	c.buf.Reset()
+	c.w.Reset(&c.buf)
+	for _, js := range batch {
+		if _, err := c.w.Write(js); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.w.Close(); err != nil {
+		return nil, err
+	}
+	return c.buf.Bytes(), nil
+}
+
+// zstdCompressor writes each batch as one zstd frame. Consecutive frames
+// form a valid zstd stream.
+type zstdCompressor struct {
+	enc *zstd.Encoder
+	buf []byte
+}
+
+func (c *zstdCompressor) compress(batch [][]byte) ([]byte, error) {
+	c.buf = c.buf[:0]
+	for _, js := range batch {
+		c.buf = append(c.buf, js...)
+	}
+	return c.enc.EncodeAll(c.buf, nil), nil
+}
@@ -0,0 +1,64 @@
+package logstash
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGzipCompressorMultiMember(t *testing.T) {
+	assert := assert.New(t)
+
+	c, err := newCompressor("gzip", "")
+	assert.Nil(err)
+
+	var stream []byte
+	for _, batch := range [][][]byte{
+		{[]byte("{\"a\":1}\n"), []byte("{\"b\":2}\n")},
+		{[]byte("{\"c\":3}\n")},
+	} {
+		frame, err := c.compress(batch)
+		assert.Nil(err)
+		stream = append(stream, frame...)
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(stream))
+	assert.Nil(err)
+	out, err := ioutil.ReadAll(r)
+	assert.Nil(err)
+	assert.Equal("{\"a\":1}\n{\"b\":2}\n{\"c\":3}\n", string(out))
+}
+
+func TestZstdCompressorLevel(t *testing.T) {
+	assert := assert.New(t)
+
+	c, err := newCompressor("zstd", "19")
+	assert.Nil(err)
+
+	frame, err := c.compress([][]byte{[]byte("{\"a\":1}\n"), []byte("{\"b\":2}\n")})
+	assert.Nil(err)
+
+	dec, err := zstd.NewReader(nil)
+	assert.Nil(err)
+	out, err := dec.DecodeAll(frame, nil)
+	assert.Nil(err)
+	assert.Equal("{\"a\":1}\n{\"b\":2}\n", string(out))
+}
+
+func TestZstdCompressorInvalidLevel(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, level := range []string{"0", "23", "-1", "fast"} {
+		_, err := newCompressor("zstd", level)
+		assert.NotNil(err, level)
+	}
+}
+
+func TestNewCompressorUnknown(t *testing.T) {
+	_, err := newCompressor("lz4", "")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,92 @@
+package logstash
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// DCOSData describes where in a DC/OS cluster a container runs.
+type DCOSData struct {
+	Framework string `json:"framework,omitempty"`
+	AgentID   string `json:"agent_id,omitempty"`
+	Region    string `json:"region,omitempty"`
+	Zone      string `json:"zone,omitempty"`
+}
+
+// GetDCOSNodeData returns the DC/OS topology of the node logspout runs on.
+// The agent ID is read from LOGSTASH_DCOS_AGENT_ID, or from the name of the
+// agent's "latest" metadata symlink. Region and zone are read from
+// LOGSTASH_DCOS_REGION and LOGSTASH_DCOS_ZONE, or from the JSON output of
+// the DC/OS fault domain detection script mounted at
+// LOGSTASH_DCOS_FAULT_DOMAIN_FILE.
+func GetDCOSNodeData() DCOSData {
+	d := DCOSData{
+		AgentID: getopt("LOGSTASH_DCOS_AGENT_ID", ""),
+		Region:  getopt("LOGSTASH_DCOS_REGION", ""),
+		Zone:    getopt("LOGSTASH_DCOS_ZONE", ""),
+	}
+
+	if d.AgentID == "" {
+		latest := getopt("LOGSTASH_DCOS_AGENT_META", "/var/lib/mesos/slave/meta/slaves/latest")
+		if target, err := os.Readlink(latest); err == nil {
+			d.AgentID = filepath.Base(target)
+		}
+	}
+
+	if d.Region == "" || d.Zone == "" {
+		var domain struct {
+			FaultDomain struct {
+				Region struct {
+					Name string `json:"name"`
+				} `json:"region"`
+				Zone struct {
+					Name string `json:"name"`
+				} `json:"zone"`
+			} `json:"fault_domain"`
+		}
+		if b, err := ioutil.ReadFile(getopt("LOGSTASH_DCOS_FAULT_DOMAIN_FILE", "/var/lib/dcos/fault-domain.json")); err == nil && json.Unmarshal(b, &domain) == nil {
+			if d.Region == "" {
+				d.Region = domain.FaultDomain.Region.Name
+			}
+			if d.Zone == "" {
+				d.Zone = domain.FaultDomain.Zone.Name
+			}
+		}
+	}
+
+	return d
+}
+
+// GetDCOSData completes the node's DC/OS data with the framework and agent
+// of the container, as found in its environment.
+func GetDCOSData(c *docker.Container, node DCOSData) *DCOSData {
+	d := node
+	for _, e := range c.Config.Env {
+		if strings.HasPrefix(e, "FRAMEWORK_NAME=") {
+			d.Framework = strings.TrimPrefix(e, "FRAMEWORK_NAME=")
+		} else if strings.HasPrefix(e, "MESOS_AGENT_ID=") || strings.HasPrefix(e, "MESOS_SLAVE_ID=") {
+			d.AgentID = e[strings.Index(e, "=")+1:]
+		} else if d.Framework == "" && strings.HasPrefix(e, "MARATHON_APP_ID=") {
+			d.Framework = "marathon"
+		} else if d.Framework == "" && strings.HasPrefix(e, "CHRONOS_JOB_NAME=") {
+			d.Framework = "chronos"
+		}
+	}
+	return &d
+}
+
+// appendJSON appends the JSON encoding of d to dst.
+func (d *DCOSData) appendJSON(dst []byte) []byte {
+	dst = append(dst, '{')
+	n := len(dst)
+	dst = appendJSONStringField(dst, n, "framework", d.Framework)
+	dst = appendJSONStringField(dst, n, "agent_id", d.AgentID)
+	dst = appendJSONStringField(dst, n, "region", d.Region)
+	dst = appendJSONStringField(dst, n, "zone", d.Zone)
+	return append(dst, '}')
+}
@@ -0,0 +1,41 @@
+package logstash
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDCOSData(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "dcos")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	domain := filepath.Join(dir, "fault-domain.json")
+	assert.Nil(ioutil.WriteFile(domain, []byte(`{"fault_domain":{"region":{"name":"aws/us-east-1"},"zone":{"name":"aws/us-east-1a"}}}`), 0644))
+	latest := filepath.Join(dir, "latest")
+	assert.Nil(os.Symlink(filepath.Join(dir, "0b1f1e2e-S3"), latest))
+
+	os.Setenv("LOGSTASH_DCOS_FAULT_DOMAIN_FILE", domain)
+	os.Setenv("LOGSTASH_DCOS_AGENT_META", latest)
+	defer os.Unsetenv("LOGSTASH_DCOS_FAULT_DOMAIN_FILE")
+	defer os.Unsetenv("LOGSTASH_DCOS_AGENT_META")
+
+	node := GetDCOSNodeData()
+	assert.Equal(DCOSData{AgentID: "0b1f1e2e-S3", Region: "aws/us-east-1", Zone: "aws/us-east-1a"}, node)
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Env: []string{"MARATHON_APP_ID=/app"}}}
+	assert.Equal("marathon", GetDCOSData(&container, node).Framework)
+
+	container.Config.Env = []string{"FRAMEWORK_NAME=kafka", "MESOS_AGENT_ID=other-S1"}
+	d := GetDCOSData(&container, node)
+	assert.Equal("kafka", d.Framework)
+	assert.Equal("other-S1", d.AgentID)
+	assert.Equal("aws/us-east-1", d.Region)
+}
@@ -0,0 +1,77 @@
+package logstash
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func init() {
+	router.AdapterTransports.Register(new(debugTransport), "debug")
+}
+
+// debugTransport prints the documents to the standard output of logspout,
+// for routes such as logstash+debug://, so enrichment and formatting can be
+// checked without a Logstash.
+type debugTransport struct{}
+
+// Dial returns a connection to the standard output, which pretty-prints
+// documents if the debug_pretty route option or the LOGSTASH_DEBUG_PRETTY
+// environment variable is true.
+func (t *debugTransport) Dial(addr string, options map[string]string) (net.Conn, error) {
+	s, ok := options["debug_pretty"]
+	if !ok {
+		s = getopt("LOGSTASH_DEBUG_PRETTY", "false")
+	}
+	pretty, err := strconv.ParseBool(s)
+	if err != nil {
+		return nil, err
+	}
+	return &debugConn{w: os.Stdout, pretty: pretty}, nil
+}
+
+// debugConn writes documents to w, indented if pretty is set.
+type debugConn struct {
+	w      io.Writer
+	pretty bool
+	buf    bytes.Buffer
+}
+
+// Write writes b, one or more documents each ending with a newline. Those
+// that are not JSON, such as compressed frames, are written as they are.
+func (c *debugConn) Write(b []byte) (int, error) {
+	if !c.pretty {
+		return c.w.Write(b)
+	}
+	c.buf.Reset()
+	for _, doc := range bytes.SplitAfter(b, []byte("\n")) {
+		if len(doc) == 0 {
+			continue
+		}
+		mark := c.buf.Len()
+		if err := json.Indent(&c.buf, bytes.TrimRight(doc, "\n"), "", "  "); err != nil {
+			c.buf.Truncate(mark)
+			c.buf.Write(doc)
+			continue
+		}
+		c.buf.WriteByte('\n')
+	}
+	if _, err := c.w.Write(c.buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (c *debugConn) Close() error                       { return nil }
+func (c *debugConn) Read(b []byte) (int, error)         { return 0, io.EOF }
+func (c *debugConn) LocalAddr() net.Addr                { return localAddr{"debug", "stdout"} }
+func (c *debugConn) RemoteAddr() net.Addr               { return localAddr{"debug", "stdout"} }
+func (c *debugConn) SetDeadline(t time.Time) error      { return nil }
+func (c *debugConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *debugConn) SetWriteDeadline(t time.Time) error { return nil }
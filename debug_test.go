@@ -0,0 +1,59 @@
+package logstash
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugConn(t *testing.T) {
+	assert := assert.New(t)
+
+	var out bytes.Buffer
+	c := &debugConn{w: &out}
+	c.Write([]byte("{\"message\":\"line\"}\n"))
+	assert.Equal("{\"message\":\"line\"}\n", out.String())
+
+	out.Reset()
+	c.pretty = true
+	b := []byte("{\"message\":\"line\",\"tags\":[]}\n\x1f\x8b\n")
+	n, err := c.Write(b)
+	assert.Nil(err)
+	assert.Equal(len(b), n)
+	assert.Equal("{\n  \"message\": \"line\",\n  \"tags\": []\n}\n\x1f\x8b\n", out.String())
+}
+
+func TestDebugTransport(t *testing.T) {
+	assert := assert.New(t)
+
+	conn, err := new(debugTransport).Dial("", map[string]string{"debug_pretty": "true"})
+	if assert.Nil(err) {
+		assert.True(conn.(*debugConn).pretty)
+	}
+	_, err = new(debugTransport).Dial("", map[string]string{"debug_pretty": "very"})
+	assert.NotNil(err)
+}
+
+func TestStreamToDebug(t *testing.T) {
+	assert := assert.New(t)
+
+	var out bytes.Buffer
+	adapter := LogstashAdapter{route: new(router.Route), conn: &debugConn{w: &out, pretty: true}}
+
+	container := docker.Container{ID: "ID", Name: "/web", Config: &docker.Config{}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: "started", Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	assert.Contains(out.String(), "\n  \"message\": \"started\",\n")
+	assert.Contains(out.String(), "\n    \"name\": \"/web\",\n")
+}
@@ -0,0 +1,185 @@
+package logstash
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// decodeNested replaces JSON objects encoded as strings at paths of a decoded
+// JSON message with the objects. Only one level is decoded: the objects
+// themselves are left as they are.
+func decodeNested(data map[string]interface{}, paths [][]string) {
+	for _, path := range paths {
+		obj, ok := object(data, path[:len(path)-1], false)
+		if !ok {
+			continue
+		}
+		key := path[len(path)-1]
+		s, ok := obj[key].(string)
+		if !ok || !looksLikeJSON(s) {
+			continue
+		}
+		var nested map[string]interface{}
+		if json.Unmarshal([]byte(s), &nested) == nil && nested != nil {
+			obj[key] = nested
+		}
+	}
+}
+
+// nestJSON moves the fields of a decoded JSON message under key, except for
+// its message, so they can never collide with the fields of other
+// applications or of the adapter.
+func nestJSON(data map[string]interface{}, key string) {
+	nested := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		if k != "message" {
+			nested[k] = v
+			delete(data, k)
+		}
+	}
+	if len(nested) > 0 {
+		data[key] = nested
+	}
+}
+
+// sanitizeKeys rewrites the keys of a decoded JSON message, and of the
+// objects in it, that strict Elasticsearch mappings reject: dots are
+// replaced with underscores and leading underscores are stripped. Keys that
+// end up empty are dropped, as are those that end up equal to another key.
+func sanitizeKeys(data map[string]interface{}) {
+	for k, v := range data {
+		sanitizeValue(v)
+		key := strings.TrimLeft(strings.Replace(k, ".", "_", -1), "_")
+		if key == k && key != "" {
+			continue
+		}
+		delete(data, k)
+		if _, exists := data[key]; key != "" && !exists {
+			data[key] = v
+		}
+	}
+}
+
+func sanitizeValue(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		sanitizeKeys(v)
+	case []interface{}:
+		for _, e := range v {
+			sanitizeValue(e)
+		}
+	}
+}
+
+// flattenJSON moves the members of objects in a decoded JSON message to the
+// top level, joining the keys on the way with sep, e.g. user_address_city.
+// Keys have at most depth parts: objects and arrays found at that depth are
+// encoded as JSON strings, so the number of fields stays bounded. Where keys
+// collide, the value of the shallower key is kept.
+func flattenJSON(data map[string]interface{}, depth int, sep string) {
+	flat := make(map[string]interface{}, len(data))
+	flattenInto(flat, "", data, depth, sep)
+	for k := range data {
+		delete(data, k)
+	}
+	for k, v := range flat {
+		data[k] = v
+	}
+}
+
+func flattenInto(flat map[string]interface{}, prefix string, obj map[string]interface{}, depth int, sep string) {
+	for k, v := range obj {
+		key := prefix + k
+		if _, ok := v.(map[string]interface{}); ok && depth > 1 {
+			continue
+		}
+		if _, exists := flat[key]; exists {
+			continue
+		}
+		if depth == 1 {
+			switch v.(type) {
+			case map[string]interface{}, []interface{}:
+				if js, err := json.Marshal(v); err == nil {
+					v = string(js)
+				}
+			}
+		}
+		flat[key] = v
+	}
+	if depth > 1 {
+		for k, v := range obj {
+			if nested, ok := v.(map[string]interface{}); ok {
+				flattenInto(flat, prefix+k+sep, nested, depth-1, sep)
+			}
+		}
+	}
+}
+
+// fieldLimitTag tags events whose JSON message had more keys than allowed,
+// after the _jsonparsefailure tag of Logstash.
+const fieldLimitTag = "_jsonfieldlimit"
+
+// limitFields keeps at most max keys of a decoded JSON message: its message
+// and the first of the others in sorted order. The others are removed or,
+// with an overflow key, encoded together as a JSON string under it. It
+// reports whether keys were removed.
+func limitFields(data map[string]interface{}, max int, overflow string) bool {
+	if len(data) <= max {
+		return false
+	}
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		if k != "message" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	keep := max - (len(data) - len(keys))
+	if overflow != "" {
+		keep--
+	}
+	if keep < 0 {
+		keep = 0
+	}
+	excess := make(map[string]interface{}, len(keys)-keep)
+	for _, k := range keys[keep:] {
+		excess[k] = data[k]
+		delete(data, k)
+	}
+	if overflow != "" {
+		if js, err := json.Marshal(excess); err == nil {
+			data[overflow] = string(js)
+		}
+	}
+	return true
+}
+
+// stringValues replaces the numbers and booleans of a decoded JSON message,
+// and of the objects and arrays in it, with strings, so services logging the
+// same key with different types cannot cause mapping conflicts. Numbers keep
+// the text encoding/json gives them.
+func stringValues(data map[string]interface{}) {
+	for k, v := range data {
+		data[k] = stringValue(v)
+	}
+}
+
+func stringValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case float64:
+		return string(appendJSONFloat(nil, v))
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case map[string]interface{}:
+		stringValues(v)
+	case []interface{}:
+		for i, e := range v {
+			v[i] = stringValue(e)
+		}
+	}
+	return v
+}
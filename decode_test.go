@@ -0,0 +1,191 @@
+package logstash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeNested(t *testing.T) {
+	data := map[string]interface{}{
+		"log":     `{"level":"info","inner":"{\"deeper\":1}"}`,
+		"payload": `not json`,
+		"request": map[string]interface{}{"body": `{"id":7}`},
+		"array":   `[1,2]`,
+	}
+	decodeNested(data, [][]string{{"log"}, {"payload"}, {"request", "body"}, {"array"}, {"missing", "key"}})
+
+	assert.Equal(t, map[string]interface{}{
+		"log":     map[string]interface{}{"level": "info", "inner": `{"deeper":1}`},
+		"payload": `not json`,
+		"request": map[string]interface{}{"body": map[string]interface{}{"id": float64(7)}},
+		"array":   `[1,2]`,
+	}, data)
+}
+
+func TestSanitizeKeys(t *testing.T) {
+	data := map[string]interface{}{
+		"http.status": "200",
+		"_id":         "1",
+		"":            "empty",
+		"__":          "underscores",
+		"a.b":         "dropped",
+		"a_b":         "kept",
+		"user":        map[string]interface{}{"first.name": "Ann"},
+		"items":       []interface{}{map[string]interface{}{"_type": "book"}, "x.y"},
+	}
+	sanitizeKeys(data)
+
+	assert.Equal(t, map[string]interface{}{
+		"http_status": "200",
+		"id":          "1",
+		"a_b":         "kept",
+		"user":        map[string]interface{}{"first_name": "Ann"},
+		"items":       []interface{}{map[string]interface{}{"type": "book"}, "x.y"},
+	}, data)
+}
+
+func TestFlattenJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	message := func() map[string]interface{} {
+		return map[string]interface{}{
+			"message": "hi",
+			"user": map[string]interface{}{
+				"name":    "Ann",
+				"address": map[string]interface{}{"city": "Oslo", "geo": map[string]interface{}{"lat": 59.9}},
+				"roles":   []interface{}{"admin"},
+			},
+			"user_name": "kept",
+		}
+	}
+
+	data := message()
+	flattenJSON(data, 3, "_")
+	assert.Equal(map[string]interface{}{
+		"message":           "hi",
+		"user_name":         "kept",
+		"user_address_city": "Oslo",
+		"user_address_geo":  `{"lat":59.9}`,
+		"user_roles":        []interface{}{"admin"},
+	}, data)
+
+	data = message()
+	flattenJSON(data, 1, ".")
+	assert.Equal(map[string]interface{}{
+		"message":   "hi",
+		"user_name": "kept",
+		"user":      `{"address":{"city":"Oslo","geo":{"lat":59.9}},"name":"Ann","roles":["admin"]}`,
+	}, data)
+}
+
+func TestLimitFields(t *testing.T) {
+	assert := assert.New(t)
+
+	message := func() map[string]interface{} {
+		return map[string]interface{}{"message": "hi", "d": 4.0, "c": 3.0, "b": 2.0, "a": 1.0}
+	}
+
+	data := message()
+	assert.False(limitFields(data, 5, ""))
+	assert.Len(data, 5)
+
+	data = message()
+	assert.True(limitFields(data, 3, ""))
+	assert.Equal(map[string]interface{}{"message": "hi", "a": 1.0, "b": 2.0}, data)
+
+	data = message()
+	assert.True(limitFields(data, 3, "overflow"))
+	assert.Equal(map[string]interface{}{"message": "hi", "a": 1.0, "overflow": `{"b":2,"c":3,"d":4}`}, data)
+}
+
+func TestStreamWithMaxFields(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:     new(router.Route),
+		conn:      conn,
+		maxFields: 2,
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Env: []string{"LOGSTASH_TAGS=web"}}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: `{"message":"hi","a":1,"b":2}`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `{"message":"hi","a":1}`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 2) {
+		assert.Nil(lines[0]["b"])
+		assert.Equal([]interface{}{"web", "_jsonfieldlimit"}, lines[0]["tags"])
+		assert.Equal([]interface{}{"web"}, lines[1]["tags"])
+	}
+}
+
+func TestStringValues(t *testing.T) {
+	data := map[string]interface{}{
+		"status": 200.0,
+		"ratio":  0.25,
+		"huge":   1e21,
+		"ok":     true,
+		"none":   nil,
+		"user":   map[string]interface{}{"id": 7.0, "name": "Ann"},
+		"codes":  []interface{}{1.0, "two", false},
+	}
+	stringValues(data)
+
+	assert.Equal(t, map[string]interface{}{
+		"status": "200",
+		"ratio":  "0.25",
+		"huge":   "1e+21",
+		"ok":     "true",
+		"none":   nil,
+		"user":   map[string]interface{}{"id": "7", "name": "Ann"},
+		"codes":  []interface{}{"1", "two", "false"},
+	}, data)
+}
+
+func TestStreamWithJSONKey(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:   new(router.Route),
+		conn:    conn,
+		jsonKey: "app",
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Env: []string{"LOGSTASH_TAGS=web"}}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: `foo bananas`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `{"message":"started","tags":"app","user":{"id":7}}`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `{"message":"only"}`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 3) {
+		assert.Equal("foo bananas", lines[0]["message"])
+		assert.Nil(lines[0]["app"])
+
+		assert.Equal("started", lines[1]["message"])
+		assert.Equal([]interface{}{"web"}, lines[1]["tags"])
+		assert.Equal(map[string]interface{}{"tags": "app", "user": map[string]interface{}{"id": float64(7)}}, lines[1]["app"])
+
+		assert.Equal("only", lines[2]["message"])
+		assert.Nil(lines[2]["app"])
+	}
+}
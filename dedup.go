@@ -0,0 +1,68 @@
+package logstash
+
+import (
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// repeatedLine is a line held back while identical lines follow it.
+type repeatedLine struct {
+	message *router.Message
+	count   int
+	first   time.Time
+}
+
+// deduper collapses identical consecutive lines of a container and stream
+// into one event, so retry loops that print the same error over and over do
+// not flood Logstash.
+type deduper struct {
+	window time.Duration
+	held   map[string]*repeatedLine
+}
+
+// add holds m back while the lines after it repeat it, for at most window
+// since it arrived at now. emit gets the line with the number of times it
+// was seen once a different line arrives or the window is over.
+func (d *deduper) add(m *router.Message, now time.Time, emit func(*router.Message, int)) {
+	key := m.Container.ID + "\x00" + m.Source
+	if h := d.held[key]; h != nil {
+		if h.message.Data == m.Data && now.Sub(h.first) < d.window {
+			h.count++
+			return
+		}
+		delete(d.held, key)
+		emit(h.message, h.count)
+	}
+	if d.held == nil {
+		d.held = make(map[string]*repeatedLine)
+	}
+	d.held[key] = &repeatedLine{message: m, count: 1, first: now}
+}
+
+// expire emits the lines whose window is over at now.
+func (d *deduper) expire(now time.Time, emit func(*router.Message, int)) {
+	for key, h := range d.held {
+		if now.Sub(h.first) >= d.window {
+			delete(d.held, key)
+			emit(h.message, h.count)
+		}
+	}
+}
+
+// flush emits all held lines.
+func (d *deduper) flush(emit func(*router.Message, int)) {
+	for key, h := range d.held {
+		delete(d.held, key)
+		emit(h.message, h.count)
+	}
+}
+
+// repeatCount returns the repeat_count of an event seen repeats times, 0 for
+// events that were not repeated.
+func repeatCount(repeats int) int {
+	if repeats > 1 {
+		return repeats
+	}
+	return 0
+}
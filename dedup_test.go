@@ -0,0 +1,83 @@
+package logstash
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeduper(t *testing.T) {
+	assert := assert.New(t)
+
+	a := &docker.Container{ID: "a"}
+	b := &docker.Container{ID: "b"}
+
+	var emitted []string
+	emit := func(m *router.Message, n int) {
+		emitted = append(emitted, m.Container.ID+":"+m.Data+"*"+strconv.Itoa(n))
+	}
+
+	d := deduper{window: time.Second}
+	now := time.Now()
+	d.add(&router.Message{Container: a, Data: "retry"}, now, emit)
+	d.add(&router.Message{Container: a, Data: "retry"}, now, emit)
+	d.add(&router.Message{Container: b, Data: "retry"}, now, emit)
+	d.add(&router.Message{Container: a, Data: "retry", Source: "stderr"}, now, emit)
+	d.add(&router.Message{Container: a, Data: "retry"}, now.Add(500*time.Millisecond), emit)
+	d.add(&router.Message{Container: a, Data: "retry"}, now.Add(time.Second), emit)
+	d.add(&router.Message{Container: a, Data: "gave up"}, now.Add(time.Second), emit)
+	assert.Equal([]string{"a:retry*3", "a:retry*1"}, emitted)
+
+	emitted = nil
+	d.expire(now.Add(999*time.Millisecond), emit)
+	assert.Empty(emitted)
+	d.expire(now.Add(time.Second), emit)
+	assert.ElementsMatch([]string{"b:retry*1", "a:retry*1"}, emitted)
+
+	emitted = nil
+	d.flush(emit)
+	assert.Equal([]string{"a:gave up*1"}, emitted)
+	assert.Empty(d.held)
+}
+
+func TestStreamWithDedup(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:       new(router.Route),
+		conn:        conn,
+		dedupWindow: time.Minute,
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{}}
+	verbatim := docker.Container{ID: "verbatim", Config: &docker.Config{Labels: map[string]string{"logstash.dedup": "false"}}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		for i := 0; i < 1000; i++ {
+			logstream <- &router.Message{Container: &container, Data: `{"msg":"connection refused"}`, Time: time.Now()}
+		}
+		logstream <- &router.Message{Container: &container, Data: "connected", Time: time.Now()}
+		logstream <- &router.Message{Container: &verbatim, Data: "tick", Time: time.Now()}
+		logstream <- &router.Message{Container: &verbatim, Data: "tick", Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 4) {
+		assert.Equal("connection refused", lines[0]["msg"])
+		assert.Equal(1000.0, lines[0]["repeat_count"])
+		assert.Equal("tick", lines[1]["message"])
+		assert.Nil(lines[1]["repeat_count"])
+		assert.Equal("tick", lines[2]["message"])
+		assert.Equal("connected", lines[3]["message"])
+		assert.Nil(lines[3]["repeat_count"])
+	}
+}
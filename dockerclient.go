@@ -0,0 +1,49 @@
+package logstash
+
+import (
+	"strings"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// dockerClient is the part of the Docker API the adapter uses for metadata
+// that is not part of the container passed along with each message.
+type dockerClient interface {
+	InspectImage(name string) (*docker.Image, error)
+	Stats(opts docker.StatsOptions) error
+	InspectContainer(id string) (*docker.Container, error)
+	AddEventListener(listener chan<- *docker.APIEvents) error
+	RemoveEventListener(listener chan *docker.APIEvents) error
+	Logs(opts docker.LogsOptions) error
+}
+
+// newDockerClient connects to the Docker daemon configured through the
+// DOCKER_HOST environment, the same way logspout itself does.
+func newDockerClient() (dockerClient, error) {
+	return docker.NewClientFromEnv()
+}
+
+// imageDigest returns the repository digest of the container's image. Images
+// referenced by digest need no lookup, others are inspected through the
+// Docker API once per image, if a client is configured.
+func (a *LogstashAdapter) imageDigest(c *docker.Container) string {
+	if i := strings.LastIndex(c.Config.Image, "@"); i >= 0 {
+		return c.Config.Image[i+1:]
+	}
+	if a.docker == nil || c.Image == "" {
+		return ""
+	}
+	if digest, ok := a.imageDigests[c.Image]; ok {
+		return digest
+	}
+
+	var digest string
+	if image, err := a.docker.InspectImage(c.Image); err == nil && len(image.RepoDigests) > 0 {
+		digest = image.RepoDigests[0][strings.LastIndex(image.RepoDigests[0], "@")+1:]
+	}
+	if a.imageDigests == nil {
+		a.imageDigests = make(map[string]string)
+	}
+	a.imageDigests[c.Image] = digest
+	return digest
+}
@@ -0,0 +1,101 @@
+package logstash
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockDockerClient serves canned API responses.
+type MockDockerClient struct {
+	images     map[string]*docker.Image
+	inspects   int
+	stats      map[string]*docker.Stats
+	containers map[string]*docker.Container
+	listener   chan<- *docker.APIEvents
+	listeners  []chan<- *docker.APIEvents
+	mu         sync.Mutex
+	logs       map[string][2]string
+	since      int64
+}
+
+func (c *MockDockerClient) InspectImage(name string) (*docker.Image, error) {
+	c.inspects++
+	if image, ok := c.images[name]; ok {
+		return image, nil
+	}
+	return nil, errors.New("no such image: " + name)
+}
+
+func (c *MockDockerClient) Stats(opts docker.StatsOptions) error {
+	defer close(opts.Stats)
+	if stats, ok := c.stats[opts.ID]; ok {
+		opts.Stats <- stats
+		return nil
+	}
+	return errors.New("no such container: " + opts.ID)
+}
+
+func (c *MockDockerClient) InspectContainer(id string) (*docker.Container, error) {
+	if container, ok := c.containers[id]; ok {
+		return container, nil
+	}
+	return nil, errors.New("no such container: " + id)
+}
+
+func (c *MockDockerClient) AddEventListener(listener chan<- *docker.APIEvents) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listener = listener
+	c.listeners = append(c.listeners, listener)
+	return nil
+}
+
+// broadcast sends ev to every listener added so far.
+func (c *MockDockerClient) broadcast(ev *docker.APIEvents) {
+	c.mu.Lock()
+	listeners := c.listeners
+	c.mu.Unlock()
+	for _, listener := range listeners {
+		listener <- ev
+	}
+}
+
+func (c *MockDockerClient) RemoveEventListener(listener chan *docker.APIEvents) error {
+	return nil
+}
+
+func (c *MockDockerClient) Logs(opts docker.LogsOptions) error {
+	logs, ok := c.logs[opts.Container]
+	if !ok {
+		return errors.New("no such container: " + opts.Container)
+	}
+	c.since = opts.Since
+	opts.OutputStream.Write([]byte(logs[0]))
+	opts.ErrorStream.Write([]byte(logs[1]))
+	return nil
+}
+
+func TestImageDigest(t *testing.T) {
+	assert := assert.New(t)
+
+	client := &MockDockerClient{images: map[string]*docker.Image{
+		"sha256:0123": {ID: "sha256:0123", RepoDigests: []string{"example/shop@sha256:abcd"}},
+	}}
+	adapter := LogstashAdapter{docker: client}
+
+	pinned := docker.Container{ID: "pinned", Image: "sha256:0123", Config: &docker.Config{Image: "example/shop@sha256:ef01"}}
+	assert.Equal("sha256:ef01", adapter.imageDigest(&pinned))
+	assert.Equal(0, client.inspects)
+
+	tagged := docker.Container{ID: "tagged", Image: "sha256:0123", Config: &docker.Config{Image: "example/shop:1.6"}}
+	assert.Equal("sha256:abcd", adapter.imageDigest(&tagged))
+	assert.Equal("sha256:abcd", adapter.imageDigest(&tagged))
+	assert.Equal(1, client.inspects)
+
+	local := docker.Container{ID: "local", Image: "sha256:4567", Config: &docker.Config{Image: "shop:dev"}}
+	assert.Equal("", adapter.imageDigest(&local))
+}
@@ -0,0 +1,61 @@
+package logstash
+
+import (
+	"expvar"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+)
+
+// droppedEvents counts the events drop rules discarded, by rule, published
+// under "logstash_dropped" in expvar.
+var droppedEvents = expvar.NewMap("logstash_dropped")
+
+// containerDropRules returns the drop rules of a container: the one of the
+// adapter, then those of its logstash.drop_pattern label and
+// LOGSTASH_DROP_PATTERN environment variable, as well as any
+// LOGSTASH_DROP_PATTERN_ variables. Invalid expressions are logged and left
+// out.
+func (a *LogstashAdapter) containerDropRules(c *docker.Container) []*regexp.Regexp {
+	var rules []*regexp.Regexp
+	if a.dropPattern != nil {
+		rules = append(rules, a.dropPattern)
+	}
+
+	var exprs []string
+	if expr := c.Config.Labels["logstash.drop_pattern"]; expr != "" {
+		exprs = append(exprs, expr)
+	}
+	for _, e := range c.Config.Env {
+		if strings.HasPrefix(e, "LOGSTASH_DROP_PATTERN=") || strings.HasPrefix(e, "LOGSTASH_DROP_PATTERN_") {
+			if i := strings.IndexByte(e, '='); i >= 0 && i < len(e)-1 {
+				exprs = append(exprs, e[i+1:])
+			}
+		}
+	}
+
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			log.Println("logstash: invalid LOGSTASH_DROP_PATTERN of container", c.ID+":", err)
+			continue
+		}
+		rules = append(rules, re)
+	}
+	return rules
+}
+
+// dropped reports whether a drop rule of the container discards m, and
+// counts it for the first rule that matches.
+func dropped(m *router.Message, rules []*regexp.Regexp) bool {
+	for _, re := range rules {
+		if re.MatchString(m.Data) {
+			droppedEvents.Add(re.String(), 1)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package logstash
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerDropRules(t *testing.T) {
+	assert := assert.New(t)
+
+	adapter := &LogstashAdapter{dropPattern: regexp.MustCompile(`kube-probe/`)}
+	rules := adapter.containerDropRules(&docker.Container{Config: &docker.Config{
+		Env:    []string{`LOGSTASH_DROP_PATTERN=GET /healthz`, `LOGSTASH_DROP_PATTERN_2=(`, `LOGSTASH_DROP_PATTERN_3=^DEBUG`},
+		Labels: map[string]string{"logstash.drop_pattern": `ELB-HealthChecker`},
+	}})
+	var exprs []string
+	for _, re := range rules {
+		exprs = append(exprs, re.String())
+	}
+	assert.Equal([]string{`kube-probe/`, `ELB-HealthChecker`, `GET /healthz`, `^DEBUG`}, exprs)
+
+	assert.Empty((&LogstashAdapter{}).containerDropRules(&docker.Container{Config: &docker.Config{}}))
+}
+
+func TestStreamWithDropRules(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:       new(router.Route),
+		conn:        conn,
+		dropPattern: regexp.MustCompile(`"GET /ping `),
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Env: []string{`LOGSTASH_DROP_PATTERN=kube-probe/\d`}}}
+	other := docker.Container{ID: "other", Config: &docker.Config{}}
+
+	droppedCount := func(rule string) int64 {
+		if v, ok := droppedEvents.Get(rule).(interface{ Value() int64 }); ok {
+			return v.Value()
+		}
+		return 0
+	}
+	global, probes := droppedCount(`"GET /ping `), droppedCount(`kube-probe/\d`)
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: `10.0.0.1 - - "GET /ping HTTP/1.1" 200`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `10.0.0.2 - - "GET /ready HTTP/1.1" 200 "kube-probe/1.27"`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `10.0.0.3 - - "GET /orders HTTP/1.1" 200`, Time: time.Now()}
+		logstream <- &router.Message{Container: &other, Data: `10.0.0.1 - - "GET /ping HTTP/1.1" 200`, Time: time.Now()}
+		logstream <- &router.Message{Container: &other, Data: `"kube-probe/1.27"`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 2) {
+		assert.Equal(`10.0.0.3 - - "GET /orders HTTP/1.1" 200`, lines[0]["message"])
+		assert.Equal(`"kube-probe/1.27"`, lines[1]["message"])
+	}
+	assert.Equal(global+2, droppedCount(`"GET /ping `))
+	assert.Equal(probes+1, droppedCount(`kube-probe/\d`))
+}
@@ -0,0 +1,44 @@
+package logstash
+
+import (
+	"github.com/fsouza/go-dockerclient"
+)
+
+// ECSData describes the Amazon ECS task a container belongs to.
+type ECSData struct {
+	Cluster       string `json:"cluster,omitempty"`
+	TaskARN       string `json:"task_arn,omitempty"`
+	TaskFamily    string `json:"task_family,omitempty"`
+	TaskRevision  string `json:"task_revision,omitempty"`
+	ContainerName string `json:"container_name,omitempty"`
+}
+
+// GetECSData returns the ECS task information the ECS agent stores in the
+// com.amazonaws.ecs.* container labels, or nil if the container was not
+// scheduled by ECS.
+func GetECSData(c *docker.Container) *ECSData {
+	labels := c.Config.Labels
+	d := ECSData{
+		Cluster:       labels["com.amazonaws.ecs.cluster"],
+		TaskARN:       labels["com.amazonaws.ecs.task-arn"],
+		TaskFamily:    labels["com.amazonaws.ecs.task-definition-family"],
+		TaskRevision:  labels["com.amazonaws.ecs.task-definition-version"],
+		ContainerName: labels["com.amazonaws.ecs.container-name"],
+	}
+	if d == (ECSData{}) {
+		return nil
+	}
+	return &d
+}
+
+// appendJSON appends the JSON encoding of d to dst.
+func (d *ECSData) appendJSON(dst []byte) []byte {
+	dst = append(dst, '{')
+	n := len(dst)
+	dst = appendJSONStringField(dst, n, "cluster", d.Cluster)
+	dst = appendJSONStringField(dst, n, "task_arn", d.TaskARN)
+	dst = appendJSONStringField(dst, n, "task_family", d.TaskFamily)
+	dst = appendJSONStringField(dst, n, "task_revision", d.TaskRevision)
+	dst = appendJSONStringField(dst, n, "container_name", d.ContainerName)
+	return append(dst, '}')
+}
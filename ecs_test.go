@@ -0,0 +1,30 @@
+package logstash
+
+import (
+	"testing"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetECSData(t *testing.T) {
+	assert := assert.New(t)
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Labels: map[string]string{
+		"com.amazonaws.ecs.cluster":                 "production",
+		"com.amazonaws.ecs.task-arn":                "arn:aws:ecs:eu-west-1:123456789012:task/0b69d5c0-d655-4695-98cd-5d2d526d9d5a",
+		"com.amazonaws.ecs.task-definition-family":  "shop-web",
+		"com.amazonaws.ecs.task-definition-version": "42",
+		"com.amazonaws.ecs.container-name":          "web",
+	}}}
+	assert.Equal(&ECSData{
+		Cluster:       "production",
+		TaskARN:       "arn:aws:ecs:eu-west-1:123456789012:task/0b69d5c0-d655-4695-98cd-5d2d526d9d5a",
+		TaskFamily:    "shop-web",
+		TaskRevision:  "42",
+		ContainerName: "web",
+	}, GetECSData(&container))
+
+	container.Config.Labels = nil
+	assert.Nil(GetECSData(&container))
+}
@@ -0,0 +1,407 @@
+package logstash
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// The appendJSON methods below encode the plain text message path by hand.
+// Their output is byte for byte what encoding/json produces for the same
+// values, without the cost of reflection. Keep them in sync with the struct
+// tags when adding fields.
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSON appends the JSON encoding of m to dst.
+func (m *LogstashMessage) appendJSON(dst []byte) []byte {
+	dst = append(dst, '{')
+	if m.Timestamp != "" {
+		dst = append(dst, `"@timestamp":`...)
+		dst = appendJSONString(dst, m.Timestamp)
+		dst = append(dst, ',')
+	}
+	dst = append(dst, `"message":`...)
+	dst = appendJSONString(dst, m.Message)
+	dst = append(dst, `,"stream":`...)
+	dst = appendJSONString(dst, m.Stream)
+	dst = append(dst, `,"docker":`...)
+	dst = m.Docker.appendJSON(dst)
+	if m.Marathon != nil {
+		dst = append(dst, `,"marathon":`...)
+		dst = m.Marathon.appendJSON(dst)
+	}
+	if m.Mesos != nil {
+		dst = append(dst, `,"mesos":`...)
+		dst = m.Mesos.appendJSON(dst)
+	}
+	if m.Chronos != nil {
+		dst = append(dst, `,"chronos":`...)
+		dst = m.Chronos.appendJSON(dst)
+	}
+	if m.DCOS != nil {
+		dst = append(dst, `,"dcos":`...)
+		dst = m.DCOS.appendJSON(dst)
+	}
+	if m.Swarm != nil {
+		dst = append(dst, `,"swarm":`...)
+		dst = m.Swarm.appendJSON(dst)
+	}
+	if m.Compose != nil {
+		dst = append(dst, `,"compose":`...)
+		dst = m.Compose.appendJSON(dst)
+	}
+	if m.Rancher != nil {
+		dst = append(dst, `,"rancher":`...)
+		dst = m.Rancher.appendJSON(dst)
+	}
+	if m.Nomad != nil {
+		dst = append(dst, `,"nomad":`...)
+		dst = m.Nomad.appendJSON(dst)
+	}
+	if m.ECS != nil {
+		dst = append(dst, `,"ecs":`...)
+		dst = m.ECS.appendJSON(dst)
+	}
+	if m.Cloud != nil {
+		dst = append(dst, `,"cloud":`...)
+		dst = m.Cloud.appendJSON(dst)
+	}
+	if m.Node != nil {
+		dst = append(dst, `,"node":`...)
+		dst = m.Node.appendJSON(dst)
+	}
+	if m.Shipper != nil {
+		dst = append(dst, `,"shipper":`...)
+		dst = m.Shipper.appendJSON(dst)
+	}
+	dst = appendJSONStringField(dst, 0, "source_host", m.SourceHost)
+	if m.Stats != nil {
+		dst = append(dst, `,"stats":`...)
+		dst = m.Stats.appendJSON(dst)
+	}
+	dst = appendJSONMapField(dst, 0, "env", m.Env)
+	dst = appendJSONMapField(dst, 0, "image_meta", m.ImageMeta)
+	dst = appendJSONStringField(dst, 0, "severity", m.Severity)
+	dst = appendJSONStringField(dst, 0, "level", m.Level)
+	if m.Code != nil {
+		dst = append(dst, `,"severity_code":`...)
+		dst = strconv.AppendInt(dst, int64(*m.Code), 10)
+	}
+	dst = appendJSONStringField(dst, 0, "type", m.Type)
+	dst = appendJSONMapField(dst, 0, "@metadata", m.Metadata)
+	dst = appendJSONStringField(dst, 0, "event_id", m.EventID)
+	if m.Sequence != 0 {
+		dst = append(dst, `,"sequence":`...)
+		dst = strconv.AppendUint(dst, m.Sequence, 10)
+	}
+	if m.BadUTF8 {
+		dst = append(dst, `,"invalid_utf8":true`...)
+	}
+	if m.OrigLength != 0 {
+		dst = append(dst, `,"original_length":`...)
+		dst = strconv.AppendInt(dst, int64(m.OrigLength), 10)
+	}
+	if m.Chunk != nil {
+		dst = append(dst, `,"chunk":`...)
+		dst = m.Chunk.appendJSON(dst)
+	}
+	if m.Sampled {
+		dst = append(dst, `,"sampled":true`...)
+	}
+	if m.SampleRate != 0 {
+		dst = append(dst, `,"sample_rate":`...)
+		dst = strconv.AppendInt(dst, int64(m.SampleRate), 10)
+	}
+	if m.Repeats != 0 {
+		dst = append(dst, `,"repeat_count":`...)
+		dst = strconv.AppendInt(dst, int64(m.Repeats), 10)
+	}
+	dst = append(dst, `,"tags":`...)
+	dst = appendJSONStrings(dst, m.Tags)
+	dst = appendJSONMembers(dst, 0, m.Fields)
+	return append(dst, '}')
+}
+
+// appendJSON appends the JSON encoding of d to dst.
+func (d *DockerInfo) appendJSON(dst []byte) []byte {
+	dst = append(dst, `{"name":`...)
+	dst = appendJSONString(dst, d.Name)
+	dst = append(dst, `,"id":`...)
+	dst = appendJSONString(dst, d.ID)
+	dst = append(dst, `,"image":`...)
+	dst = appendJSONString(dst, d.Image)
+	dst = append(dst, `,"hostname":`...)
+	dst = appendJSONString(dst, d.Hostname)
+	dst = appendJSONStringField(dst, 0, "image_id", d.ImageID)
+	dst = appendJSONStringField(dst, 0, "image_digest", d.ImageDigest)
+	dst = appendJSONStringField(dst, 0, "created", d.Created)
+	dst = appendJSONStringField(dst, 0, "entrypoint", d.Entrypoint)
+	dst = appendJSONStringField(dst, 0, "command", d.Command)
+	dst = appendJSONStringsField(dst, 0, "networks", d.Networks)
+	dst = appendJSONStringsField(dst, 0, "ip_addresses", d.IPAddresses)
+	if len(d.Ports) > 0 {
+		dst = append(dst, `,"ports":[`...)
+		for i := range d.Ports {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = d.Ports[i].appendJSON(dst)
+		}
+		dst = append(dst, ']')
+	}
+	if d.State != nil {
+		dst = append(dst, `,"state":`...)
+		dst = d.State.appendJSON(dst)
+	}
+	dst = appendJSONMapField(dst, 0, "labels", d.Labels)
+	return append(dst, '}')
+}
+
+// appendJSON appends the JSON encoding of s to dst.
+func (s *ContainerState) appendJSON(dst []byte) []byte {
+	dst = append(dst, `{"restart_count":`...)
+	dst = strconv.AppendInt(dst, int64(s.RestartCount), 10)
+	dst = appendJSONStringField(dst, 0, "started_at", s.StartedAt)
+	dst = append(dst, `,"uptime":`...)
+	dst = strconv.AppendInt(dst, s.Uptime, 10)
+	return append(dst, '}')
+}
+
+// appendJSON appends the JSON encoding of p to dst.
+func (p *PortMapping) appendJSON(dst []byte) []byte {
+	dst = append(dst, '{')
+	n := len(dst)
+	dst = appendJSONStringField(dst, n, "host_ip", p.HostIP)
+	dst = appendJSONKey(dst, n, "host_port")
+	dst = appendJSONString(dst, p.HostPort)
+	dst = append(dst, `,"container_port":`...)
+	dst = appendJSONString(dst, p.ContainerPort)
+	dst = append(dst, `,"protocol":`...)
+	dst = appendJSONString(dst, p.Protocol)
+	return append(dst, '}')
+}
+
+// appendJSON appends the JSON encoding of u to dst.
+func (u *ResourceUsage) appendJSON(dst []byte) []byte {
+	dst = append(dst, `{"cpu_percent":`...)
+	dst = appendJSONFloat(dst, u.CPUPercent)
+	dst = append(dst, `,"memory_rss":`...)
+	dst = strconv.AppendUint(dst, u.MemoryRSS, 10)
+	if u.MemoryLimit != 0 {
+		dst = append(dst, `,"memory_limit":`...)
+		dst = strconv.AppendUint(dst, u.MemoryLimit, 10)
+	}
+	dst = append(dst, `,"sampled_at":`...)
+	dst = appendJSONString(dst, u.SampledAt)
+	return append(dst, '}')
+}
+
+// appendJSON appends the JSON encoding of m to dst.
+func (m *MarathonData) appendJSON(dst []byte) []byte {
+	dst = append(dst, '{')
+	n := len(dst)
+	dst = appendJSONStringField(dst, n, "version", m.Version)
+	dst = appendJSONValueMapField(dst, n, "resource", m.Resource)
+	dst = appendJSONStringField(dst, n, "id", m.ID)
+	dst = appendJSONMapField(dst, n, "label", m.Label)
+	dst = appendJSONStringField(dst, n, "image", m.Image)
+	return append(dst, '}')
+}
+
+// appendJSON appends the JSON encoding of m to dst.
+func (m *MesosData) appendJSON(dst []byte) []byte {
+	dst = append(dst, '{')
+	n := len(dst)
+	dst = appendJSONStringField(dst, n, "sandbox", m.Sandbox)
+	dst = appendJSONStringField(dst, n, "container_name", m.ContainerName)
+	dst = appendJSONStringField(dst, n, "task_id", m.Task)
+	return append(dst, '}')
+}
+
+// appendJSON appends the JSON encoding of m to dst.
+func (m *ChronosData) appendJSON(dst []byte) []byte {
+	dst = append(dst, '{')
+	n := len(dst)
+	dst = appendJSONStringField(dst, n, "job_name", m.JobName)
+	dst = appendJSONStringField(dst, n, "owner", m.Owner)
+	dst = appendJSONValueMapField(dst, n, "resource", m.Resource)
+	return append(dst, '}')
+}
+
+// appendJSONKey appends a key of an object starting at offset start of dst,
+// preceded by a comma unless it is the first one.
+func appendJSONKey(dst []byte, start int, key string) []byte {
+	if len(dst) > start {
+		dst = append(dst, ',')
+	}
+	dst = appendJSONString(dst, key)
+	return append(dst, ':')
+}
+
+// appendJSONStringField appends an omitempty string field.
+func appendJSONStringField(dst []byte, start int, key, value string) []byte {
+	if value == "" {
+		return dst
+	}
+	dst = appendJSONKey(dst, start, key)
+	return appendJSONString(dst, value)
+}
+
+// appendJSONMapField appends an omitempty map field with sorted keys.
+func appendJSONMapField(dst []byte, start int, key string, value map[string]string) []byte {
+	if len(value) == 0 {
+		return dst
+	}
+	dst = appendJSONKey(dst, start, key)
+	dst = append(dst, '{')
+	dst = appendJSONMembers(dst, len(dst), value)
+	return append(dst, '}')
+}
+
+// appendJSONMembers appends the members of value to an object starting at
+// offset start of dst, with sorted keys.
+func appendJSONMembers(dst []byte, start int, value map[string]string) []byte {
+	keys := make([]string, 0, len(value))
+	for k := range value {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		dst = appendJSONKey(dst, start, k)
+		dst = appendJSONString(dst, value[k])
+	}
+	return dst
+}
+
+// appendJSONValueMapField appends an omitempty map field whose values are
+// strings or float64s, with sorted keys.
+func appendJSONValueMapField(dst []byte, start int, key string, value map[string]interface{}) []byte {
+	if len(value) == 0 {
+		return dst
+	}
+	dst = appendJSONKey(dst, start, key)
+
+	keys := make([]string, 0, len(value))
+	for k := range value {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	dst = append(dst, '{')
+	for i, k := range keys {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = appendJSONString(dst, k)
+		dst = append(dst, ':')
+		switch v := value[k].(type) {
+		case float64:
+			dst = appendJSONFloat(dst, v)
+		case string:
+			dst = appendJSONString(dst, v)
+		default:
+			dst = append(dst, "null"...)
+		}
+	}
+	return append(dst, '}')
+}
+
+// appendJSONFloat appends a finite float64 formatted like encoding/json.
+func appendJSONFloat(dst []byte, f float64) []byte {
+	abs := math.Abs(f)
+	format := byte('f')
+	if abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	dst = strconv.AppendFloat(dst, f, format, -1, 64)
+	if format == 'e' {
+		// Clean up e-09 to e-9
+		n := len(dst)
+		if n >= 4 && dst[n-4] == 'e' && dst[n-3] == '-' && dst[n-2] == '0' {
+			dst[n-2] = dst[n-1]
+			dst = dst[:n-1]
+		}
+	}
+	return dst
+}
+
+// appendJSONStringsField appends an omitempty string array field.
+func appendJSONStringsField(dst []byte, start int, key string, values []string) []byte {
+	if len(values) == 0 {
+		return dst
+	}
+	dst = appendJSONKey(dst, start, key)
+	return appendJSONStrings(dst, values)
+}
+
+// appendJSONStrings appends a string array, or null for a nil slice.
+func appendJSONStrings(dst []byte, values []string) []byte {
+	if values == nil {
+		return append(dst, "null"...)
+	}
+	dst = append(dst, '[')
+	for i, v := range values {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = appendJSONString(dst, v)
+	}
+	return append(dst, ']')
+}
+
+// appendJSONString appends s as a quoted JSON string, escaping it exactly
+// like encoding/json does, HTML characters included.
+func appendJSONString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' && b != '<' && b != '>' && b != '&' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch b {
+			case '\\', '"':
+				dst = append(dst, '\\', b)
+			case '\b':
+				dst = append(dst, '\\', 'b')
+			case '\f':
+				dst = append(dst, '\\', 'f')
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hexDigits[b>>4], hexDigits[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if c == utf8.RuneError && size == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 and U+2029 are valid JSON but break JavaScript parsers.
+		if c == '\u2028' || c == '\u2029' {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hexDigits[c&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
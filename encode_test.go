@@ -0,0 +1,92 @@
+package logstash
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppendJSONMatchesEncodingJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	messages := []LogstashMessage{
+		{},
+		{
+			Timestamp: "2016-10-20T13:25:13.627Z",
+			Message:   "plain line",
+			Stream:    "stdout",
+			Docker:    DockerInfo{Name: "/name", ID: "ID", Image: "image:1.0", Hostname: "hostname"},
+			Tags:      []string{},
+		},
+		{
+			Message: "quotes \" backslash \\ html <a href=\"x\">&amp;</a> ctrl \x00\x01\b\f\n\r\t\x1f",
+			Stream:  "stderr",
+			Docker:  DockerInfo{Name: "ünïcødé", ID: "line sep ", Image: "bad \xff\xfe utf8", ImageID: "sha256:0123", Created: "2016-10-20T13:25:13.627Z", Entrypoint: "/bin/sh -c", Command: "echo \"<hi>\"", Networks: []string{"bridge"}, IPAddresses: []string{"172.17.0.2", "fd00::5"}, Ports: []PortMapping{{HostIP: "0.0.0.0", HostPort: "80", ContainerPort: "8080", Protocol: "tcp"}, {HostPort: "53", ContainerPort: "53", Protocol: "udp"}}, State: &ContainerState{RestartCount: 2, StartedAt: "2016-10-20T13:25:14Z", Uptime: 42}, Labels: map[string]string{"b": "2", "a": "<1>"}},
+			Marathon: &MarathonData{
+				Version:  "2016-10-20T13:25:13.627Z",
+				Resource: map[string]interface{}{"mem": 128.0, "cpus": 0.01, "disk": 0.0, "gpus": "n/a", "tiny": 1e-9, "huge": 1e22},
+				ID:       "/flapjack-notifier",
+				Label:    map[string]string{"VERSION": "1.6", "ENVIRONMENT": "prod"},
+				Image:    "registry:5000/flapjack:1.6",
+			},
+			Mesos: &MesosData{
+				Sandbox:       "/mnt/mesos/sandbox",
+				ContainerName: "mesos-04fb9b4e",
+				Task:          "flapjack-notifier.c101b8cd",
+			},
+			Tags: []string{"a", "b<c>"},
+		},
+		{
+			Marathon: &MarathonData{Label: map[string]string{"ONLY": "label"}},
+			Mesos:    &MesosData{Task: "task"},
+			Chronos: &ChronosData{
+				JobName:  "nightly-report",
+				Owner:    "ops@example.com",
+				Resource: map[string]interface{}{"cpu": 0.5, "mem": 512.0},
+			},
+			DCOS:       &DCOSData{Framework: "marathon", Zone: "us-east-1a"},
+			Swarm:      &SwarmData{ServiceName: "web", Stack: "shop"},
+			Compose:    &ComposeData{Project: "shop", Service: "web", ContainerNumber: "1"},
+			Rancher:    &RancherData{Stack: "shop", Service: "web"},
+			Nomad:      &NomadData{JobName: "shop", Datacenter: "dc1"},
+			ECS:        &ECSData{Cluster: "production", TaskRevision: "42"},
+			Cloud:      &CloudData{Provider: "aws", Region: "eu-west-1"},
+			Node:       &HostData{Hostname: "node-1", Kernel: "4.9.0"},
+			SourceHost: "node-1.example.com",
+			Shipper:    &ShipperInfo{Name: "logspout-logstash", Version: "v3.2.0", ID: "edge-7"},
+			Stats:      &ResourceUsage{CPUPercent: 12.5, MemoryRSS: 52428800, SampledAt: "2016-10-20T13:25:13.627Z"},
+			Env:        map[string]string{"SERVICE_NAME": "web", "GIT_SHA": "abc123"},
+			ImageMeta:  map[string]string{"version": "1.6.0", "revision": "def456"},
+			Severity:   "error",
+			Level:      "error",
+			Code:       &levelCodes[3],
+			Type:       "nginx-access",
+			Metadata:   map[string]string{"index": "logs-payments"},
+			EventID:    "6ba7b810-9dad-41d1-80b4-00c04fd430c8",
+			Sequence:   18446744073709551615,
+			BadUTF8:    true,
+			OrigLength: 1048576,
+			Chunk:      &ChunkInfo{ID: "6ba7b810-9dad-41d1-80b4-00c04fd430c8", Index: 0, Count: 3},
+			Sampled:    true,
+			SampleRate: 100,
+			Repeats:    2500,
+		},
+	}
+
+	for _, m := range messages {
+		expected, err := json.Marshal(m)
+		assert.Nil(err)
+		assert.Equal(string(expected), string(m.appendJSON(nil)))
+	}
+}
+
+func TestAppendJSONFields(t *testing.T) {
+	assert := assert.New(t)
+
+	m := LogstashMessage{Message: "line", Tags: []string{}, Fields: map[string]string{"team": "payments", "stage": "<prod>"}}
+	expected, err := json.Marshal(m)
+	assert.Nil(err)
+	expected = append(expected[:len(expected)-1], `,"stage":"\u003cprod\u003e","team":"payments"}`...)
+	assert.Equal(string(expected), string(m.appendJSON(nil)))
+}
@@ -0,0 +1,109 @@
+package logstash
+
+import (
+	"github.com/fsouza/go-dockerclient"
+)
+
+// Enricher is a source of metadata about containers, such as an inventory
+// or a service catalog, that the adapter adds to events next to the
+// metadata of its own.
+type Enricher interface {
+	// Enrich returns the metadata of a container, or nil if it has none.
+	// The result must encode to JSON. Enrich is called when a container
+	// is first seen, and again once it is restarted or renamed; its events
+	// share the result in the meantime.
+	Enrich(c *docker.Container) interface{}
+}
+
+// EnricherFunc adapts a function to the Enricher interface.
+type EnricherFunc func(c *docker.Container) interface{}
+
+// Enrich calls f(c).
+func (f EnricherFunc) Enrich(c *docker.Container) interface{} {
+	return f(c)
+}
+
+// namedEnricher is a registered enricher along with the key of its block.
+type namedEnricher struct {
+	name     string
+	enricher Enricher
+}
+
+// enrichers are the registered enrichers, in order. The adapter registers
+// its own first, for the docker, tags, marathon, mesos, chronos, dcos,
+// swarm, compose, rancher, nomad and ecs blocks.
+var enrichers []namedEnricher
+
+// enricherIndex returns the index of the enricher of name in enrichers, or
+// -1 if there is none.
+func enricherIndex(name string) int {
+	for i, registered := range enrichers {
+		if registered.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// RegisterEnricher adds the metadata of an enricher to all events, as a
+// block under key name next to the docker block, e.g.
+//
+//	func init() {
+//		logstash.RegisterEnricher("owner", logstash.EnricherFunc(lookupOwner))
+//	}
+//
+// It is meant to be called from init functions, and panics if name is
+// empty, is a key the adapter uses or was registered before. Events whose
+// message has a key of the same name keep theirs.
+func RegisterEnricher(name string, e Enricher) {
+	RegisterEnricherBefore(name, "", e)
+}
+
+// RegisterEnricherBefore is like RegisterEnricher, but runs the enricher
+// before the one registered as before, or last if before is empty. It
+// panics if there is no enricher of that name.
+func RegisterEnricherBefore(name, before string, e Enricher) {
+	if name == "" || reservedFields[name] {
+		panic("logstash: invalid enricher name " + name)
+	}
+	if enricherIndex(name) >= 0 {
+		panic("logstash: enricher " + name + " registered twice")
+	}
+	i := len(enrichers)
+	if before != "" {
+		if i = enricherIndex(before); i < 0 {
+			panic("logstash: no enricher " + before)
+		}
+	}
+	registered := make([]namedEnricher, 0, len(enrichers)+1)
+	registered = append(registered, enrichers[:i]...)
+	registered = append(registered, namedEnricher{name: name, enricher: e})
+	enrichers = append(registered, enrichers[i:]...)
+}
+
+// ReplaceEnricher replaces the enricher registered as name, which may be
+// one of the adapter's own, e.g. to look up the marathon block of
+// containers elsewhere. The block of a replaced enricher of the adapter
+// is left out of events if the new one returns nil. It panics if there is
+// no enricher of that name.
+func ReplaceEnricher(name string, e Enricher) {
+	i := enricherIndex(name)
+	if i < 0 {
+		panic("logstash: no enricher " + name)
+	}
+	registered := append([]namedEnricher(nil), enrichers...)
+	registered[i].enricher = e
+	enrichers = registered
+}
+
+// DisableEnricher removes the enricher registered as name, which may be one
+// of the adapter's own, so that events go without its block. It panics if
+// there is no enricher of that name.
+func DisableEnricher(name string) {
+	i := enricherIndex(name)
+	if i < 0 {
+		panic("logstash: no enricher " + name)
+	}
+	registered := append([]namedEnricher(nil), enrichers[:i]...)
+	enrichers = append(registered, enrichers[i+1:]...)
+}
@@ -0,0 +1,145 @@
+package logstash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterEnricher(t *testing.T) {
+	assert := assert.New(t)
+
+	registered := enrichers
+	defer func() { enrichers = registered }()
+
+	owner := EnricherFunc(func(c *docker.Container) interface{} {
+		if team := c.Config.Labels["team"]; team != "" {
+			return map[string]string{"team": team}
+		}
+		return nil
+	})
+	RegisterEnricher("owner", owner)
+	assert.Panics(func() { RegisterEnricher("owner", owner) })
+	assert.Panics(func() { RegisterEnricher("docker", owner) })
+	assert.Panics(func() { RegisterEnricher("", owner) })
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route: new(router.Route),
+		conn:  conn,
+	}
+
+	owned := docker.Container{ID: "owned", Config: &docker.Config{Labels: map[string]string{"team": "payments"}}}
+	orphan := docker.Container{ID: "orphan", Config: &docker.Config{}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &owned, Data: "started", Time: time.Now()}
+		logstream <- &router.Message{Container: &owned, Data: `{"owner":"app"}`, Time: time.Now()}
+		logstream <- &router.Message{Container: &orphan, Data: "started", Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 3) {
+		assert.Equal(map[string]interface{}{"team": "payments"}, lines[0]["owner"])
+		assert.Equal("owned", lines[0]["docker"].(map[string]interface{})["id"])
+		assert.Equal("app", lines[1]["owner"])
+		assert.Nil(lines[2]["owner"])
+	}
+}
+
+func TestEnricherBlockIsNotShared(t *testing.T) {
+	assert := assert.New(t)
+
+	registered := enrichers
+	defer func() { enrichers = registered }()
+
+	RegisterEnricher("owner", EnricherFunc(func(c *docker.Container) interface{} {
+		return map[string]interface{}{"team": "payments"}
+	}))
+
+	renames, err := parseRenames("owner.team:team")
+	assert.Nil(err)
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:   new(router.Route),
+		conn:    conn,
+		renames: renames,
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: "first", Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: "second", Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 2) {
+		for _, line := range lines {
+			assert.Equal("payments", line["team"], line["message"])
+		}
+	}
+}
+
+func TestReplaceAndDisableEnrichers(t *testing.T) {
+	assert := assert.New(t)
+
+	registered := enrichers
+	defer func() { enrichers = registered }()
+
+	var order []string
+	ReplaceEnricher("marathon", EnricherFunc(func(c *docker.Container) interface{} {
+		order = append(order, "marathon")
+		return map[string]string{"app": "/payments"}
+	}))
+	ReplaceEnricher("compose", EnricherFunc(func(c *docker.Container) interface{} {
+		return nil
+	}))
+	DisableEnricher("docker")
+	RegisterEnricherBefore("owner", "marathon", EnricherFunc(func(c *docker.Container) interface{} {
+		order = append(order, "owner")
+		return "payments"
+	}))
+	assert.Panics(func() { ReplaceEnricher("unknown", EnricherFunc(nil)) })
+	assert.Panics(func() { DisableEnricher("docker") })
+	assert.Panics(func() { RegisterEnricherBefore("team", "unknown", EnricherFunc(nil)) })
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route: new(router.Route),
+		conn:  conn,
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Labels: map[string]string{
+		"com.docker.compose.project": "shop",
+	}}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: "started", Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 1) {
+		assert.Equal(map[string]interface{}{"app": "/payments"}, lines[0]["marathon"])
+		assert.Equal("payments", lines[0]["owner"])
+		assert.NotContains(lines[0], "docker")
+		assert.NotContains(lines[0], "compose")
+		assert.Contains(lines[0], "tags")
+	}
+	assert.Equal([]string{"owner", "marathon"}, order)
+}
@@ -0,0 +1,146 @@
+package logstash
+
+import (
+	"encoding/json"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+)
+
+// dockerEventSource is the stream of messages made from Docker lifecycle
+// events, as opposed to stdout and stderr.
+const dockerEventSource = "docker_event"
+
+// DockerEvent describes a container lifecycle event reported by the Docker
+// daemon.
+type DockerEvent struct {
+	Action   string `json:"action"`
+	ExitCode *int   `json:"exit_code,omitempty"`
+	Signal   string `json:"signal,omitempty"`
+}
+
+// withDockerEvents returns a stream with the messages of logstream and a
+// message for every container lifecycle event in LOGSTASH_DOCKER_EVENTS.
+// It closes once logstream does. If the daemon cannot be subscribed to,
+// logstream is returned as is.
+func (a *LogstashAdapter) withDockerEvents(logstream chan *router.Message) chan *router.Message {
+	listener := make(chan *docker.APIEvents, a.stageBuffer)
+	if err := a.events.AddEventListener(listener); err != nil {
+		log.Println("logstash: could not listen for docker events:", err)
+		return logstream
+	}
+
+	merged := make(chan *router.Message, a.stageBuffer)
+	go func() {
+		defer close(merged)
+		defer a.events.RemoveEventListener(listener)
+		events := listener
+		for {
+			select {
+			case m, ok := <-logstream:
+				if !ok {
+					return
+				}
+				merged <- m
+			case ev, ok := <-events:
+				if !ok {
+					events = nil
+					continue
+				}
+				if m := a.dockerEventMessage(ev); m != nil {
+					merged <- m
+				}
+			}
+		}
+	}()
+	return merged
+}
+
+// dockerEventMessage turns a Docker event into a message of its container,
+// or returns nil for events that are not shipped.
+func (a *LogstashAdapter) dockerEventMessage(ev *docker.APIEvents) *router.Message {
+	action, id := ev.Action, ev.Actor.ID
+	if action == "" {
+		// API versions before 1.22
+		action, id = ev.Status, ev.ID
+	}
+	if (ev.Type != "" && ev.Type != "container") || !a.dockerEvents[strings.ToUpper(action)] {
+		return nil
+	}
+
+	container, err := a.events.InspectContainer(id)
+	if err != nil {
+		// The container may be gone already, the event still names it.
+		attrs := ev.Actor.Attributes
+		container = &docker.Container{ID: id, Name: "/" + attrs["name"], Config: &docker.Config{Image: attrs["image"]}}
+	}
+	if !routeFilters(a.route, container, dockerEventSource) {
+		return nil
+	}
+
+	data := struct {
+		Message string      `json:"message"`
+		Event   DockerEvent `json:"docker_event"`
+	}{
+		Message: "container " + action,
+		Event:   DockerEvent{Action: action, Signal: ev.Actor.Attributes["signal"]},
+	}
+	if code, err := strconv.Atoi(ev.Actor.Attributes["exitCode"]); err == nil {
+		data.Event.ExitCode = &code
+	}
+	js, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+
+	t := time.Unix(0, ev.TimeNano)
+	if ev.TimeNano == 0 {
+		t = time.Unix(ev.Time, 0)
+	}
+	return &router.Message{Container: container, Source: dockerEventSource, Data: string(js), Time: t}
+}
+
+// routeFilters reports whether the filters of route let the messages of
+// source from c through, as logspout does for the logs it pumps to the
+// route. Docker events do not go through logspout, so the adapter applies
+// them itself.
+func routeFilters(route *router.Route, c *docker.Container, source string) bool {
+	if route == nil {
+		return true
+	}
+	if route.FilterID != "" && !strings.HasPrefix(c.ID, route.FilterID) {
+		return false
+	}
+	if route.FilterName != "" {
+		if ok, err := path.Match(route.FilterName, strings.TrimPrefix(c.Name, "/")); err != nil || !ok {
+			return false
+		}
+	}
+	var labels map[string]string
+	if c.Config != nil {
+		labels = c.Config.Labels
+	}
+	for _, filter := range route.FilterLabels {
+		kv := strings.SplitN(filter, ":", 2)
+		if len(kv) < 2 || kv[1] == "" {
+			continue
+		}
+		if ok, err := path.Match(kv[1], labels[kv[0]]); err != nil || !ok {
+			return false
+		}
+	}
+	if len(route.FilterSources) == 0 {
+		return true
+	}
+	for _, s := range route.FilterSources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package logstash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDockerEventMessage(t *testing.T) {
+	assert := assert.New(t)
+
+	container := &docker.Container{ID: "ID", Name: "/shop", Config: &docker.Config{Image: "shop:1.6"}}
+	client := &MockDockerClient{containers: map[string]*docker.Container{"ID": container}}
+	adapter := LogstashAdapter{events: client, dockerEvents: map[string]bool{"DIE": true}}
+
+	m := adapter.dockerEventMessage(&docker.APIEvents{
+		Action:   "die",
+		Type:     "container",
+		Actor:    docker.APIActor{ID: "ID", Attributes: map[string]string{"exitCode": "137", "name": "shop"}},
+		TimeNano: 1476969913627000000,
+	})
+	if assert.NotNil(m) {
+		assert.Equal(container, m.Container)
+		assert.Equal(dockerEventSource, m.Source)
+		assert.Equal(`{"message":"container die","docker_event":{"action":"die","exit_code":137}}`, m.Data)
+		assert.Equal(time.Unix(0, 1476969913627000000), m.Time)
+	}
+
+	// Removed containers are described from the event attributes.
+	m = adapter.dockerEventMessage(&docker.APIEvents{Status: "die", ID: "gone", From: "shop:1.5"})
+	if assert.NotNil(m) {
+		assert.Equal("gone", m.Container.ID)
+	}
+
+	assert.Nil(adapter.dockerEventMessage(&docker.APIEvents{Action: "start", Type: "container", Actor: docker.APIActor{ID: "ID"}}))
+	assert.Nil(adapter.dockerEventMessage(&docker.APIEvents{Action: "die", Type: "network", Actor: docker.APIActor{ID: "ID"}}))
+}
+
+func TestStreamDockerEvents(t *testing.T) {
+	assert := assert.New(t)
+
+	container := &docker.Container{ID: "ID", Name: "/shop", Config: &docker.Config{Image: "shop:1.6"}}
+	client := &MockDockerClient{containers: map[string]*docker.Container{"ID": container}}
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:        new(router.Route),
+		conn:         conn,
+		events:       client,
+		dockerEvents: map[string]bool{"OOM": true},
+	}
+
+	logstream := make(chan *router.Message)
+	merged := adapter.withDockerEvents(logstream)
+	client.listener <- &docker.APIEvents{Action: "oom", Type: "container", Actor: docker.APIActor{ID: "ID"}}
+	event := <-merged
+	logstream <- &router.Message{Container: container, Source: "stdout", Data: "out of memory", Time: time.Now()}
+	close(logstream)
+
+	events := make(chan *router.Message, 2)
+	events <- event
+	events <- <-merged
+	close(events)
+	adapter.events = nil
+	adapter.Stream(events)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 2) {
+		assert.Equal("container oom", lines[0]["message"])
+		assert.Equal(dockerEventSource, lines[0]["stream"])
+		assert.Equal(map[string]interface{}{"action": "oom"}, lines[0]["docker_event"])
+		assert.Equal([]interface{}{dockerEventSource}, lines[0]["tags"])
+		assert.Equal("out of memory", lines[1]["message"])
+		assert.Equal([]interface{}{}, lines[1]["tags"])
+	}
+}
+
+func TestDockerEventRouteFilters(t *testing.T) {
+	assert := assert.New(t)
+
+	shop := &docker.Container{ID: "shop1", Name: "/shop", Config: &docker.Config{Image: "shop:1.6", Labels: map[string]string{"team": "payments"}}}
+	db := &docker.Container{ID: "db1", Name: "/db", Config: &docker.Config{Image: "postgres:16"}}
+	client := &MockDockerClient{containers: map[string]*docker.Container{"shop1": shop, "db1": db}}
+
+	var tests = []struct {
+		route    router.Route
+		shop, db bool
+	}{
+		{router.Route{}, true, true},
+		{router.Route{FilterID: "shop"}, true, false},
+		{router.Route{FilterName: "sh*"}, true, false},
+		{router.Route{FilterLabels: []string{"team:pay*"}}, true, false},
+		{router.Route{FilterSources: []string{"stdout"}}, false, false},
+		{router.Route{FilterSources: []string{"stdout", dockerEventSource}}, true, true},
+	}
+	for _, test := range tests {
+		route := test.route
+		adapter := LogstashAdapter{
+			route:        &route,
+			events:       client,
+			dockerEvents: map[string]bool{"DIE": true},
+		}
+		for id, shipped := range map[string]bool{"shop1": test.shop, "db1": test.db} {
+			m := adapter.dockerEventMessage(&docker.APIEvents{Action: "die", Type: "container", Actor: docker.APIActor{ID: id}})
+			assert.Equal(shipped, m != nil, "%+v %s", test.route, id)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package logstash
+
+import (
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// GetContainerExtractors returns the regular expressions a container
+// declares: the built-in pattern it selects with LOGSTASH_PATTERN or the
+// logstash.pattern label, then those of its logstash.extract label and
+// LOGSTASH_EXTRACT environment variable, as well as any LOGSTASH_EXTRACT_
+// variables such as LOGSTASH_EXTRACT_2, in that order. Unknown patterns and
+// invalid expressions are logged and left out.
+func GetContainerExtractors(c *docker.Container) []*regexp.Regexp {
+	var extractors []*regexp.Regexp
+	if name := containerSetting(c, "LOGSTASH_PATTERN", "logstash.pattern"); name != "" {
+		if re, ok := patterns[strings.ToLower(name)]; ok {
+			extractors = append(extractors, re)
+		} else {
+			log.Println("logstash: unknown LOGSTASH_PATTERN of container", c.ID+":", name)
+		}
+	}
+
+	var exprs []string
+	if expr := c.Config.Labels["logstash.extract"]; expr != "" {
+		exprs = append(exprs, expr)
+	}
+	for _, e := range c.Config.Env {
+		if strings.HasPrefix(e, "LOGSTASH_EXTRACT=") || strings.HasPrefix(e, "LOGSTASH_EXTRACT_") {
+			if i := strings.IndexByte(e, '='); i >= 0 && i < len(e)-1 {
+				exprs = append(exprs, e[i+1:])
+			}
+		}
+	}
+
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			log.Println("logstash: invalid LOGSTASH_EXTRACT of container", c.ID+":", err)
+			continue
+		}
+		extractors = append(extractors, re)
+	}
+	return extractors
+}
+
+// extractFields matches a line against regular expressions and returns the
+// named groups of those that match. Where groups of several expressions have
+// the same name, the first expression wins.
+func extractFields(line string, extractors []*regexp.Regexp) map[string]interface{} {
+	var fields map[string]interface{}
+	for _, re := range extractors {
+		match := re.FindStringSubmatchIndex(line)
+		if match == nil {
+			continue
+		}
+		for i, name := range re.SubexpNames() {
+			if name == "" || match[2*i] < 0 {
+				continue
+			}
+			if fields == nil {
+				fields = make(map[string]interface{})
+			}
+			if _, exists := fields[name]; !exists {
+				fields[name] = line[match[2*i]:match[2*i+1]]
+			}
+		}
+	}
+	return fields
+}
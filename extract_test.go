@@ -0,0 +1,73 @@
+package logstash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetContainerExtractors(t *testing.T) {
+	assert := assert.New(t)
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{
+		Env: []string{
+			`LOGSTASH_EXTRACT=took (?P<took>\d+)ms`,
+			`LOGSTASH_EXTRACT_USER=user=(?P<user>\w+)`,
+			`LOGSTASH_EXTRACT_BROKEN=(?P<x`,
+			`LOGSTASH_EXTRACTOR=ignored`,
+		},
+		Labels: map[string]string{"logstash.extract": `^(?P<level>[A-Z]+) `},
+	}}
+
+	var exprs []string
+	for _, re := range GetContainerExtractors(&container) {
+		exprs = append(exprs, re.String())
+	}
+	assert.Equal([]string{`^(?P<level>[A-Z]+) `, `took (?P<took>\d+)ms`, `user=(?P<user>\w+)`}, exprs)
+	assert.Nil(GetContainerExtractors(&docker.Container{ID: "ID", Config: &docker.Config{}}))
+}
+
+func TestStreamWithExtract(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route: new(router.Route),
+		conn:  conn,
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Env: []string{
+		`LOGSTASH_EXTRACT=^(?P<level>[A-Z]+) (?:user=(?P<user>\w+) )?(?P<message>.*)`,
+		`LOGSTASH_EXTRACT_2=took (?P<took>\d+)ms`,
+	}}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: `INFO user=ann request took 12ms`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `INFO started`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `no match`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `{"status":"200"}`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 4) {
+		assert.Equal("INFO user=ann request took 12ms", lines[0]["message"])
+		assert.Equal("INFO", lines[0]["level"])
+		assert.Equal("ann", lines[0]["user"])
+		assert.Equal("12", lines[0]["took"])
+
+		assert.Equal("INFO", lines[1]["level"])
+		assert.NotContains(lines[1], "user")
+		assert.NotContains(lines[1], "took")
+
+		assert.NotContains(lines[2], "level")
+		assert.Equal("200", lines[3]["status"])
+		assert.NotContains(lines[3], "level")
+	}
+}
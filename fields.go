@@ -0,0 +1,119 @@
+package logstash
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+)
+
+// reservedFields are the top-level keys the adapter sets itself. Static
+// fields never replace them.
+var reservedFields = func() map[string]bool {
+	reserved := make(map[string]bool)
+	t := reflect.TypeOf(LogstashMessage{})
+	for i := 0; i < t.NumField(); i++ {
+		if name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+			reserved[name] = true
+		}
+	}
+	return reserved
+}()
+
+// parseFields parses static fields of the form key1=val1,key2=val2 into
+// fields, skipping pairs without a key and keys the adapter reserves. Keys
+// may also be separated from values by a colon, which reads better in route
+// URLs; the first separator counts.
+func parseFields(s string, fields map[string]string) map[string]string {
+	for _, pair := range splitList(s) {
+		i := strings.IndexAny(pair, "=:")
+		if i < 0 {
+			continue
+		}
+		key := strings.TrimSpace(pair[:i])
+		if key == "" || reservedFields[key] {
+			continue
+		}
+		if fields == nil {
+			fields = make(map[string]string)
+		}
+		fields[key] = strings.TrimSpace(pair[i+1:])
+	}
+	return fields
+}
+
+// routeFields returns the static fields of every event shipped on route,
+// set with its fields option, e.g. logstash://host:5000?fields=dc:eu-west,
+// or else with LOGSTASH_FIELDS in the adapter's environment.
+func routeFields(route *router.Route) map[string]string {
+	return parseFields(routeopt(route, "LOGSTASH_FIELDS", ""), nil)
+}
+
+// GetContainerFields returns the static fields a container describes itself
+// with, from its logstash.fields label and LOGSTASH_FIELDS environment
+// variable. The environment variable wins where both set a key.
+func GetContainerFields(c *docker.Container) map[string]string {
+	fields := parseFields(c.Config.Labels["logstash.fields"], nil)
+	for _, e := range c.Config.Env {
+		if strings.HasPrefix(e, "LOGSTASH_FIELDS=") {
+			fields = parseFields(strings.TrimPrefix(e, "LOGSTASH_FIELDS="), fields)
+		}
+	}
+	return fields
+}
+
+// containerFields returns the adapter's static fields overlaid with the
+// container's own.
+func (a *LogstashAdapter) containerFields(c *docker.Container) map[string]string {
+	fields := GetContainerFields(c)
+	if len(a.fields) == 0 {
+		return fields
+	}
+
+	merged := make(map[string]string, len(a.fields)+len(fields))
+	for k, v := range a.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return merged
+}
+
+// hintField names a field the adapter sets for the Logstash pipeline, either
+// a top-level key or a key of @metadata, written [@metadata][key] as in
+// Logstash configuration.
+type hintField struct {
+	key      string
+	metadata bool
+}
+
+func parseHintField(name string) (hintField, error) {
+	if strings.HasPrefix(name, "[@metadata][") && strings.HasSuffix(name, "]") {
+		key := strings.TrimSuffix(strings.TrimPrefix(name, "[@metadata]["), "]")
+		if key != "" && !strings.ContainsAny(key, "[]") {
+			return hintField{key: key, metadata: true}, nil
+		}
+	} else if name != "" && !strings.ContainsAny(name, "[]") && !reservedFields[name] {
+		return hintField{key: name}, nil
+	}
+	return hintField{}, errors.New("unsupported field name " + name)
+}
+
+// set sets the field to value in the metadata of a container. The zero
+// hintField sets nothing.
+func (f hintField) set(meta *containerMeta, value string) {
+	if f.key == "" {
+		return
+	}
+	fields := &meta.fields
+	if f.metadata {
+		fields = &meta.metadata
+	}
+	if *fields == nil {
+		*fields = make(map[string]string)
+	}
+	(*fields)[f.key] = value
+}
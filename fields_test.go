@@ -0,0 +1,168 @@
+package logstash
+
+import (
+	"os"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFields(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(parseFields("", nil))
+	assert.Equal(map[string]string{
+		"team":  "payments",
+		"stage": "prod",
+		"query": "a=b",
+		"empty": "",
+	}, parseFields(" team=payments, stage = prod,query=a=b,empty=,novalue,=nokey,docker=reserved", nil))
+}
+
+func TestParseFieldsWithColons(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(map[string]string{
+		"dc":   "eu-west",
+		"role": "worker",
+		"url":  "http://example.com",
+		"time": "12:00",
+	}, parseFields("dc:eu-west,role:worker,url=http://example.com,time=12:00", nil))
+}
+
+func TestRouteFields(t *testing.T) {
+	assert := assert.New(t)
+
+	os.Setenv("LOGSTASH_FIELDS", "dc=us-east")
+	defer os.Unsetenv("LOGSTASH_FIELDS")
+
+	assert.Equal(map[string]string{"dc": "us-east"}, routeFields(new(router.Route)))
+	route := &router.Route{Options: map[string]string{"fields": "dc:eu-west,role:worker"}}
+	assert.Equal(map[string]string{"dc": "eu-west", "role": "worker"}, routeFields(route))
+}
+
+func TestContainerFieldsOverlayRouteFields(t *testing.T) {
+	assert := assert.New(t)
+
+	adapter := LogstashAdapter{fields: map[string]string{"dc": "eu-west", "role": "worker"}}
+	container := docker.Container{ID: "ID", Config: &docker.Config{Env: []string{"LOGSTASH_FIELDS=role=api"}}}
+	assert.Equal(map[string]string{"dc": "eu-west", "role": "api"}, adapter.containerFields(&container))
+	assert.Equal(map[string]string{"dc": "eu-west", "role": "worker"}, adapter.fields)
+}
+
+func TestGetContainerFields(t *testing.T) {
+	assert := assert.New(t)
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{
+		Labels: map[string]string{"logstash.fields": "team=payments,stage=staging"},
+		Env:    []string{"LOGSTASH_FIELDS=stage=prod,component=api"},
+	}}
+	assert.Equal(map[string]string{
+		"team":      "payments",
+		"stage":     "prod",
+		"component": "api",
+	}, GetContainerFields(&container))
+}
+
+func TestStreamWithFields(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route: new(router.Route),
+		conn:  conn,
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Env: []string{
+		"LOGSTASH_FIELDS=team=payments,status=unknown",
+	}}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: `foo bananas`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `{ "status": "200" }`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 2) {
+		assert.Equal("payments", lines[0]["team"])
+		assert.Equal("unknown", lines[0]["status"])
+		assert.Equal("payments", lines[1]["team"])
+		assert.Equal("200", lines[1]["status"])
+	}
+}
+
+func TestParseHintField(t *testing.T) {
+	assert := assert.New(t)
+
+	f, err := parseHintField("[@metadata][index]")
+	assert.Nil(err)
+	assert.Equal(hintField{key: "index", metadata: true}, f)
+
+	f, err = parseHintField("target_index")
+	assert.Nil(err)
+	assert.Equal(hintField{key: "target_index"}, f)
+
+	for _, name := range []string{"", "docker", "[@metadata][]", "[a][b]", "[@metadata][a][b]"} {
+		_, err = parseHintField(name)
+		assert.NotNil(err, name)
+	}
+}
+
+func TestStreamWithIndexTemplate(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpl := template.Must(template.New("index").Parse(`logs-{{.Label "com.example.team"}}`))
+	container := docker.Container{ID: "ID", Config: &docker.Config{Labels: map[string]string{"com.example.team": "payments"}}}
+
+	for _, field := range []hintField{{key: "index", metadata: true}, {key: "target_index"}} {
+		conn := &BufferConn{}
+		adapter := LogstashAdapter{
+			route:         new(router.Route),
+			conn:          conn,
+			indexField:    field,
+			indexTemplate: tmpl,
+		}
+
+		logstream := make(chan *router.Message)
+		go func() {
+			logstream <- &router.Message{Container: &container, Data: `foo bananas`, Time: time.Now()}
+			logstream <- &router.Message{Container: &container, Data: `{ "status": "200" }`, Time: time.Now()}
+			close(logstream)
+		}()
+
+		adapter.Stream(logstream)
+
+		for _, data := range conn.Lines() {
+			if field.metadata {
+				assert.Equal(map[string]interface{}{"index": "logs-payments"}, data["@metadata"])
+			} else {
+				assert.Equal("logs-payments", data["target_index"])
+			}
+		}
+		assert.Len(conn.Lines(), 2)
+	}
+}
+
+func TestPipelineField(t *testing.T) {
+	assert := assert.New(t)
+
+	env := docker.Container{ID: "env", Config: &docker.Config{Env: []string{"LOGSTASH_PIPELINE=nginx"}}}
+	label := docker.Container{ID: "label", Config: &docker.Config{Labels: map[string]string{"logstash.pipeline": "java"}}}
+	none := docker.Container{ID: "none", Config: &docker.Config{}}
+
+	adapter := LogstashAdapter{pipelineField: hintField{key: "pipeline", metadata: true}}
+	assert.Equal(map[string]string{"pipeline": "nginx"}, adapter.containerMeta(&env).metadata)
+	assert.Equal(map[string]string{"pipeline": "java"}, adapter.containerMeta(&label).metadata)
+	assert.Nil(adapter.containerMeta(&none).metadata)
+
+	adapter = LogstashAdapter{pipelineField: hintField{key: "ingest_pipeline"}}
+	assert.Equal(map[string]string{"ingest_pipeline": "nginx"}, adapter.containerMeta(&env).fields)
+}
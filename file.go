@@ -0,0 +1,108 @@
+package logstash
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+func init() {
+	router.AdapterTransports.Register(new(fileTransport), "file")
+}
+
+// fileTransport writes to local files instead of the network, for routes
+// such as logstash+file://.
+type fileTransport struct{}
+
+// Dial opens the file named by the file_path route option or the
+// LOGSTASH_FILE_PATH environment variable, or else by addr.
+func (t *fileTransport) Dial(addr string, options map[string]string) (net.Conn, error) {
+	pattern := options["file_path"]
+	if pattern == "" {
+		pattern = getopt("LOGSTASH_FILE_PATH", addr)
+	}
+	if pattern == "" {
+		return nil, errors.New("no file path, set LOGSTASH_FILE_PATH")
+	}
+	f := &rotatingFile{pattern: pattern, now: time.Now}
+	if err := f.rotate(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// rotatingFile appends to the file its pattern names at the current time,
+// moving on to the next file when the name changes, so a pattern such as
+// /var/log/shipped/%Y%m%d.ndjson starts a new file every day.
+type rotatingFile struct {
+	pattern string
+	now     func() time.Time
+	name    string
+	file    *os.File
+}
+
+// fileName expands the strftime directives %Y, %m, %d, %H and %M, and %%
+// for a percent sign, of a pattern with the UTC time t.
+func fileName(pattern string, t time.Time) string {
+	t = t.UTC()
+	r := strings.NewReplacer("%%", "%", "%Y", t.Format("2006"), "%m", t.Format("01"), "%d", t.Format("02"), "%H", t.Format("15"), "%M", t.Format("04"))
+	return r.Replace(pattern)
+}
+
+// rotate opens the file named for the current time, unless it is open.
+func (f *rotatingFile) rotate() error {
+	name := fileName(f.pattern, f.now())
+	if f.file != nil && name == f.name {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if f.file != nil {
+		f.file.Close()
+	}
+	f.name, f.file = name, file
+	return nil
+}
+
+// Write appends b to the current file. Every write goes to a single file,
+// so documents are never split across two.
+func (f *rotatingFile) Write(b []byte) (int, error) {
+	if err := f.rotate(); err != nil {
+		return 0, err
+	}
+	return f.file.Write(b)
+}
+
+func (f *rotatingFile) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Close()
+}
+
+func (f *rotatingFile) Read(b []byte) (int, error)         { return 0, io.EOF }
+func (f *rotatingFile) LocalAddr() net.Addr                { return localAddr{"file", f.pattern} }
+func (f *rotatingFile) RemoteAddr() net.Addr               { return localAddr{"file", f.pattern} }
+func (f *rotatingFile) SetDeadline(t time.Time) error      { return nil }
+func (f *rotatingFile) SetReadDeadline(t time.Time) error  { return nil }
+func (f *rotatingFile) SetWriteDeadline(t time.Time) error { return nil }
+
+// localAddr is the address of a connection that does not leave the host,
+// such as the path pattern of a file.
+type localAddr struct {
+	network, name string
+}
+
+func (a localAddr) Network() string { return a.network }
+func (a localAddr) String() string  { return a.name }
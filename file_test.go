@@ -0,0 +1,87 @@
+package logstash
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileName(t *testing.T) {
+	assert := assert.New(t)
+
+	at := time.Date(2016, 10, 20, 23, 5, 0, 0, time.FixedZone("CEST", -2*3600))
+	assert.Equal("/var/log/shipped/20161021/0105-100%.ndjson", fileName("/var/log/shipped/%Y%m%d/%H%M-100%%.ndjson", at))
+	assert.Equal("/var/log/shipped.ndjson", fileName("/var/log/shipped.ndjson", at))
+}
+
+func TestRotatingFile(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "logstash")
+	if !assert.Nil(err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2016, 10, 20, 23, 59, 59, 0, time.UTC)
+	f := &rotatingFile{pattern: filepath.Join(dir, "%Y", "%m%d.ndjson"), now: func() time.Time { return now }}
+	f.Write([]byte("{\"message\":\"first\"}\n"))
+	f.Write([]byte("{\"message\":\"second\"}\n"))
+	now = now.Add(time.Second)
+	f.Write([]byte("{\"message\":\"third\"}\n"))
+	assert.Nil(f.Close())
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "2016", "1020.ndjson"))
+	assert.Nil(err)
+	assert.Equal("{\"message\":\"first\"}\n{\"message\":\"second\"}\n", string(data))
+	data, err = ioutil.ReadFile(filepath.Join(dir, "2016", "1021.ndjson"))
+	assert.Nil(err)
+	assert.Equal("{\"message\":\"third\"}\n", string(data))
+}
+
+func TestStreamToFile(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "logstash")
+	if !assert.Nil(err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	_, err = new(fileTransport).Dial("", nil)
+	assert.NotNil(err)
+
+	conn, err := new(fileTransport).Dial("", map[string]string{"file_path": filepath.Join(dir, "shipped.ndjson")})
+	if !assert.Nil(err) {
+		return
+	}
+	adapter := LogstashAdapter{route: new(router.Route), conn: conn}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: "plain", Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `{"msg":"json"}`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+	conn.Close()
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "shipped.ndjson"))
+	assert.Nil(err)
+	written := &BufferConn{}
+	written.Write(data)
+	lines := written.Lines()
+	if assert.Len(lines, 2) {
+		assert.Equal("plain", lines[0]["message"])
+		assert.Equal("json", lines[1]["msg"])
+	}
+}
@@ -0,0 +1,57 @@
+package logstash
+
+import (
+	"regexp"
+	"strings"
+)
+
+// nameFilter matches names against a comma-separated list whose entries are
+// either exact names or, when written as /pattern/, regular expressions.
+type nameFilter struct {
+	names    map[string]bool
+	patterns []*regexp.Regexp
+}
+
+func newNameFilter(list string) (*nameFilter, error) {
+	f := &nameFilter{names: make(map[string]bool)}
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) > 1 && strings.HasPrefix(entry, "/") && strings.HasSuffix(entry, "/") {
+			re, err := regexp.Compile(entry[1 : len(entry)-1])
+			if err != nil {
+				return nil, err
+			}
+			f.patterns = append(f.patterns, re)
+		} else if entry != "" {
+			f.names[entry] = true
+		}
+	}
+	return f, nil
+}
+
+// empty reports whether the filter has no entries.
+func (f *nameFilter) empty() bool {
+	return f == nil || (len(f.names) == 0 && len(f.patterns) == 0)
+}
+
+// match reports whether name matches one of the entries.
+func (f *nameFilter) match(name string) bool {
+	if f == nil {
+		return false
+	}
+	if f.names[name] {
+		return true
+	}
+	for _, re := range f.patterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed reports whether name passes an allow and a deny filter. An empty
+// allow filter allows everything.
+func allowed(name string, allow, deny *nameFilter) bool {
+	return (allow.empty() || allow.match(name)) && !deny.match(name)
+}
@@ -0,0 +1,28 @@
+package logstash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNameFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	f, err := newNameFilter(`com.example.team, /^com\.docker\.compose\./`)
+	assert.Nil(err)
+	assert.True(f.match("com.example.team"))
+	assert.True(f.match("com.docker.compose.service"))
+	assert.False(f.match("com.example.team.secret"))
+	assert.False(f.match("com.docker.swarm.node.id"))
+
+	empty, err := newNameFilter("")
+	assert.Nil(err)
+	assert.True(empty.empty())
+	assert.True(allowed("anything", empty, nil))
+	assert.False(allowed("com.example.team", empty, f))
+	assert.False(allowed("other", f, nil))
+
+	_, err = newNameFilter("/[/")
+	assert.NotNil(err)
+}
@@ -0,0 +1,178 @@
+package logstash
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// gelfLevels maps severity names to syslog levels, as GELF expects them.
+var gelfLevels = map[string]int{
+	"emerg":     0,
+	"emergency": 0,
+	"panic":     0,
+	"alert":     1,
+	"crit":      2,
+	"critical":  2,
+	"fatal":     2,
+	"err":       3,
+	"error":     3,
+	"warn":      4,
+	"warning":   4,
+	"notice":    5,
+	"info":      6,
+	"debug":     7,
+	"trace":     7,
+}
+
+// GELF messages sent over UDP that do not fit a datagram of gelfChunkSize
+// bytes are split into at most gelfMaxChunks chunks of that size, each with
+// a header of gelfChunkHeader bytes.
+const (
+	gelfChunkSize   = 8192
+	gelfChunkHeader = 12
+	gelfMaxChunks   = 128
+)
+
+// gelfInvalidKey matches the characters GELF does not allow in field names.
+var gelfInvalidKey = regexp.MustCompile(`[^\w.\-]`)
+
+// gelfLevel returns the syslog level of a document, from its severity or
+// level field, or else from the stream it was written to.
+func gelfLevel(data map[string]interface{}, stream string) int {
+	for _, key := range []string{"severity", "level"} {
+		switch v := data[key].(type) {
+		case string:
+			if level, ok := gelfLevels[strings.ToLower(v)]; ok {
+				return level
+			}
+		case float64:
+			if v >= 0 && v <= 7 {
+				return int(v)
+			}
+		}
+	}
+	if stream == "stderr" {
+		return 3
+	}
+	return 6
+}
+
+// toGELF converts a document into a GELF 1.1 message in out. The message
+// becomes short_message, and every other field an additional field named
+// after its path, joined with underscores, e.g. _docker_name.
+func toGELF(data map[string]interface{}, e *event, host string, out map[string]interface{}) {
+	out["version"] = "1.1"
+	out["host"] = host
+	out["timestamp"] = float64(e.message.Time.UnixNano()/1e3) / 1e6
+	out["level"] = gelfLevel(data, e.message.Source)
+
+	msg, _ := data["message"].(string)
+	if msg == "" {
+		msg = e.text
+	}
+	out["short_message"] = msg
+
+	for k, v := range data {
+		if k == "message" {
+			continue
+		}
+		addGELFField(out, k, v)
+	}
+}
+
+// addGELFField adds v as additional fields named after key. GELF fields are
+// strings or numbers: objects are flattened, lists of strings joined with
+// commas, and anything else encoded as JSON.
+func addGELFField(out map[string]interface{}, key string, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+	case string:
+		out[gelfKey(key)] = v
+	case float64:
+		out[gelfKey(key)] = v
+	case int, int64, uint64:
+		out[gelfKey(key)] = v
+	case bool:
+		out[gelfKey(key)] = strconv.FormatBool(v)
+	case []string:
+		out[gelfKey(key)] = strings.Join(v, ",")
+	case map[string]string:
+		for k, s := range v {
+			out[gelfKey(key+"_"+k)] = s
+		}
+	case map[string]interface{}:
+		for k, value := range v {
+			addGELFField(out, key+"_"+k, value)
+		}
+	case []interface{}:
+		strs := make([]string, 0, len(v))
+		for _, value := range v {
+			if s, ok := value.(string); ok {
+				strs = append(strs, s)
+			}
+		}
+		if len(strs) == len(v) {
+			out[gelfKey(key)] = strings.Join(strs, ",")
+			return
+		}
+		js, _ := json.Marshal(v)
+		out[gelfKey(key)] = string(js)
+	default:
+		if obj, ok := asObject(v); ok {
+			addGELFField(out, key, obj)
+			return
+		}
+		if js, err := json.Marshal(v); err == nil {
+			out[gelfKey(key)] = string(js)
+		}
+	}
+}
+
+// gelfKey returns the name of an additional field, which GELF requires to
+// start with an underscore and to not be _id.
+func gelfKey(key string) string {
+	key = "_" + gelfInvalidKey.ReplaceAllString(key, "_")
+	if key == "_id" {
+		return "__id"
+	}
+	return key
+}
+
+// chunkGELF splits the message at the end of d, from offset start, into GELF
+// chunks if it does not fit a single datagram. Every chunk becomes a document
+// of its own, starting with the chunk magic bytes, the ID of the message, its
+// sequence number and the number of chunks. Messages too large for
+// gelfMaxChunks chunks cannot be sent.
+func chunkGELF(d *document, start int) error {
+	if d.Len()-start <= gelfChunkSize {
+		return nil
+	}
+	msg := append([]byte(nil), d.Bytes()[start:]...)
+	size := gelfChunkSize - gelfChunkHeader
+	count := (len(msg) + size - 1) / size
+	if count > gelfMaxChunks {
+		return errors.New("GELF message of " + strconv.Itoa(len(msg)) + " bytes is too large to send over UDP")
+	}
+
+	var header [gelfChunkHeader]byte
+	header[0], header[1] = 0x1e, 0x0f
+	randomBytes(header[2:10])
+	header[11] = byte(count)
+	d.Truncate(start)
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			d.ends = append(d.ends, d.Len())
+		}
+		header[10] = byte(i)
+		d.Write(header[:])
+		end := (i + 1) * size
+		if end > len(msg) {
+			end = len(msg)
+		}
+		d.Write(msg[i*size : end])
+	}
+	return nil
+}
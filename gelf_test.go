@@ -0,0 +1,196 @@
+package logstash
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGELFLevel(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(6, gelfLevel(map[string]interface{}{}, "stdout"))
+	assert.Equal(3, gelfLevel(map[string]interface{}{}, "stderr"))
+	assert.Equal(4, gelfLevel(map[string]interface{}{"level": "WARN"}, "stdout"))
+	assert.Equal(2, gelfLevel(map[string]interface{}{"severity": "fatal", "level": "info"}, "stdout"))
+	assert.Equal(7, gelfLevel(map[string]interface{}{"level": 7.0}, "stderr"))
+	assert.Equal(3, gelfLevel(map[string]interface{}{"level": "verbose"}, "stderr"))
+}
+
+func TestStreamGELF(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:      new(router.Route),
+		conn:       conn,
+		wireFormat: "gelf",
+		gelfHost:   "node-1",
+	}
+
+	container := docker.Container{ID: "ID", Name: "/name", Config: &docker.Config{Env: []string{"LOGSTASH_TAGS=web,prod"}}}
+	at := time.Date(2016, 10, 20, 13, 25, 13, 627000000, time.UTC)
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Source: "stderr", Data: `foo bananas`, Time: at}
+		logstream <- &router.Message{Container: &container, Source: "stdout", Data: `{"message":"done","id":7,"level":"debug","http":{"status":200}}`, Time: at}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	frames := strings.Split(strings.TrimSuffix(conn.buf.String(), "\x00"), "\x00")
+	if assert.Len(frames, 2) {
+		var data map[string]interface{}
+		assert.Nil(json.Unmarshal([]byte(frames[0]), &data))
+		assert.Equal("1.1", data["version"])
+		assert.Equal("node-1", data["host"])
+		assert.Equal("foo bananas", data["short_message"])
+		assert.Equal(1476969913.627, data["timestamp"])
+		assert.Equal(float64(3), data["level"])
+		assert.Equal("/name", data["_docker_name"])
+		assert.Equal("ID", data["_docker_id"])
+		assert.Equal("web,prod", data["_tags"])
+		assert.Equal("stderr", data["_stream"])
+		assert.Nil(data["message"])
+		assert.Nil(data["docker"])
+
+		data = nil
+		assert.Nil(json.Unmarshal([]byte(frames[1]), &data))
+		assert.Equal("done", data["short_message"])
+		assert.Equal(float64(7), data["level"])
+		assert.Equal(float64(7), data["__id"])
+		assert.Equal(float64(200), data["_http_status"])
+	}
+}
+
+// DatagramConn keeps every write as a datagram of its own.
+type DatagramConn struct {
+	MockConn
+	mu        sync.Mutex
+	datagrams [][]byte
+}
+
+func (c *DatagramConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.datagrams = append(c.datagrams, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+func TestStreamGELFChunked(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &DatagramConn{}
+	adapter := LogstashAdapter{
+		route:      new(router.Route),
+		conn:       conn,
+		wireFormat: "gelf",
+		gelfUDP:    true,
+		gelfHost:   "node-1",
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{}}
+	long := strings.Repeat("x", 20000)
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: "short", Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: long, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: strings.Repeat("x", gelfMaxChunks*gelfChunkSize), Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	if !assert.Len(conn.datagrams, 4) {
+		return
+	}
+	var data map[string]interface{}
+	assert.Nil(json.Unmarshal(conn.datagrams[0], &data))
+	assert.Equal("short", data["short_message"])
+
+	var msg []byte
+	for i, chunk := range conn.datagrams[1:] {
+		assert.True(len(chunk) <= gelfChunkSize)
+		assert.Equal([]byte{0x1e, 0x0f}, chunk[:2])
+		assert.Equal(conn.datagrams[1][2:10], chunk[2:10])
+		assert.Equal(byte(i), chunk[10])
+		assert.Equal(byte(3), chunk[11])
+		msg = append(msg, chunk[gelfChunkHeader:]...)
+	}
+	data = nil
+	assert.Nil(json.Unmarshal(msg, &data))
+	assert.Equal(long, data["short_message"])
+	assert.False(bytes.Equal(conn.datagrams[1][2:10], make([]byte, 8)))
+}
+
+func TestStreamGELFChunkedWithMaxEventSize(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, chunkOversize := range []bool{false, true} {
+		conn := &DatagramConn{}
+		adapter := LogstashAdapter{
+			route:         new(router.Route),
+			conn:          conn,
+			wireFormat:    "gelf",
+			gelfUDP:       true,
+			gelfHost:      "node-1",
+			maxEventSize:  10000,
+			chunkOversize: chunkOversize,
+		}
+
+		container := docker.Container{ID: "ID", Config: &docker.Config{}}
+		long := strings.Repeat("x", 50000)
+
+		logstream := make(chan *router.Message)
+		go func() {
+			logstream <- &router.Message{Container: &container, Data: long, Time: time.Now()}
+			logstream <- &router.Message{Container: &container, Data: "short", Time: time.Now()}
+			close(logstream)
+		}()
+
+		adapter.Stream(logstream)
+
+		// Join the GELF chunks back into messages.
+		var messages [][]byte
+		for _, datagram := range conn.datagrams {
+			assert.True(len(datagram) <= gelfChunkSize)
+			if !bytes.HasPrefix(datagram, []byte{0x1e, 0x0f}) {
+				messages = append(messages, datagram)
+				continue
+			}
+			if datagram[10] == 0 {
+				messages = append(messages, nil)
+			}
+			messages[len(messages)-1] = append(messages[len(messages)-1], datagram[gelfChunkHeader:]...)
+		}
+
+		var pieces []string
+		for _, msg := range messages {
+			var data map[string]interface{}
+			if assert.Nil(json.Unmarshal(msg, &data)) {
+				pieces = append(pieces, data["short_message"].(string))
+			}
+		}
+		if !assert.True(len(pieces) >= 2, chunkOversize) {
+			continue
+		}
+		assert.Equal("short", pieces[len(pieces)-1])
+		if chunkOversize {
+			assert.Equal(long, strings.Join(pieces[:len(pieces)-1], ""))
+		} else {
+			assert.Len(pieces, 2)
+			assert.True(strings.HasPrefix(long, pieces[0]))
+			assert.True(len(pieces[0]) < 10000)
+		}
+	}
+}
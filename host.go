@@ -0,0 +1,88 @@
+package logstash
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// HostData describes the node logspout runs on.
+type HostData struct {
+	Hostname string `json:"hostname,omitempty"`
+	IP       string `json:"ip,omitempty"`
+	OS       string `json:"os,omitempty"`
+	Kernel   string `json:"kernel,omitempty"`
+}
+
+// GetHostData returns the hostname, primary IP address, operating system and
+// kernel version of the node, each of which can be overridden with the
+// LOGSTASH_HOST_HOSTNAME, LOGSTASH_HOST_IP, LOGSTASH_HOST_OS and
+// LOGSTASH_HOST_KERNEL environment variables. The hostname is that of
+// GetSourceHost, if known, rather than the container's own. The IP address
+// is the container's unless logspout runs on the host network. It is meant
+// to be called once, at startup.
+func GetHostData() HostData {
+	d := HostData{
+		Hostname: getopt("LOGSTASH_HOST_HOSTNAME", ""),
+		IP:       getopt("LOGSTASH_HOST_IP", ""),
+		OS:       getopt("LOGSTASH_HOST_OS", runtime.GOOS),
+		Kernel:   getopt("LOGSTASH_HOST_KERNEL", ""),
+	}
+
+	if d.Hostname == "" {
+		d.Hostname = GetSourceHost()
+	}
+	if d.Hostname == "" {
+		d.Hostname, _ = os.Hostname()
+	}
+	if d.IP == "" {
+		d.IP = primaryIP()
+	}
+	if d.Kernel == "" {
+		// The container shares the kernel of the host.
+		if release, err := ioutil.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+			d.Kernel = strings.TrimSpace(string(release))
+		}
+	}
+	return d
+}
+
+// GetSourceHost returns the name of the node from the HOST_HOSTNAME
+// environment variable or, failing that, from /etc/host_hostname, which is
+// where the host's /etc/hostname is usually mounted. Unlike the container's
+// own hostname it names the node even when logspout runs in a container.
+func GetSourceHost() string {
+	if hostname := os.Getenv("HOST_HOSTNAME"); hostname != "" {
+		return hostname
+	}
+	if hostname, err := ioutil.ReadFile(hostHostnameFile); err == nil {
+		return strings.TrimSpace(string(hostname))
+	}
+	return ""
+}
+
+var hostHostnameFile = "/etc/host_hostname"
+
+// primaryIP returns the source address the node uses for its default route.
+// Connecting a UDP socket does not send any packets.
+func primaryIP() string {
+	conn, err := net.Dial("udp", "192.0.2.1:9")
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String()
+}
+
+// appendJSON appends the JSON encoding of d to dst.
+func (d *HostData) appendJSON(dst []byte) []byte {
+	dst = append(dst, '{')
+	n := len(dst)
+	dst = appendJSONStringField(dst, n, "hostname", d.Hostname)
+	dst = appendJSONStringField(dst, n, "ip", d.IP)
+	dst = appendJSONStringField(dst, n, "os", d.OS)
+	dst = appendJSONStringField(dst, n, "kernel", d.Kernel)
+	return append(dst, '}')
+}
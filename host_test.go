@@ -0,0 +1,66 @@
+package logstash
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetHostDataOverrides(t *testing.T) {
+	assert := assert.New(t)
+
+	for k, v := range map[string]string{
+		"LOGSTASH_HOST_HOSTNAME": "node-1.example.com",
+		"LOGSTASH_HOST_IP":       "10.0.0.1",
+		"LOGSTASH_HOST_OS":       "coreos",
+		"LOGSTASH_HOST_KERNEL":   "4.9.0",
+	} {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	assert.Equal(HostData{Hostname: "node-1.example.com", IP: "10.0.0.1", OS: "coreos", Kernel: "4.9.0"}, GetHostData())
+}
+
+func TestGetHostData(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(name string) { hostHostnameFile = name }(hostHostnameFile)
+	hostHostnameFile = "/nonexistent"
+
+	hostname, _ := os.Hostname()
+	d := GetHostData()
+	assert.Equal(hostname, d.Hostname)
+	assert.NotEmpty(d.OS)
+
+	// The name of the node is preferred to that of the container.
+	os.Setenv("HOST_HOSTNAME", "node-1.example.com")
+	defer os.Unsetenv("HOST_HOSTNAME")
+	assert.Equal("node-1.example.com", GetHostData().Hostname)
+}
+
+func TestGetSourceHost(t *testing.T) {
+	assert := assert.New(t)
+
+	file, err := ioutil.TempFile("", "host_hostname")
+	if !assert.Nil(err) {
+		return
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("node-1.example.com\n")
+	file.Close()
+
+	defer func(name string) { hostHostnameFile = name }(hostHostnameFile)
+	hostHostnameFile = file.Name()
+	assert.Equal("node-1.example.com", GetSourceHost())
+
+	os.Setenv("HOST_HOSTNAME", "node-2.example.com")
+	defer os.Unsetenv("HOST_HOSTNAME")
+	assert.Equal("node-2.example.com", GetSourceHost())
+
+	os.Unsetenv("HOST_HOSTNAME")
+	hostHostnameFile = file.Name() + ".missing"
+	assert.Equal("", GetSourceHost())
+}
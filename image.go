@@ -0,0 +1,46 @@
+package logstash
+
+import (
+	"strings"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// labelSchemaNames maps the deprecated label-schema.org labels to their OCI
+// image annotation equivalents.
+var labelSchemaNames = map[string]string{
+	"build-date":  "created",
+	"vcs-ref":     "revision",
+	"vcs-url":     "source",
+	"version":     "version",
+	"name":        "title",
+	"description": "description",
+	"url":         "url",
+	"vendor":      "vendor",
+}
+
+// GetImageMeta returns the build provenance recorded in the standard
+// org.opencontainers.image.* and org.label-schema.* labels, keyed by the OCI
+// annotation name without its prefix. OCI labels take precedence.
+func GetImageMeta(c *docker.Container) map[string]string {
+	var meta map[string]string
+	set := func(k, v string) {
+		if meta == nil {
+			meta = make(map[string]string)
+		}
+		meta[k] = v
+	}
+
+	for k, v := range c.Config.Labels {
+		if strings.HasPrefix(k, "org.label-schema.") {
+			if name, ok := labelSchemaNames[strings.TrimPrefix(k, "org.label-schema.")]; ok {
+				if _, exists := c.Config.Labels["org.opencontainers.image."+name]; !exists {
+					set(name, v)
+				}
+			}
+		} else if strings.HasPrefix(k, "org.opencontainers.image.") {
+			set(strings.TrimPrefix(k, "org.opencontainers.image."), v)
+		}
+	}
+	return meta
+}
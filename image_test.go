@@ -0,0 +1,33 @@
+package logstash
+
+import (
+	"testing"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetImageMeta(t *testing.T) {
+	assert := assert.New(t)
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Labels: map[string]string{
+		"org.opencontainers.image.version":  "1.6.0",
+		"org.opencontainers.image.source":   "https://github.com/example/shop",
+		"org.label-schema.version":          "1.5.0",
+		"org.label-schema.vcs-ref":          "abc123",
+		"org.label-schema.schema-version":   "1.0",
+		"com.docker.compose.service":        "web",
+		"org.opencontainers.image.revision": "def456",
+	}}}
+	assert.Equal(map[string]string{
+		"version":  "1.6.0",
+		"source":   "https://github.com/example/shop",
+		"revision": "def456",
+	}, GetImageMeta(&container))
+
+	container.Config.Labels = map[string]string{"org.label-schema.vcs-ref": "abc123"}
+	assert.Equal(map[string]string{"revision": "abc123"}, GetImageMeta(&container))
+
+	container.Config.Labels = nil
+	assert.Nil(GetImageMeta(&container))
+}
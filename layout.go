@@ -0,0 +1,220 @@
+package logstash
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// fieldRename moves the value at one dotted path of a document to another,
+// e.g. docker.name to container_name.
+type fieldRename struct {
+	from, to []string
+}
+
+// parseRenames parses renames of the form from:to,from:to, or a JSON object
+// mapping from to to.
+func parseRenames(s string) ([]fieldRename, error) {
+	pairs := make(map[string]string)
+	var order []string
+	if strings.HasPrefix(strings.TrimSpace(s), "{") {
+		if err := json.Unmarshal([]byte(s), &pairs); err != nil {
+			return nil, err
+		}
+		for from := range pairs {
+			order = append(order, from)
+		}
+		sort.Strings(order)
+	} else {
+		for _, pair := range splitList(s) {
+			i := strings.IndexAny(pair, "=:")
+			if i < 0 {
+				return nil, errors.New("missing target in " + pair)
+			}
+			from := strings.TrimSpace(pair[:i])
+			pairs[from] = strings.TrimSpace(pair[i+1:])
+			order = append(order, from)
+		}
+	}
+
+	var renames []fieldRename
+	for _, from := range order {
+		to := pairs[from]
+		r := fieldRename{from: strings.Split(from, "."), to: strings.Split(to, ".")}
+		if from == "" || to == "" || strings.Contains(from+"."+to, "..") || within(r.to, r.from) {
+			return nil, errors.New("invalid rename of " + from + " to " + to)
+		}
+		renames = append(renames, r)
+	}
+	return renames, nil
+}
+
+// parseFieldPaths parses a list of dotted paths such as docker.hostname,tags.
+func parseFieldPaths(s string) ([][]string, error) {
+	var paths [][]string
+	for _, field := range splitList(s) {
+		if strings.HasPrefix(field, ".") || strings.HasSuffix(field, ".") || strings.Contains(field, "..") {
+			return nil, errors.New("invalid field " + field)
+		}
+		paths = append(paths, strings.Split(field, "."))
+	}
+	return paths, nil
+}
+
+// asObject returns v as a JSON object, converting metadata blocks through
+// their JSON encoding. ok is false if v does not encode to an object.
+func asObject(v interface{}) (obj map[string]interface{}, ok bool) {
+	if obj, ok = v.(map[string]interface{}); ok {
+		return obj, true
+	}
+	js, err := json.Marshal(v)
+	if err != nil || json.Unmarshal(js, &obj) != nil || obj == nil {
+		return nil, false
+	}
+	return obj, true
+}
+
+// object returns the object at path in data, converting the objects on the
+// way to maps so they can be changed. With create set, missing objects are
+// added.
+func object(data map[string]interface{}, path []string, create bool) (map[string]interface{}, bool) {
+	for _, key := range path {
+		v, ok := data[key]
+		if !ok {
+			if !create {
+				return nil, false
+			}
+			v = make(map[string]interface{})
+		}
+		obj, ok := asObject(v)
+		if !ok {
+			return nil, false
+		}
+		data[key] = obj
+		data = obj
+	}
+	return data, true
+}
+
+// renameFields applies renames to a document. A rename is skipped if its
+// source is missing, or its target exists already.
+func renameFields(data map[string]interface{}, renames []fieldRename) {
+	for _, r := range renames {
+		from, ok := object(data, r.from[:len(r.from)-1], false)
+		if !ok {
+			continue
+		}
+		v, ok := from[r.from[len(r.from)-1]]
+		if !ok {
+			continue
+		}
+		to, ok := object(data, r.to[:len(r.to)-1], true)
+		if !ok {
+			continue
+		}
+		if _, exists := to[r.to[len(r.to)-1]]; exists {
+			continue
+		}
+		delete(from, r.from[len(r.from)-1])
+		to[r.to[len(r.to)-1]] = v
+	}
+}
+
+// dropFields removes the values at paths from a document.
+func dropFields(data map[string]interface{}, paths [][]string) {
+	for _, path := range paths {
+		if obj, ok := object(data, path[:len(path)-1], false); ok {
+			delete(obj, path[len(path)-1])
+		}
+	}
+}
+
+// omitEmpty removes empty strings, arrays and objects from a document,
+// including objects that are left empty once their members are removed.
+func omitEmpty(data map[string]interface{}) {
+	for k, v := range data {
+		switch v := v.(type) {
+		case nil:
+			continue
+		case string:
+			if v == "" {
+				delete(data, k)
+			}
+		case bool, float64, int, uint64:
+			continue
+		case []string:
+			if len(v) == 0 {
+				delete(data, k)
+			}
+		case []interface{}:
+			if len(v) == 0 {
+				delete(data, k)
+			}
+		default:
+			obj, ok := asObject(v)
+			if !ok {
+				continue
+			}
+			if omitEmpty(obj); len(obj) == 0 {
+				delete(data, k)
+			} else {
+				data[k] = obj
+			}
+		}
+	}
+}
+
+// flatDockerKeys are the top-level keys of the docker block members in the
+// flat layout. Other members are prefixed with container_.
+var flatDockerKeys = map[string]string{
+	"name":     "container_name",
+	"id":       "container_id",
+	"image":    "image_name",
+	"hostname": "hostname",
+}
+
+// flattenDocker moves the members of the docker block to the top level of a
+// document. Members whose key is taken stay in the block, which is removed
+// once empty.
+func flattenDocker(data map[string]interface{}) {
+	docker, ok := object(data, []string{"docker"}, false)
+	if !ok {
+		return
+	}
+	for k, v := range docker {
+		key, ok := flatDockerKeys[k]
+		if !ok {
+			key = "container_" + k
+		}
+		if _, exists := data[key]; !exists {
+			data[key] = v
+			delete(docker, k)
+		}
+	}
+	if len(docker) == 0 {
+		delete(data, "docker")
+	}
+}
+
+// upstreamDockerInfo is the docker block of the upstream
+// looplab/logspout-logstash adapter, which has dots in label names replaced
+// by underscores.
+type upstreamDockerInfo struct {
+	Name     string            `json:"name"`
+	ID       string            `json:"id"`
+	Image    string            `json:"image"`
+	Hostname string            `json:"hostname"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+func newUpstreamDockerInfo(d *DockerInfo) *upstreamDockerInfo {
+	u := &upstreamDockerInfo{Name: d.Name, ID: d.ID, Image: d.Image, Hostname: d.Hostname}
+	if len(d.Labels) > 0 {
+		u.Labels = make(map[string]string, len(d.Labels))
+		for k, v := range d.Labels {
+			u.Labels[strings.Replace(k, ".", "_", -1)] = v
+		}
+	}
+	return u
+}
@@ -0,0 +1,327 @@
+package logstash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRenames(t *testing.T) {
+	assert := assert.New(t)
+
+	renames, err := parseRenames("docker.name:container_name, stream=log_stream")
+	assert.Nil(err)
+	assert.Equal([]fieldRename{
+		{from: []string{"docker", "name"}, to: []string{"container_name"}},
+		{from: []string{"stream"}, to: []string{"log_stream"}},
+	}, renames)
+
+	renames, err = parseRenames(`{"stream":"log.stream","docker.id":"container_id"}`)
+	assert.Nil(err)
+	assert.Equal([]fieldRename{
+		{from: []string{"docker", "id"}, to: []string{"container_id"}},
+		{from: []string{"stream"}, to: []string{"log", "stream"}},
+	}, renames)
+
+	for _, s := range []string{"stream", "stream:", ":x", "a..b:c", `{"a":1}`, "docker:docker.old"} {
+		_, err = parseRenames(s)
+		assert.NotNil(err, s)
+	}
+}
+
+func TestRenameFields(t *testing.T) {
+	assert := assert.New(t)
+
+	renames, _ := parseRenames("docker.name:container_name,stream:log.stream,missing:x,level:status,message:msg.text")
+	data := map[string]interface{}{
+		"message": "hello",
+		"msg":     "taken",
+		"stream":  "stdout",
+		"level":   "info",
+		"status":  "200",
+		"docker":  DockerInfo{Name: "/name", ID: "ID"},
+	}
+	renameFields(data, renames)
+
+	assert.Equal(map[string]interface{}{
+		"message":        "hello",
+		"msg":            "taken",
+		"log":            map[string]interface{}{"stream": "stdout"},
+		"level":          "info",
+		"status":         "200",
+		"container_name": "/name",
+		"docker":         map[string]interface{}{"id": "ID", "image": "", "hostname": ""},
+	}, data)
+}
+
+func TestDropFields(t *testing.T) {
+	assert := assert.New(t)
+
+	paths, err := parseFieldPaths("docker.hostname, stream,missing.key")
+	assert.Nil(err)
+	data := map[string]interface{}{
+		"message": "hello",
+		"stream":  "stdout",
+		"docker":  DockerInfo{Name: "/name", ID: "ID", Hostname: "abc"},
+	}
+	dropFields(data, paths)
+
+	assert.Equal(map[string]interface{}{
+		"message": "hello",
+		"docker":  map[string]interface{}{"name": "/name", "id": "ID", "image": ""},
+	}, data)
+
+	for _, s := range []string{"docker.", ".name", "docker..name"} {
+		_, err := parseFieldPaths(s)
+		assert.NotNil(err, s)
+	}
+}
+
+func TestOmitEmpty(t *testing.T) {
+	data := map[string]interface{}{
+		"message": "hello",
+		"stream":  "",
+		"tags":    []string{},
+		"items":   []interface{}{},
+		"count":   float64(0),
+		"ok":      false,
+		"docker":  DockerInfo{Name: "/name"},
+		"labels":  map[string]string{},
+		"nested":  map[string]interface{}{"empty": map[string]interface{}{"value": ""}},
+	}
+	omitEmpty(data)
+
+	assert.Equal(t, map[string]interface{}{
+		"message": "hello",
+		"count":   float64(0),
+		"ok":      false,
+		"docker":  map[string]interface{}{"name": "/name"},
+	}, data)
+}
+
+func TestStreamWithRenames(t *testing.T) {
+	assert := assert.New(t)
+
+	renames, _ := parseRenames("docker.name:container_name,stream:log_stream")
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:   new(router.Route),
+		conn:    conn,
+		renames: renames,
+	}
+
+	container := docker.Container{ID: "ID", Name: "/name", Config: &docker.Config{}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Source: "stdout", Data: `foo bananas`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Source: "stderr", Data: `{"status":"200"}`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 2) {
+		for _, data := range lines {
+			assert.Equal("/name", data["container_name"])
+			assert.Nil(data["docker"].(map[string]interface{})["name"])
+			assert.Equal("ID", data["docker"].(map[string]interface{})["id"])
+			assert.Nil(data["stream"])
+		}
+		assert.Equal("foo bananas", lines[0]["message"])
+		assert.Equal("stdout", lines[0]["log_stream"])
+		assert.Equal("200", lines[1]["status"])
+		assert.Equal("stderr", lines[1]["log_stream"])
+	}
+}
+
+func TestFlattenDocker(t *testing.T) {
+	assert := assert.New(t)
+
+	data := map[string]interface{}{
+		"message":  "hello",
+		"hostname": "taken",
+		"docker":   DockerInfo{Name: "/name", ID: "ID", Image: "image:1.0", Hostname: "abc", Labels: map[string]string{"a": "b"}},
+	}
+	flattenDocker(data)
+	assert.Equal(map[string]interface{}{
+		"message":          "hello",
+		"hostname":         "taken",
+		"container_name":   "/name",
+		"container_id":     "ID",
+		"image_name":       "image:1.0",
+		"container_labels": map[string]interface{}{"a": "b"},
+		"docker":           map[string]interface{}{"hostname": "abc"},
+	}, data)
+
+	data = map[string]interface{}{"docker": DockerInfo{Name: "/name"}}
+	flattenDocker(data)
+	assert.Nil(data["docker"])
+	assert.Equal("/name", data["container_name"])
+	assert.Equal("", data["hostname"])
+}
+
+func TestStreamFlatLayout(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:  new(router.Route),
+		conn:   conn,
+		layout: "flat",
+	}
+
+	container := docker.Container{ID: "ID", Name: "/name", Config: &docker.Config{Image: "image:1.0", Hostname: "abc"}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: `foo bananas`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `{"status":"200"}`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 2) {
+		for _, data := range lines {
+			assert.Nil(data["docker"])
+			assert.Equal("/name", data["container_name"])
+			assert.Equal("ID", data["container_id"])
+			assert.Equal("image:1.0", data["image_name"])
+			assert.Equal("abc", data["hostname"])
+		}
+	}
+}
+
+func TestStreamWithNamespace(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:     new(router.Route),
+		conn:      conn,
+		namespace: "logspout",
+		layout:    "flat",
+	}
+
+	container := docker.Container{ID: "ID", Name: "/name", Config: &docker.Config{Env: []string{"LOGSTASH_TAGS=web", "LOGSTASH_TYPE=nginx"}}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Source: "stdout", Data: `foo bananas`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Source: "stdout", Data: `{"tags":"app","stream":"orders"}`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 2) {
+		assert.Equal("foo bananas", lines[0]["message"])
+		assert.Equal("app", lines[1]["tags"])
+		assert.Equal("orders", lines[1]["stream"])
+		for _, data := range lines {
+			assert.Equal("nginx", data["type"])
+			ns := data["logspout"].(map[string]interface{})
+			assert.Equal([]interface{}{"web"}, ns["tags"])
+			assert.Equal("stdout", ns["stream"])
+			assert.Equal("/name", ns["container_name"])
+			assert.Nil(ns["marathon"])
+		}
+	}
+}
+
+func TestStreamUpstreamLayout(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:  new(router.Route),
+		conn:   conn,
+		layout: "upstream",
+		labels: true,
+	}
+
+	container := docker.Container{ID: "ID", Name: "/name", Config: &docker.Config{
+		Image:    "image:1.0",
+		Hostname: "abc",
+		Env:      []string{"LOGSTASH_TAGS=web", "LOGSTASH_FIELDS=team=payments", "MARATHON_APP_ID=/app"},
+		Labels:   map[string]string{"com.example.team": "payments", "com.docker.compose.service": "web"},
+	}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Source: "stdout", Data: `foo bananas`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Source: "stdout", Data: `{"status":"200"}`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	dockerBlock := map[string]interface{}{
+		"name":     "/name",
+		"id":       "ID",
+		"image":    "image:1.0",
+		"hostname": "abc",
+		"labels": map[string]interface{}{
+			"com_example_team":           "payments",
+			"com_docker_compose_service": "web",
+		},
+	}
+	lines := conn.Lines()
+	if assert.Len(lines, 2) {
+		assert.Equal(map[string]interface{}{
+			"message": "foo bananas",
+			"stream":  "stdout",
+			"docker":  dockerBlock,
+			"tags":    []interface{}{"web"},
+			"team":    "payments",
+		}, lines[0])
+		assert.Equal(map[string]interface{}{
+			"status": "200",
+			"stream": "stdout",
+			"docker": dockerBlock,
+			"tags":   []interface{}{"web"},
+			"team":   "payments",
+		}, lines[1])
+	}
+}
+
+func TestStreamWithDroppedFields(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:      new(router.Route),
+		conn:       conn,
+		dropFields: [][]string{{"docker", "hostname"}},
+		omitEmpty:  true,
+	}
+
+	container := docker.Container{ID: "ID", Name: "/name", Config: &docker.Config{Hostname: "abc"}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: `foo bananas`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `{"status":""}`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 2) {
+		assert.Equal(map[string]interface{}{
+			"message": "foo bananas",
+			"docker":  map[string]interface{}{"name": "/name", "id": "ID"},
+		}, lines[0])
+		assert.Equal(map[string]interface{}{
+			"docker": map[string]interface{}{"name": "/name", "id": "ID"},
+		}, lines[1])
+	}
+}
@@ -0,0 +1,85 @@
+package logstash
+
+import (
+	"regexp"
+	"strings"
+)
+
+// levelNames are the normalized names of the syslog severity levels.
+var levelNames = [8]string{"emergency", "alert", "critical", "error", "warning", "notice", "info", "debug"}
+
+// levelCodes backs the severity codes of LogstashMessage.
+var levelCodes = [8]int{0, 1, 2, 3, 4, 5, 6, 7}
+
+// levelToken matches the severity of a text line: an upper case level name,
+// a bracketed one such as [error], or a level=... pair.
+var levelToken = regexp.MustCompile(`\b(TRACE|DEBUG|INFO|NOTICE|WARN|WARNING|ERR|ERROR|CRIT|CRITICAL|FATAL|PANIC|ALERT|EMERG|EMERGENCY)\b|\[([A-Za-z]+)\]|\blevel=["']?([A-Za-z]+)`)
+
+// levelPrefix is how much of a text line is searched for its severity, so
+// that words in the message itself are not mistaken for it.
+const levelPrefix = 100
+
+// levelKeys are the keys JSON messages carry their severity in.
+var levelKeys = []string{"level", "severity", "lvl", "loglevel", "log_level"}
+
+// levelName returns the name of a severity level, or "" if it is -1.
+func levelName(level int) string {
+	if level < 0 {
+		return ""
+	}
+	return levelNames[level]
+}
+
+// levelCode returns a pointer to a severity level, or nil if it is -1.
+func levelCode(level int) *int {
+	if level < 0 {
+		return nil
+	}
+	return &levelCodes[level]
+}
+
+// textLevel returns the syslog severity of a text line, or -1 if none is
+// found near its start.
+func textLevel(line string) int {
+	if len(line) > levelPrefix {
+		line = line[:levelPrefix]
+	}
+	for _, match := range levelToken.FindAllStringSubmatch(line, -1) {
+		for _, name := range match[1:] {
+			if level, ok := gelfLevels[strings.ToLower(name)]; ok {
+				return level
+			}
+		}
+	}
+	return -1
+}
+
+// jsonLevel returns the syslog severity of a decoded JSON message, or -1 if
+// it has none. Numbers from 0 to 7 are syslog severities, and 10 to 60 the
+// levels of pino and bunyan.
+func jsonLevel(data map[string]interface{}) int {
+	for _, key := range levelKeys {
+		switch v := data[key].(type) {
+		case string:
+			if level, ok := gelfLevels[strings.ToLower(v)]; ok {
+				return level
+			}
+		case float64:
+			switch {
+			case v >= 0 && v <= 7 && v == float64(int(v)):
+				return int(v)
+			case v >= 10 && v < 30:
+				return 7
+			case v >= 30 && v < 40:
+				return 6
+			case v >= 40 && v < 50:
+				return 4
+			case v >= 50 && v < 60:
+				return 3
+			case v >= 60 && v <= 70:
+				return 2
+			}
+		}
+	}
+	return -1
+}
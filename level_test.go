@@ -0,0 +1,92 @@
+package logstash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTextLevel(t *testing.T) {
+	assert := assert.New(t)
+
+	for line, expected := range map[string]int{
+		"2016-10-20 13:25:13 ERROR payment failed":       3,
+		"[warn] disk almost full":                        4,
+		"13:25:13 [main] [Info] started":                 6,
+		`time=now level=debug msg="cache miss"`:          7,
+		`level="FATAL" msg=bye`:                          2,
+		"WARNING: deprecated flag":                       4,
+		"panic: runtime error":                           -1,
+		"started, no errors":                             -1,
+		"Info about the Error":                           -1,
+		"plain":                                          -1,
+		"x" + string(make([]byte, levelPrefix)) + "INFO": -1,
+	} {
+		assert.Equal(expected, textLevel(line), line)
+	}
+}
+
+func TestJSONLevel(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, c := range []struct {
+		data     map[string]interface{}
+		expected int
+	}{
+		{map[string]interface{}{"level": "WARN"}, 4},
+		{map[string]interface{}{"severity": "critical"}, 2},
+		{map[string]interface{}{"lvl": "trace"}, 7},
+		{map[string]interface{}{"log_level": 3.0}, 3},
+		{map[string]interface{}{"level": 30.0}, 6},
+		{map[string]interface{}{"level": 50.0}, 3},
+		{map[string]interface{}{"level": 60.0}, 2},
+		{map[string]interface{}{"level": 8.0}, -1},
+		{map[string]interface{}{"level": "verbose", "severity": "info"}, 6},
+		{map[string]interface{}{"message": "ERROR"}, -1},
+	} {
+		assert.Equal(c.expected, jsonLevel(c.data), c.data)
+	}
+}
+
+func TestStreamWithLevels(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:         new(router.Route),
+		conn:          conn,
+		levels:        true,
+		syslogHeaders: true,
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{}}
+	quiet := docker.Container{ID: "quiet", Config: &docker.Config{Env: []string{"LOGSTASH_DETECT_LEVEL=false"}}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: "ERROR payment failed", Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `{"level":40,"msg":"slow"}`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: "<13>Oct 11 22:14:15 web app: started", Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: "plain", Time: time.Now()}
+		logstream <- &router.Message{Container: &quiet, Data: "ERROR payment failed", Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 5) {
+		assert.Equal("error", lines[0]["level"])
+		assert.Equal(3.0, lines[0]["severity_code"])
+		assert.Equal("warning", lines[1]["level"])
+		assert.Equal(4.0, lines[1]["severity_code"])
+		assert.Equal("notice", lines[2]["level"])
+		assert.Equal(5.0, lines[2]["severity_code"])
+		assert.Nil(lines[3]["level"])
+		assert.Nil(lines[3]["severity_code"])
+		assert.Nil(lines[4]["level"])
+	}
+}
@@ -0,0 +1,69 @@
+package logstash
+
+import (
+	"strconv"
+	"strings"
+)
+
+// parseLogfmt decodes a logfmt line such as
+// level=info msg="request done" status=200 into its keys and values. ok is
+// false unless the whole line consists of key=value pairs, so that plain
+// text is never mistaken for logfmt. Values are kept as strings.
+func parseLogfmt(line string) (fields map[string]interface{}, ok bool) {
+	s := strings.TrimSpace(line)
+	for s != "" {
+		eq := strings.IndexAny(s, "= \t\"")
+		if eq <= 0 || s[eq] != '=' {
+			return nil, false
+		}
+		key := s[:eq]
+		s = s[eq+1:]
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := closingQuote(s)
+			if end < 0 {
+				return nil, false
+			}
+			var err error
+			if value, err = strconv.Unquote(s[:end+1]); err != nil {
+				return nil, false
+			}
+			s = s[end+1:]
+			if s != "" && s[0] != ' ' && s[0] != '\t' {
+				return nil, false
+			}
+		} else {
+			end := strings.IndexAny(s, " \t")
+			if end < 0 {
+				end = len(s)
+			}
+			value = s[:end]
+			if strings.Contains(value, `"`) {
+				return nil, false
+			}
+			s = s[end:]
+		}
+		s = strings.TrimLeft(s, " \t")
+
+		if fields == nil {
+			fields = make(map[string]interface{})
+		}
+		fields[key] = value
+	}
+	return fields, fields != nil
+}
+
+// closingQuote returns the index of the quote that closes the quoted string
+// s starts with, or -1 if there is none.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
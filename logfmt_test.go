@@ -0,0 +1,67 @@
+package logstash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLogfmt(t *testing.T) {
+	assert := assert.New(t)
+
+	fields, ok := parseLogfmt(`level=info msg="request \"done\"" status=200 path=/a?b=c empty= quoted=""`)
+	assert.True(ok)
+	assert.Equal(map[string]interface{}{
+		"level":  "info",
+		"msg":    `request "done"`,
+		"status": "200",
+		"path":   "/a?b=c",
+		"empty":  "",
+		"quoted": "",
+	}, fields)
+
+	for _, line := range []string{
+		"",
+		"plain text",
+		"GET /index.html status=200",
+		`msg="unterminated`,
+		`msg="closed"trailing`,
+		`=value`,
+		`key=a"b`,
+	} {
+		_, ok := parseLogfmt(line)
+		assert.False(ok, line)
+	}
+}
+
+func TestStreamWithLogfmt(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route: new(router.Route),
+		conn:  conn,
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Env: []string{"LOGSTASH_FORMAT=logfmt"}}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: `level=info msg=started`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `panic: oops`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 2) {
+		assert.Equal("level=info msg=started", lines[0]["message"])
+		assert.Equal(map[string]interface{}{"level": "info", "msg": "started"}, lines[0]["logfmt"])
+		assert.Equal("panic: oops", lines[1]["message"])
+		assert.Nil(lines[1]["logfmt"])
+	}
+}
@@ -4,8 +4,15 @@ import (
 	"encoding/json"
 	"errors"
 	"log"
+	"math"
 	"net"
+	"os"
+	"regexp"
+	"strconv"
 	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
 
 	"github.com/fsouza/go-dockerclient"
 	"github.com/gliderlabs/logspout/router"
@@ -17,9 +24,176 @@ func init() {
 
 // LogstashAdapter is an adapter that streams UDP JSON to Logstash.
 type LogstashAdapter struct {
-	conn          net.Conn
-	route         *router.Route
-	containerTags map[string][]string
+	conn                    net.Conn
+	route                   *router.Route
+	transport               router.AdapterTransport
+	containers              map[string]*containerMeta
+	envWhitelist            []string
+	tagsLabel               string
+	defaultTags             []string
+	stderrTag               string
+	stderrType              string
+	indexField              hintField
+	indexTemplate           *template.Template
+	pipelineField           hintField
+	typeField               hintField
+	defaultType             string
+	messageField            string
+	rawMessageField         string
+	jsonKey                 string
+	logfmtKey               string
+	nestedJSON              [][]string
+	sanitizeKeys            bool
+	syslogHeaders           bool
+	stripANSI               bool
+	utf8Escapes             bool
+	maxEventSize            int
+	chunkOversize           bool
+	levels                  bool
+	redact                  []string
+	redactMask              string
+	script                  *script
+	rules                   []fieldRule
+	dropPattern             *regexp.Regexp
+	rateLimit               float64
+	rateBurst               int
+	rateLimitDrop           bool
+	dedupWindow             time.Duration
+	priorities              bool
+	slowConsumerInterval    time.Duration
+	eventCap                *throttle
+	byteCap                 *throttle
+	include                 containerFilter
+	exclude                 containerFilter
+	multilineTimeout        time.Duration
+	multilineMaxLines       int
+	partialLines            bool
+	flattenDepth            int
+	flattenDelimiter        string
+	coerceJSON              string
+	maxFields               int
+	overflowField           string
+	renames                 []fieldRename
+	dropFields              [][]string
+	omitEmpty               bool
+	layout                  string
+	namespace               string
+	wireFormat              string
+	timestamps              bool
+	timestampField          string
+	parseTimestamps         bool
+	eventIDs                bool
+	sequences               *sequencer
+	gelfHost                string
+	gelfUDP                 bool
+	stderrSeverity          string
+	fields                  map[string]string
+	docker                  dockerClient
+	imageDigests            map[string]string
+	stats                   *statsSampler
+	events                  dockerClient
+	history                 dockerClient
+	backfillAge             time.Duration
+	backfillBytes           int
+	dockerEvents            map[string]bool
+	containerState          bool
+	command                 bool
+	commandRedact           bool
+	labels                  bool
+	labelsAllow             *nameFilter
+	labelsDeny              *nameFilter
+	marathonDisabled        bool
+	jsonDisabled            bool
+	mesosDisabled           bool
+	marathonResourceStrings bool
+	marathonLabelsStrict    bool
+	marathonLabelsAllow     map[string]bool
+	marathonLabelsDeny      map[string]bool
+	dcosNode                *DCOSData
+	cloud                   *CloudData
+	node                    *HostData
+	shipper                 *ShipperInfo
+	sourceHost              string
+	shipperMetadata         bool
+	packets                 packetBatchWriter
+	compressor              compressor
+	compression             string
+	compressionLevel        string
+	batch                   [][]byte
+	arena                   []byte
+	batchSize               int
+	flushInterval           time.Duration
+	perContainer            bool
+	tenantLabel             string
+	tenantField             hintField
+	tenantIsolation         bool
+	queueSize               int
+	idleTimeout             time.Duration
+	failed                  chan struct{}
+	endpoints               []string
+	ring                    *hashRing
+	shards                  []*LogstashAdapter
+	poolSize                int
+	poolOrdered             bool
+	stageBuffer             int
+	serializeWorkers        int
+	routes                  []messageRoute
+	mirror                  *mirror
+}
+
+func getopt(name, dfault string) string {
+	value := os.Getenv(name)
+	if value == "" {
+		value = dfault
+	}
+	return value
+}
+
+// routeOptionAliases are the short names of some route options.
+var routeOptionAliases = map[string]string{
+	"LOGSTASH_RENAME_FIELDS": "rename",
+	"LOGSTASH_DEFAULT_TAGS":  "tags",
+	"LOGSTASH_DEFAULT_TYPE":  "type",
+	"LOGSTASH_DECODE_JSON":   "parse_json",
+}
+
+// routeopt returns the option of a route named like the environment variable
+// without its LOGSTASH_ prefix, in lower case, e.g. default_tags for
+// LOGSTASH_DEFAULT_TAGS, or else getopt(name, dfault). Route options come
+// from the query string of the route address, so each route can be
+// configured on its own.
+func routeopt(route *router.Route, name, dfault string) string {
+	if value, ok := route.Options[strings.ToLower(strings.TrimPrefix(name, "LOGSTASH_"))]; ok {
+		return value
+	}
+	if alias, ok := routeOptionAliases[name]; ok {
+		if value, ok := route.Options[alias]; ok {
+			return value
+		}
+	}
+	return getopt(name, dfault)
+}
+
+// splitList splits a comma-separated list into its trimmed, non-empty
+// values.
+func splitList(list string) []string {
+	var values []string
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// getset splits a comma-separated list into a set of its upper-cased,
+// trimmed, non-empty values.
+func getset(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, v := range splitList(list) {
+		set[strings.ToUpper(v)] = true
+	}
+	return set
 }
 
 // NewLogstashAdapter creates a LogstashAdapter with UDP as the default transport.
@@ -29,40 +203,763 @@ func NewLogstashAdapter(route *router.Route) (router.LogAdapter, error) {
 		return nil, errors.New("unable to find adapter: " + route.Adapter)
 	}
 
-	conn, err := transport.Dial(route.Address, route.Options)
+	batchSize, err := strconv.Atoi(routeopt(route, "LOGSTASH_BATCH_SIZE", "1"))
+	if err != nil || batchSize < 1 {
+		return nil, errors.New("invalid LOGSTASH_BATCH_SIZE: " + routeopt(route, "LOGSTASH_BATCH_SIZE", ""))
+	}
+
+	flushInterval, err := time.ParseDuration(routeopt(route, "LOGSTASH_FLUSH_INTERVAL", "1s"))
+	if err != nil || flushInterval <= 0 {
+		return nil, errors.New("invalid LOGSTASH_FLUSH_INTERVAL: " + routeopt(route, "LOGSTASH_FLUSH_INTERVAL", ""))
+	}
+
+	compression := routeopt(route, "LOGSTASH_COMPRESSION", "")
+	compressionLevel := routeopt(route, "LOGSTASH_COMPRESSION_LEVEL", "")
+	compressor, err := newCompressor(compression, compressionLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	perContainer, err := strconv.ParseBool(routeopt(route, "LOGSTASH_PER_CONTAINER_CONNECTIONS", "false"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_PER_CONTAINER_CONNECTIONS: " + routeopt(route, "LOGSTASH_PER_CONTAINER_CONNECTIONS", ""))
+	}
+
+	tenantLabel := routeopt(route, "LOGSTASH_TENANT_LABEL", "")
+	tenantField, err := parseHintField(routeopt(route, "LOGSTASH_TENANT_FIELD", "tenant"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_TENANT_FIELD: " + err.Error())
+	}
+
+	tenantIsolation, err := strconv.ParseBool(routeopt(route, "LOGSTASH_TENANT_ISOLATION", "false"))
+	if err != nil || tenantIsolation && tenantLabel == "" {
+		return nil, errors.New("invalid LOGSTASH_TENANT_ISOLATION: " + routeopt(route, "LOGSTASH_TENANT_ISOLATION", ""))
+	}
+
+	queueSize, err := strconv.Atoi(routeopt(route, "LOGSTASH_CONTAINER_QUEUE_SIZE", "1024"))
+	if err != nil || queueSize < 1 {
+		return nil, errors.New("invalid LOGSTASH_CONTAINER_QUEUE_SIZE: " + routeopt(route, "LOGSTASH_CONTAINER_QUEUE_SIZE", ""))
+	}
+
+	idleTimeout, err := time.ParseDuration(routeopt(route, "LOGSTASH_CONTAINER_IDLE_TIMEOUT", "5m"))
+	if err != nil || idleTimeout <= 0 {
+		return nil, errors.New("invalid LOGSTASH_CONTAINER_IDLE_TIMEOUT: " + routeopt(route, "LOGSTASH_CONTAINER_IDLE_TIMEOUT", ""))
+	}
+
+	poolSize, err := strconv.Atoi(routeopt(route, "LOGSTASH_POOL_SIZE", "1"))
+	if err != nil || poolSize < 1 {
+		return nil, errors.New("invalid LOGSTASH_POOL_SIZE: " + routeopt(route, "LOGSTASH_POOL_SIZE", ""))
+	}
+
+	poolOrdered, err := strconv.ParseBool(routeopt(route, "LOGSTASH_POOL_ORDERED", "true"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_POOL_ORDERED: " + routeopt(route, "LOGSTASH_POOL_ORDERED", ""))
+	}
+
+	stageBuffer, err := strconv.Atoi(routeopt(route, "LOGSTASH_STAGE_BUFFER", "1024"))
+	if err != nil || stageBuffer < 0 {
+		return nil, errors.New("invalid LOGSTASH_STAGE_BUFFER: " + routeopt(route, "LOGSTASH_STAGE_BUFFER", ""))
+	}
+
+	serializeWorkers, err := strconv.Atoi(routeopt(route, "LOGSTASH_SERIALIZE_WORKERS", "1"))
+	if err != nil || serializeWorkers < 1 {
+		return nil, errors.New("invalid LOGSTASH_SERIALIZE_WORKERS: " + routeopt(route, "LOGSTASH_SERIALIZE_WORKERS", ""))
+	}
+
+	marathonEnabled, err := strconv.ParseBool(routeopt(route, "LOGSTASH_MARATHON", "true"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_MARATHON: " + routeopt(route, "LOGSTASH_MARATHON", ""))
+	}
+
+	decodeJSON, err := strconv.ParseBool(routeopt(route, "LOGSTASH_DECODE_JSON", "true"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_DECODE_JSON: " + routeopt(route, "LOGSTASH_DECODE_JSON", ""))
+	}
+
+	mesosEnabled, err := strconv.ParseBool(routeopt(route, "LOGSTASH_MESOS", "true"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_MESOS: " + routeopt(route, "LOGSTASH_MESOS", ""))
+	}
+
+	marathonResourceStrings, err := strconv.ParseBool(routeopt(route, "LOGSTASH_MARATHON_RESOURCES_AS_STRINGS", "false"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_MARATHON_RESOURCES_AS_STRINGS: " + routeopt(route, "LOGSTASH_MARATHON_RESOURCES_AS_STRINGS", ""))
+	}
+
+	marathonLabelsStrict, err := strconv.ParseBool(routeopt(route, "LOGSTASH_MARATHON_LABELS_STRICT", "false"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_MARATHON_LABELS_STRICT: " + routeopt(route, "LOGSTASH_MARATHON_LABELS_STRICT", ""))
+	}
+
+	dcosEnabled, err := strconv.ParseBool(routeopt(route, "LOGSTASH_DCOS", "false"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_DCOS: " + routeopt(route, "LOGSTASH_DCOS", ""))
+	}
+
+	cloudProvider := routeopt(route, "LOGSTASH_CLOUD_METADATA", "")
+	if _, ok := cloudProviders[cloudProvider]; !ok && cloudProvider != "" && cloudProvider != "auto" {
+		return nil, errors.New("invalid LOGSTASH_CLOUD_METADATA: " + cloudProvider)
+	}
+
+	cloudTimeout, err := time.ParseDuration(routeopt(route, "LOGSTASH_CLOUD_METADATA_TIMEOUT", "2s"))
+	if err != nil || cloudTimeout <= 0 {
+		return nil, errors.New("invalid LOGSTASH_CLOUD_METADATA_TIMEOUT: " + routeopt(route, "LOGSTASH_CLOUD_METADATA_TIMEOUT", ""))
+	}
+
+	hostEnabled, err := strconv.ParseBool(routeopt(route, "LOGSTASH_HOST_METADATA", "false"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_HOST_METADATA: " + routeopt(route, "LOGSTASH_HOST_METADATA", ""))
+	}
+
+	labels, err := strconv.ParseBool(routeopt(route, "LOGSTASH_DOCKER_LABELS", "false"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_DOCKER_LABELS: " + routeopt(route, "LOGSTASH_DOCKER_LABELS", ""))
+	}
+
+	labelsAllow, err := newNameFilter(routeopt(route, "LOGSTASH_DOCKER_LABELS_ALLOW", ""))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_DOCKER_LABELS_ALLOW: " + err.Error())
+	}
+
+	labelsDeny, err := newNameFilter(routeopt(route, "LOGSTASH_DOCKER_LABELS_DENY", ""))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_DOCKER_LABELS_DENY: " + err.Error())
+	}
+
+	imageDigests, err := strconv.ParseBool(routeopt(route, "LOGSTASH_IMAGE_DIGEST", "false"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_IMAGE_DIGEST: " + routeopt(route, "LOGSTASH_IMAGE_DIGEST", ""))
+	}
+
+	containerState, err := strconv.ParseBool(routeopt(route, "LOGSTASH_CONTAINER_STATE", "false"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_CONTAINER_STATE: " + routeopt(route, "LOGSTASH_CONTAINER_STATE", ""))
+	}
+
+	command, err := strconv.ParseBool(routeopt(route, "LOGSTASH_DOCKER_COMMAND", "false"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_DOCKER_COMMAND: " + routeopt(route, "LOGSTASH_DOCKER_COMMAND", ""))
+	}
+
+	commandRedact, err := strconv.ParseBool(routeopt(route, "LOGSTASH_DOCKER_COMMAND_REDACT", "false"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_DOCKER_COMMAND_REDACT: " + routeopt(route, "LOGSTASH_DOCKER_COMMAND_REDACT", ""))
+	}
+
+	statsInterval, err := time.ParseDuration(routeopt(route, "LOGSTASH_STATS_INTERVAL", "0"))
+	if err != nil || statsInterval < 0 {
+		return nil, errors.New("invalid LOGSTASH_STATS_INTERVAL: " + routeopt(route, "LOGSTASH_STATS_INTERVAL", ""))
+	}
+
+	dockerEvents := getset(routeopt(route, "LOGSTASH_DOCKER_EVENTS", ""))
+
+	defaultTags := splitList(routeopt(route, "LOGSTASH_DEFAULT_TAGS", ""))
+	for _, tag := range defaultTags {
+		if _, err := parseTagTemplate(tag); err != nil {
+			return nil, errors.New("invalid LOGSTASH_DEFAULT_TAGS: " + err.Error())
+		}
+	}
+
+	indexField, err := parseHintField(routeopt(route, "LOGSTASH_INDEX_FIELD", "[@metadata][index]"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_INDEX_FIELD: " + err.Error())
+	}
+
+	pipelineField, err := parseHintField(routeopt(route, "LOGSTASH_PIPELINE_FIELD", "[@metadata][pipeline]"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_PIPELINE_FIELD: " + err.Error())
+	}
+
+	// The type is a top-level field of LogstashMessage unless it is moved
+	// elsewhere.
+	var typeField hintField
+	if s := routeopt(route, "LOGSTASH_TYPE_FIELD", "type"); s != "type" {
+		if typeField, err = parseHintField(s); err != nil {
+			return nil, errors.New("invalid LOGSTASH_TYPE_FIELD: " + err.Error())
+		}
+	}
+
+	layout := routeopt(route, "LOGSTASH_LAYOUT", "nested")
+	if layout != "nested" && layout != "flat" && layout != "upstream" {
+		return nil, errors.New("invalid LOGSTASH_LAYOUT: " + layout)
+	}
+
+	wireFormat := routeopt(route, "LOGSTASH_WIRE_FORMAT", "json")
+	if wireFormat != "json" && wireFormat != "gelf" {
+		return nil, errors.New("invalid LOGSTASH_WIRE_FORMAT: " + wireFormat)
+	}
+	gelfHost, _ := os.Hostname()
+
+	timestamps, err := strconv.ParseBool(routeopt(route, "LOGSTASH_TIMESTAMP", "false"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_TIMESTAMP: " + routeopt(route, "LOGSTASH_TIMESTAMP", ""))
+	}
+
+	parseTimestamps, err := strconv.ParseBool(routeopt(route, "LOGSTASH_TIMESTAMP_PARSE", "false"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_TIMESTAMP_PARSE: " + routeopt(route, "LOGSTASH_TIMESTAMP_PARSE", ""))
+	}
+
+	eventIDs, err := strconv.ParseBool(routeopt(route, "LOGSTASH_EVENT_ID", "false"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_EVENT_ID: " + routeopt(route, "LOGSTASH_EVENT_ID", ""))
+	}
+
+	shipperMode := routeopt(route, "LOGSTASH_SHIPPER", "none")
+	if shipperMode != "none" && shipperMode != "block" && shipperMode != "metadata" {
+		return nil, errors.New("invalid LOGSTASH_SHIPPER: " + shipperMode)
+	}
+
+	namespace := routeopt(route, "LOGSTASH_NAMESPACE", "")
+	if namespace == "message" || namespace == "type" || namespace == "@metadata" {
+		return nil, errors.New("invalid LOGSTASH_NAMESPACE: " + namespace)
+	}
+
+	renames, err := parseRenames(routeopt(route, "LOGSTASH_RENAME_FIELDS", ""))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_RENAME_FIELDS: " + err.Error())
+	}
+
+	dropFields, err := parseFieldPaths(routeopt(route, "LOGSTASH_DROP_FIELDS", ""))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_DROP_FIELDS: " + err.Error())
+	}
+
+	omitEmpty, err := strconv.ParseBool(routeopt(route, "LOGSTASH_OMIT_EMPTY", "false"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_OMIT_EMPTY: " + routeopt(route, "LOGSTASH_OMIT_EMPTY", ""))
+	}
+
+	rawMessageField := routeopt(route, "LOGSTASH_RAW_MESSAGE_FIELD", "")
+	if reservedFields[rawMessageField] || rawMessageField == routeopt(route, "LOGSTASH_MESSAGE_FIELD", "message") {
+		return nil, errors.New("invalid LOGSTASH_RAW_MESSAGE_FIELD: " + rawMessageField)
+	}
+
+	nestedJSON, err := parseFieldPaths(routeopt(route, "LOGSTASH_DECODE_NESTED_JSON", ""))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_DECODE_NESTED_JSON: " + err.Error())
+	}
+
+	multilineTimeout, err := time.ParseDuration(routeopt(route, "LOGSTASH_MULTILINE_TIMEOUT", "1s"))
+	if err != nil || multilineTimeout <= 0 {
+		return nil, errors.New("invalid LOGSTASH_MULTILINE_TIMEOUT: " + routeopt(route, "LOGSTASH_MULTILINE_TIMEOUT", ""))
+	}
+
+	multilineMaxLines, err := strconv.Atoi(routeopt(route, "LOGSTASH_MULTILINE_MAX_LINES", "500"))
+	if err != nil || multilineMaxLines < 1 {
+		return nil, errors.New("invalid LOGSTASH_MULTILINE_MAX_LINES: " + routeopt(route, "LOGSTASH_MULTILINE_MAX_LINES", ""))
+	}
+
+	partialLines, err := strconv.ParseBool(routeopt(route, "LOGSTASH_JOIN_PARTIAL_LINES", "false"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_JOIN_PARTIAL_LINES: " + routeopt(route, "LOGSTASH_JOIN_PARTIAL_LINES", ""))
+	}
+
+	maxEventSize, err := strconv.Atoi(routeopt(route, "LOGSTASH_MAX_EVENT_SIZE", "0"))
+	if err != nil || maxEventSize < 0 {
+		return nil, errors.New("invalid LOGSTASH_MAX_EVENT_SIZE: " + routeopt(route, "LOGSTASH_MAX_EVENT_SIZE", ""))
+	}
+
+	var chunkOversize bool
+	switch routeopt(route, "LOGSTASH_OVERSIZE", "truncate") {
+	case "truncate":
+	case "chunk":
+		chunkOversize = true
+	default:
+		return nil, errors.New("invalid LOGSTASH_OVERSIZE: " + routeopt(route, "LOGSTASH_OVERSIZE", ""))
+	}
+
+	var utf8Escapes bool
+	switch routeopt(route, "LOGSTASH_INVALID_UTF8", "replace") {
+	case "replace":
+	case "hex":
+		utf8Escapes = true
+	default:
+		return nil, errors.New("invalid LOGSTASH_INVALID_UTF8: " + routeopt(route, "LOGSTASH_INVALID_UTF8", ""))
+	}
+
+	redact := splitList(routeopt(route, "LOGSTASH_REDACT", ""))
+	for _, name := range redact {
+		if redactors[strings.ToLower(name)] == nil {
+			return nil, errors.New("invalid LOGSTASH_REDACT: " + name)
+		}
+	}
+
+	rateLimit, err := strconv.ParseFloat(routeopt(route, "LOGSTASH_RATE_LIMIT", "0"), 64)
+	if err != nil || rateLimit < 0 || math.IsInf(rateLimit, 0) || math.IsNaN(rateLimit) {
+		return nil, errors.New("invalid LOGSTASH_RATE_LIMIT: " + routeopt(route, "LOGSTASH_RATE_LIMIT", ""))
+	}
+
+	rateBurst, err := strconv.Atoi(routeopt(route, "LOGSTASH_RATE_BURST", "0"))
+	if err != nil || rateBurst < 0 {
+		return nil, errors.New("invalid LOGSTASH_RATE_BURST: " + routeopt(route, "LOGSTASH_RATE_BURST", ""))
+	}
+
+	var rateLimitDrop bool
+	switch routeopt(route, "LOGSTASH_RATE_LIMIT_ACTION", "summarize") {
+	case "summarize":
+	case "drop":
+		rateLimitDrop = true
+	default:
+		return nil, errors.New("invalid LOGSTASH_RATE_LIMIT_ACTION: " + routeopt(route, "LOGSTASH_RATE_LIMIT_ACTION", ""))
+	}
+
+	dedupWindow, err := time.ParseDuration(routeopt(route, "LOGSTASH_DEDUP_WINDOW", "0s"))
+	if err != nil || dedupWindow < 0 {
+		return nil, errors.New("invalid LOGSTASH_DEDUP_WINDOW: " + routeopt(route, "LOGSTASH_DEDUP_WINDOW", ""))
+	}
+
+	slowConsumerInterval, err := time.ParseDuration(routeopt(route, "LOGSTASH_SLOW_CONSUMER_INTERVAL", "0s"))
+	if err != nil || slowConsumerInterval < 0 {
+		return nil, errors.New("invalid LOGSTASH_SLOW_CONSUMER_INTERVAL: " + routeopt(route, "LOGSTASH_SLOW_CONSUMER_INTERVAL", ""))
+	}
+
+	var priorities bool
+	switch routeopt(route, "LOGSTASH_BACKPRESSURE", "block") {
+	case "block":
+	case "priority":
+		priorities = true
+	default:
+		return nil, errors.New("invalid LOGSTASH_BACKPRESSURE: " + routeopt(route, "LOGSTASH_BACKPRESSURE", ""))
+	}
+
+	eventsPerSecond, err := strconv.ParseFloat(routeopt(route, "LOGSTASH_MAX_EVENTS_PER_SECOND", "0"), 64)
+	if err != nil || eventsPerSecond < 0 || math.IsInf(eventsPerSecond, 0) || math.IsNaN(eventsPerSecond) {
+		return nil, errors.New("invalid LOGSTASH_MAX_EVENTS_PER_SECOND: " + routeopt(route, "LOGSTASH_MAX_EVENTS_PER_SECOND", ""))
+	}
+
+	eventsBurst, err := strconv.Atoi(routeopt(route, "LOGSTASH_BURST_EVENTS", "0"))
+	if err != nil || eventsBurst < 0 {
+		return nil, errors.New("invalid LOGSTASH_BURST_EVENTS: " + routeopt(route, "LOGSTASH_BURST_EVENTS", ""))
+	}
+
+	bytesPerSecond, err := strconv.ParseFloat(routeopt(route, "LOGSTASH_MAX_BYTES_PER_SECOND", "0"), 64)
+	if err != nil || bytesPerSecond < 0 || math.IsInf(bytesPerSecond, 0) || math.IsNaN(bytesPerSecond) {
+		return nil, errors.New("invalid LOGSTASH_MAX_BYTES_PER_SECOND: " + routeopt(route, "LOGSTASH_MAX_BYTES_PER_SECOND", ""))
+	}
+
+	bytesBurst, err := strconv.Atoi(routeopt(route, "LOGSTASH_BURST_BYTES", "0"))
+	if err != nil || bytesBurst < 0 {
+		return nil, errors.New("invalid LOGSTASH_BURST_BYTES: " + routeopt(route, "LOGSTASH_BURST_BYTES", ""))
+	}
+
+	routes, err := parseRoutes(routeopt(route, "LOGSTASH_ROUTES", ""))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_ROUTES: " + err.Error())
+	}
+
+	var dropPattern *regexp.Regexp
+	if s := routeopt(route, "LOGSTASH_DROP_PATTERN", ""); s != "" {
+		if dropPattern, err = regexp.Compile(s); err != nil {
+			return nil, errors.New("invalid LOGSTASH_DROP_PATTERN: " + err.Error())
+		}
+	}
+
+	var include, exclude containerFilter
+	for _, filter := range []struct {
+		f    *containerFilter
+		kind string
+	}{{&include, "INCLUDE"}, {&exclude, "EXCLUDE"}} {
+		names := "LOGSTASH_" + filter.kind + "_NAMES"
+		if filter.f.names, err = parseGlobs(routeopt(route, names, "")); err != nil {
+			return nil, errors.New("invalid " + names + ": " + err.Error())
+		}
+		images := "LOGSTASH_" + filter.kind + "_IMAGES"
+		if s := routeopt(route, images, ""); s != "" {
+			if filter.f.image, err = regexp.Compile(s); err != nil {
+				return nil, errors.New("invalid " + images + ": " + err.Error())
+			}
+		}
+		labels := "LOGSTASH_" + filter.kind + "_LABELS"
+		if filter.f.labels, err = parseLabelSelector(routeopt(route, labels, "")); err != nil {
+			return nil, errors.New("invalid " + labels + ": " + err.Error())
+		}
+	}
+
+	var rules []fieldRule
+	if path := routeopt(route, "LOGSTASH_RULES_FILE", ""); path != "" {
+		if rules, err = loadRules(path); err != nil {
+			return nil, errors.New("invalid LOGSTASH_RULES_FILE: " + err.Error())
+		}
+	}
+
+	var transform *script
+	if path := routeopt(route, "LOGSTASH_SCRIPT", ""); path != "" {
+		if transform, err = loadScript(path); err != nil {
+			return nil, errors.New("invalid LOGSTASH_SCRIPT: " + err.Error())
+		}
+	}
+
+	levels, err := strconv.ParseBool(routeopt(route, "LOGSTASH_DETECT_LEVEL", "false"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_DETECT_LEVEL: " + routeopt(route, "LOGSTASH_DETECT_LEVEL", ""))
+	}
+
+	stripANSI, err := strconv.ParseBool(routeopt(route, "LOGSTASH_STRIP_ANSI", "false"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_STRIP_ANSI: " + routeopt(route, "LOGSTASH_STRIP_ANSI", ""))
+	}
+
+	syslogHeaders, err := strconv.ParseBool(routeopt(route, "LOGSTASH_SYSLOG_HEADERS", "false"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_SYSLOG_HEADERS: " + routeopt(route, "LOGSTASH_SYSLOG_HEADERS", ""))
+	}
+
+	sanitizeKeys, err := strconv.ParseBool(routeopt(route, "LOGSTASH_SANITIZE_KEYS", "false"))
+	if err != nil {
+		return nil, errors.New("invalid LOGSTASH_SANITIZE_KEYS: " + routeopt(route, "LOGSTASH_SANITIZE_KEYS", ""))
+	}
+
+	flattenDepth, err := strconv.Atoi(routeopt(route, "LOGSTASH_FLATTEN_JSON_DEPTH", "0"))
+	if err != nil || flattenDepth < 0 {
+		return nil, errors.New("invalid LOGSTASH_FLATTEN_JSON_DEPTH: " + routeopt(route, "LOGSTASH_FLATTEN_JSON_DEPTH", ""))
+	}
+
+	coerceJSON := routeopt(route, "LOGSTASH_COERCE_JSON", "none")
+	if coerceJSON != "none" && coerceJSON != "strings" {
+		return nil, errors.New("invalid LOGSTASH_COERCE_JSON: " + coerceJSON)
+	}
+
+	maxFields, err := strconv.Atoi(routeopt(route, "LOGSTASH_MAX_JSON_FIELDS", "0"))
+	if err != nil || maxFields < 0 {
+		return nil, errors.New("invalid LOGSTASH_MAX_JSON_FIELDS: " + routeopt(route, "LOGSTASH_MAX_JSON_FIELDS", ""))
+	}
+
+	jsonKey := routeopt(route, "LOGSTASH_JSON_KEY", "")
+	if reservedFields[jsonKey] || jsonKey == "message" || (jsonKey != "" && jsonKey == rawMessageField) {
+		return nil, errors.New("invalid LOGSTASH_JSON_KEY: " + jsonKey)
+	}
+
+	logfmtKey := routeopt(route, "LOGSTASH_LOGFMT_KEY", "logfmt")
+	if reservedFields[logfmtKey] || logfmtKey == routeopt(route, "LOGSTASH_MESSAGE_FIELD", "message") {
+		return nil, errors.New("invalid LOGSTASH_LOGFMT_KEY: " + logfmtKey)
+	}
+
+	var indexTemplate *template.Template
+	if s := routeopt(route, "LOGSTASH_INDEX_TEMPLATE", ""); s != "" {
+		if indexTemplate, err = template.New("index").Option("missingkey=zero").Parse(s); err != nil {
+			return nil, errors.New("invalid LOGSTASH_INDEX_TEMPLATE: " + err.Error())
+		}
+	}
+
+	backfillAge, err := time.ParseDuration(routeopt(route, "LOGSTASH_BACKFILL_MAX_AGE", "0s"))
+	if err != nil || backfillAge < 0 {
+		return nil, errors.New("invalid LOGSTASH_BACKFILL_MAX_AGE: " + routeopt(route, "LOGSTASH_BACKFILL_MAX_AGE", ""))
+	}
+
+	backfillBytes, err := strconv.Atoi(routeopt(route, "LOGSTASH_BACKFILL_MAX_BYTES", "1048576"))
+	if err != nil || backfillBytes < 1 {
+		return nil, errors.New("invalid LOGSTASH_BACKFILL_MAX_BYTES: " + routeopt(route, "LOGSTASH_BACKFILL_MAX_BYTES", ""))
+	}
+
+	var client dockerClient
+	if imageDigests || statsInterval > 0 || len(dockerEvents) > 0 || backfillAge > 0 {
+		if client, err = newDockerClient(); err != nil {
+			return nil, err
+		}
+	}
+
+	endpoints := splitList(routeopt(route, "LOGSTASH_ENDPOINTS", route.Address))
+	if len(endpoints) == 0 {
+		endpoints = []string{route.Address}
+	}
+
+	conn, err := transport.Dial(endpoints[0], route.Options)
 	if err != nil {
 		return nil, err
 	}
 
-	return &LogstashAdapter{
-		route:         route,
-		conn:          conn,
-		containerTags: make(map[string][]string),
-	}, nil
+	if newPacketBatchWriter(conn) != nil && compressor != nil {
+		conn.Close()
+		return nil, errors.New("compression is not supported on UDP routes")
+	}
+
+	a := &LogstashAdapter{
+		route:                   route,
+		transport:               transport,
+		compression:             compression,
+		compressionLevel:        compressionLevel,
+		batchSize:               batchSize,
+		flushInterval:           flushInterval,
+		perContainer:            perContainer,
+		tenantLabel:             tenantLabel,
+		tenantField:             tenantField,
+		tenantIsolation:         tenantIsolation,
+		queueSize:               queueSize,
+		idleTimeout:             idleTimeout,
+		endpoints:               endpoints,
+		poolSize:                poolSize,
+		poolOrdered:             poolOrdered,
+		stageBuffer:             stageBuffer,
+		serializeWorkers:        serializeWorkers,
+		marathonDisabled:        !marathonEnabled,
+		mesosDisabled:           !mesosEnabled,
+		jsonDisabled:            !decodeJSON,
+		marathonResourceStrings: marathonResourceStrings,
+		marathonLabelsStrict:    marathonLabelsStrict,
+		marathonLabelsAllow:     getset(routeopt(route, "LOGSTASH_MARATHON_LABELS_ALLOW", "")),
+		marathonLabelsDeny:      getset(routeopt(route, "LOGSTASH_MARATHON_LABELS_DENY", "")),
+		labels:                  labels,
+		labelsAllow:             labelsAllow,
+		labelsDeny:              labelsDeny,
+		envWhitelist:            splitList(routeopt(route, "LOGSTASH_ENV_WHITELIST", "")),
+		tagsLabel:               routeopt(route, "LOGSTASH_TAGS_LABEL", "logstash.tags"),
+		defaultTags:             defaultTags,
+		stderrTag:               routeopt(route, "LOGSTASH_STDERR_TAG", ""),
+		stderrType:              routeopt(route, "LOGSTASH_STDERR_TYPE", ""),
+		indexField:              indexField,
+		indexTemplate:           indexTemplate,
+		pipelineField:           pipelineField,
+		typeField:               typeField,
+		defaultType:             routeopt(route, "LOGSTASH_DEFAULT_TYPE", ""),
+		messageField:            routeopt(route, "LOGSTASH_MESSAGE_FIELD", "message"),
+		rawMessageField:         rawMessageField,
+		jsonKey:                 jsonKey,
+		logfmtKey:               logfmtKey,
+		nestedJSON:              nestedJSON,
+		sanitizeKeys:            sanitizeKeys,
+		syslogHeaders:           syslogHeaders,
+		stripANSI:               stripANSI,
+		utf8Escapes:             utf8Escapes,
+		maxEventSize:            maxEventSize,
+		chunkOversize:           chunkOversize,
+		levels:                  levels,
+		redact:                  redact,
+		redactMask:              routeopt(route, "LOGSTASH_REDACT_MASK", "[REDACTED]"),
+		script:                  transform,
+		rules:                   rules,
+		dropPattern:             dropPattern,
+		rateLimit:               rateLimit,
+		rateBurst:               rateBurst,
+		rateLimitDrop:           rateLimitDrop,
+		dedupWindow:             dedupWindow,
+		priorities:              priorities,
+		slowConsumerInterval:    slowConsumerInterval,
+		eventCap:                newThrottle(eventsPerSecond, eventsBurst),
+		byteCap:                 newThrottle(bytesPerSecond, bytesBurst),
+		include:                 include,
+		exclude:                 exclude,
+		multilineTimeout:        multilineTimeout,
+		multilineMaxLines:       multilineMaxLines,
+		partialLines:            partialLines,
+		flattenDepth:            flattenDepth,
+		flattenDelimiter:        routeopt(route, "LOGSTASH_FLATTEN_JSON_DELIMITER", "_"),
+		coerceJSON:              coerceJSON,
+		maxFields:               maxFields,
+		overflowField:           routeopt(route, "LOGSTASH_JSON_OVERFLOW_FIELD", ""),
+		renames:                 renames,
+		dropFields:              dropFields,
+		omitEmpty:               omitEmpty,
+		layout:                  layout,
+		namespace:               namespace,
+		wireFormat:              wireFormat,
+		timestamps:              timestamps || parseTimestamps,
+		parseTimestamps:         parseTimestamps,
+		eventIDs:                eventIDs,
+		sourceHost:              GetSourceHost(),
+		timestampField:          routeopt(route, "LOGSTASH_TIMESTAMP_FIELD", "@timestamp"),
+		gelfHost:                routeopt(route, "LOGSTASH_HOST_HOSTNAME", gelfHost),
+		gelfUDP:                 route.AdapterTransport("udp") == "udp",
+		stderrSeverity:          routeopt(route, "LOGSTASH_STDERR_SEVERITY", ""),
+		fields:                  routeFields(route),
+		containerState:          containerState,
+		command:                 command,
+		commandRedact:           commandRedact,
+	}
+
+	if imageDigests {
+		a.docker = client
+	}
+	if statsInterval > 0 {
+		a.stats = newStatsSampler(client, statsInterval)
+	}
+	if len(dockerEvents) > 0 {
+		a.events, a.dockerEvents = client, dockerEvents
+	}
+	if backfillAge > 0 {
+		a.history, a.backfillAge, a.backfillBytes = client, backfillAge, backfillBytes
+	}
+
+	if hostEnabled {
+		node := GetHostData()
+		a.node = &node
+	}
+
+	if shipperMode != "none" {
+		shipper := GetShipperInfo()
+		a.shipper, a.shipperMetadata = &shipper, shipperMode == "metadata"
+	}
+
+	if cloudProvider != "" {
+		// Missing cloud metadata should not keep logs from being shipped.
+		if a.cloud, err = GetCloudData(cloudProvider, cloudTimeout); err != nil {
+			log.Println("logstash: could not get cloud metadata:", err)
+		}
+	}
+
+	if dcosEnabled {
+		node := GetDCOSNodeData()
+		a.dcosNode = &node
+	}
+
+	if address := routeopt(route, "LOGSTASH_MIRROR_ADDRESS", ""); address != "" {
+		a.mirror = newMirror(transport, address, route.Options, mirrorQueueSize)
+	}
+
+	// Routes are tried in order: those of LOGSTASH_ROUTES, then stderr.
+	for _, r := range routes {
+		child, err := a.routeTo(r.address)
+		if err != nil {
+			a.closeRoutes()
+			a.closeMirror()
+			conn.Close()
+			return nil, err
+		}
+		a.routes = append(a.routes, messageRoute{match: a.containerRoute(r.terms), adapter: child})
+	}
+	if address := routeopt(route, "LOGSTASH_STDERR_ADDRESS", ""); address != "" {
+		stderr, err := a.routeTo(address)
+		if err != nil {
+			a.closeRoutes()
+			a.closeMirror()
+			conn.Close()
+			return nil, err
+		}
+		a.routes = append(a.routes, messageRoute{match: isStderr, adapter: stderr})
+	}
+
+	if len(endpoints) == 1 && poolSize == 1 {
+		a.conn = conn
+		a.packets = newPacketBatchWriter(conn)
+		a.compressor = compressor
+		return a, nil
+	}
+
+	// With several endpoints every container is pinned to one of them.
+	if len(endpoints) > 1 {
+		a.ring = newHashRing(endpoints)
+	}
+	if a.perContainer {
+		conn.Close()
+		return a, nil
+	}
+
+	// Open poolSize connections to each endpoint, reusing the one above.
+	for i, endpoint := range endpoints {
+		for j := 0; j < poolSize; j++ {
+			if i > 0 || j > 0 {
+				if conn, err = transport.Dial(endpoint, route.Options); err != nil {
+					for _, shard := range a.shards {
+						shard.conn.Close()
+					}
+					a.closeRoutes()
+					a.closeMirror()
+					return nil, err
+				}
+			}
+			a.shards = append(a.shards, a.withConn(conn))
+		}
+	}
+	return a, nil
 }
 
-// Get container tags configured with the environment variable LOGSTASH_TAGS
-func GetContainerTags(c *docker.Container, a *LogstashAdapter) []string {
-	if tags, ok := a.containerTags[c.ID]; ok {
-		return tags
+// GetContainerFormat returns the log format hint configured with the
+// environment variable LOGSTASH_FORMAT, or "auto" if there is none.
+func GetContainerFormat(c *docker.Container) string {
+	format := "auto"
+	for _, e := range c.Config.Env {
+		if strings.HasPrefix(e, "LOGSTASH_FORMAT=") {
+			format = strings.ToLower(strings.TrimPrefix(e, "LOGSTASH_FORMAT="))
+			break
+		}
+	}
+	return format
+}
+
+// containerFormat returns the log format of a container. Unless
+// LOGSTASH_FORMAT says otherwise, JSON messages are decoded as the
+// container's LOGSTASH_DECODE_JSON environment variable or logstash.decode_json
+// label, or else the adapter, asks.
+func (a *LogstashAdapter) containerFormat(c *docker.Container) string {
+	format := GetContainerFormat(c)
+	if format != "auto" {
+		return format
+	}
+	if !containerBool(c, "LOGSTASH_DECODE_JSON", "logstash.decode_json", !a.jsonDisabled) {
+		return "text"
+	}
+	return format
+}
+
+// containerBool returns the boolean value of a container setting, or dfault
+// if the container has none or an invalid one.
+func containerBool(c *docker.Container, env, label string, dfault bool) bool {
+	s := containerSetting(c, env, label)
+	if s == "" {
+		return dfault
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		log.Println("logstash: invalid "+env+" of container", c.ID+":", s)
+		return dfault
+	}
+	return b
+}
+
+// containerSetting returns the value of a container environment variable,
+// or else of a container label.
+func containerSetting(c *docker.Container, env, label string) string {
+	for _, e := range c.Config.Env {
+		if strings.HasPrefix(e, env+"=") {
+			return strings.TrimPrefix(e, env+"=")
+		}
 	}
+	return c.Config.Labels[label]
+}
 
+// Get container tags configured with the environment variable LOGSTASH_TAGS,
+// or else with the adapter's tags label
+func GetContainerTags(c *docker.Container, a *LogstashAdapter) []string {
 	var tags = []string{}
+	if label, ok := c.Config.Labels[a.tagsLabel]; ok && a.tagsLabel != "" {
+		tags = strings.Split(label, ",")
+	}
 	for _, e := range c.Config.Env {
 		if strings.HasPrefix(e, "LOGSTASH_TAGS=") {
 			tags = strings.Split(strings.TrimPrefix(e, "LOGSTASH_TAGS="), ",")
 			break
 		}
 	}
-
-	a.containerTags[c.ID] = tags
 	return tags
 }
 
+// containerTags returns the adapter's default tags followed by the tags of
+// the container, with their templates expanded and without duplicates.
+func (a *LogstashAdapter) containerTags(c *docker.Container) []string {
+	tags := expandTags(GetContainerTags(c, a), c)
+	if len(a.defaultTags) == 0 {
+		return tags
+	}
+
+	merged := make([]string, 0, len(a.defaultTags)+len(tags))
+	seen := make(map[string]bool, cap(merged))
+	for _, list := range [][]string{expandTags(a.defaultTags, c), tags} {
+		for _, tag := range list {
+			if !seen[tag] {
+				seen[tag] = true
+				merged = append(merged, tag)
+			}
+		}
+	}
+	return merged
+}
+
+// GetMarathonData returns the Marathon application information found in the
+// container environment.
 func GetMarathonData(c *docker.Container) MarathonData {
 
 	m := MarathonData{
-		Resource: make(map[string]string),
+		Resource: make(map[string]interface{}),
 		Label:    make(map[string]string),
 	}
 	/*
@@ -84,7 +981,10 @@ func GetMarathonData(c *docker.Container) MarathonData {
 
 	for _, e := range c.Config.Env {
 		if strings.HasPrefix(e, "MARATHON_APP_LABEL_") {
-			kv := strings.Split(strings.TrimPrefix(e, "MARATHON_APP_LABEL_"), "=")
+			kv := strings.SplitN(strings.TrimPrefix(e, "MARATHON_APP_LABEL_"), "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
 			// k, v := kv[0], kv[1]
 			m.Label[kv[0]] = kv[1]
 			// log.Println("logstash: Marathon info:", marathondata)
@@ -106,80 +1006,826 @@ func GetMarathonData(c *docker.Container) MarathonData {
 	return m
 }
 
+// GetMesosData returns the Mesos task information found in the container
+// environment.
+func GetMesosData(c *docker.Container) MesosData {
+	var m MesosData
+	for _, e := range c.Config.Env {
+		if strings.HasPrefix(e, "MESOS_TASK_ID=") {
+			m.Task = strings.TrimPrefix(e, "MESOS_TASK_ID=")
+		} else if strings.HasPrefix(e, "MESOS_SANDBOX=") {
+			m.Sandbox = strings.TrimPrefix(e, "MESOS_SANDBOX=")
+		} else if strings.HasPrefix(e, "MESOS_CONTAINER_NAME=") {
+			m.ContainerName = strings.TrimPrefix(e, "MESOS_CONTAINER_NAME=")
+		}
+	}
+	return m
+}
+
+// marathonData returns the Marathon data of a container, or nil if the
+// container was not started by Marathon or Marathon enrichment is disabled.
+// Resources are converted to numbers unless they are configured to be kept
+// as strings.
+func (a *LogstashAdapter) marathonData(c *docker.Container) *MarathonData {
+	if a.marathonDisabled {
+		return nil
+	}
+	m := GetMarathonData(c)
+	a.filterMarathonLabels(c, m.Label)
+	if m.Version == "" && m.ID == "" && m.Image == "" && len(m.Label) == 0 && len(m.Resource) == 0 {
+		return nil
+	}
+	if !a.marathonResourceStrings {
+		numericResources(m.Resource)
+	}
+	return &m
+}
+
+// mesosData returns the Mesos data of a container, or nil if the container
+// is not a Mesos task or Mesos enrichment is disabled.
+func (a *LogstashAdapter) mesosData(c *docker.Container) *MesosData {
+	if a.mesosDisabled {
+		return nil
+	}
+	m := GetMesosData(c)
+	if m == (MesosData{}) {
+		return nil
+	}
+	return &m
+}
+
+// numericResources converts the string values of resources that hold a
+// finite number to float64.
+func numericResources(resources map[string]interface{}) {
+	for k, v := range resources {
+		if s, ok := v.(string); ok {
+			if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+				resources[k] = f
+			}
+		}
+	}
+}
+
+// GetChronosData returns the Chronos job information found in the container
+// environment, or nil if the container was not started by Chronos.
+func GetChronosData(c *docker.Container) *ChronosData {
+	var m *ChronosData
+	for _, e := range c.Config.Env {
+		if !strings.HasPrefix(e, "CHRONOS_") {
+			continue
+		}
+		if m == nil {
+			m = &ChronosData{Resource: make(map[string]interface{})}
+		}
+		if strings.HasPrefix(e, "CHRONOS_JOB_NAME=") {
+			m.JobName = strings.TrimPrefix(e, "CHRONOS_JOB_NAME=")
+		} else if strings.HasPrefix(e, "CHRONOS_JOB_OWNER=") {
+			m.Owner = strings.TrimPrefix(e, "CHRONOS_JOB_OWNER=")
+		} else if strings.HasPrefix(e, "CHRONOS_RESOURCE_") {
+			kv := strings.SplitN(strings.TrimPrefix(e, "CHRONOS_RESOURCE_"), "=", 2)
+			if len(kv) == 2 {
+				m.Resource[strings.ToLower(kv[0])] = kv[1]
+			}
+		}
+	}
+	return m
+}
+
+// chronosData returns the Chronos data of a container, with numeric
+// resources.
+func (a *LogstashAdapter) chronosData(c *docker.Container) *ChronosData {
+	m := GetChronosData(c)
+	if m != nil {
+		numericResources(m.Resource)
+	}
+	return m
+}
+
+// filterMarathonLabels removes the labels that are not listed in
+// MARATHON_APP_LABELS, which Marathon sets to the names of the application's
+// own labels. Without that variable all labels are kept, unless strict mode
+// is on. The allow and deny lists are applied on top.
+func (a *LogstashAdapter) filterMarathonLabels(c *docker.Container, labels map[string]string) {
+	var listed map[string]bool
+	for _, e := range c.Config.Env {
+		if strings.HasPrefix(e, "MARATHON_APP_LABELS=") {
+			listed = make(map[string]bool)
+			for _, name := range strings.Fields(strings.TrimPrefix(e, "MARATHON_APP_LABELS=")) {
+				listed[name] = true
+			}
+			break
+		}
+	}
+
+	for name := range labels {
+		if (listed != nil || a.marathonLabelsStrict) && !listed[name] {
+			delete(labels, name)
+		} else if len(a.marathonLabelsAllow) > 0 && !a.marathonLabelsAllow[strings.ToUpper(name)] {
+			delete(labels, name)
+		} else if a.marathonLabelsDeny[strings.ToUpper(name)] {
+			delete(labels, name)
+		}
+	}
+}
+
+// dockerLabels returns the labels of a container that pass the label
+// filters, or nil if labels are not shipped.
+func (a *LogstashAdapter) dockerLabels(c *docker.Container) map[string]string {
+	if !a.labels {
+		return nil
+	}
+	labels := make(map[string]string)
+	for k, v := range c.Config.Labels {
+		if allowed(k, a.labelsAllow, a.labelsDeny) {
+			labels[k] = v
+		}
+	}
+	return labels
+}
+
+// containerEnvFields returns the values of the container environment
+// variables named in LOGSTASH_ENV_WHITELIST, either the adapter's or the
+// container's own.
+func (a *LogstashAdapter) containerEnvFields(c *docker.Container) map[string]string {
+	names := make(map[string]bool)
+	for _, name := range a.envWhitelist {
+		names[name] = true
+	}
+	for _, e := range c.Config.Env {
+		if strings.HasPrefix(e, "LOGSTASH_ENV_WHITELIST=") {
+			for _, name := range splitList(strings.TrimPrefix(e, "LOGSTASH_ENV_WHITELIST=")) {
+				names[name] = true
+			}
+		}
+	}
+
+	var env map[string]string
+	if len(names) > 0 {
+		for _, e := range c.Config.Env {
+			kv := strings.SplitN(e, "=", 2)
+			if len(kv) == 2 && names[kv[0]] {
+				if env == nil {
+					env = make(map[string]string)
+				}
+				env[kv[0]] = kv[1]
+			}
+		}
+	}
+	return env
+}
+
 // Stream implements the router.LogAdapter interface.
 func (a *LogstashAdapter) Stream(logstream chan *router.Message) {
+	if a.eventIDs && a.sequences == nil {
+		a.sequences = new(sequencer)
+	}
+	if a.events != nil {
+		logstream = a.withDockerEvents(logstream)
+	}
+	if len(a.routes) > 0 {
+		a.streamRoutes(logstream)
+		return
+	}
+	if a.tenantIsolation {
+		a.streamPerTenant(logstream)
+		return
+	}
+	if a.perContainer {
+		a.streamPerContainer(logstream)
+		return
+	}
+	if len(a.shards) > 0 {
+		a.streamSharded(logstream)
+		return
+	}
+
+	events := make(chan *event, a.stageBuffer)
+	docs := make(chan *document, a.stageBuffer)
 
-	for m := range logstream {
+	go a.enrichStage(logstream, events, func() int { return len(docs) })
+	go a.serializeStage(events, docs)
+	a.writeStage(docs)
+}
+
+// event is a message together with the container metadata it is shipped
+// with.
+type event struct {
+	message    *router.Message
+	docker     DockerInfo
+	tags       []string
+	marathon   *MarathonData
+	mesos      *MesosData
+	chronos    *ChronosData
+	dcos       *DCOSData
+	swarm      *SwarmData
+	compose    *ComposeData
+	rancher    *RancherData
+	nomad      *NomadData
+	ecs        *ECSData
+	cloud      *CloudData
+	node       *HostData
+	shipper    *ShipperInfo
+	sourceHost string
+	stats      *ResourceUsage
+	env        map[string]string
+	imageMeta  map[string]string
+	fields     map[string]string
+	format     string
+	severity   string
+	line       string
+	text       string
+	levels     bool
+	redact     []*redactor
+	blocks     map[string]interface{}
+	logType    string
+	metadata   map[string]string
+	parseTime  timestampParser
+	extract    []*regexp.Regexp
+	eventID    string
+	sequence   uint64
+	origLength int
+	chunk      *ChunkInfo
+	sampleRate int
+	repeats    int
+	state      ContainerState
+}
 
-		dockerInfo := DockerInfo{
-			Name:     m.Container.Name,
-			ID:       m.Container.ID,
-			Image:    m.Container.Config.Image,
-			Hostname: m.Container.Config.Hostname,
+// enrich looks up the metadata of the message's container. The returned
+// event comes from eventPool.
+func (a *LogstashAdapter) enrich(m *router.Message) *event {
+	meta := a.containerMeta(m.Container)
+	e := eventPool.Get().(*event)
+	*e = event{
+		message:    m,
+		line:       m.Data,
+		text:       m.Data,
+		docker:     meta.docker,
+		tags:       meta.tags,
+		marathon:   meta.marathon,
+		mesos:      meta.mesos,
+		chronos:    meta.chronos,
+		dcos:       meta.dcos,
+		swarm:      meta.swarm,
+		compose:    meta.compose,
+		rancher:    meta.rancher,
+		nomad:      meta.nomad,
+		ecs:        meta.ecs,
+		cloud:      a.cloud,
+		node:       a.node,
+		sourceHost: a.sourceHost,
+		env:        meta.env,
+		imageMeta:  meta.imageMeta,
+		fields:     meta.fields,
+		stats:      a.resourceUsage(m.Container),
+		format:     meta.format,
+		logType:    meta.logType,
+		metadata:   meta.metadata,
+		parseTime:  meta.parseTime,
+		extract:    meta.extract,
+		levels:     meta.levels,
+		redact:     meta.redact,
+		blocks:     meta.blocks,
+		sampleRate: meta.sample,
+	}
+	if m.Source == dockerEventSource || m.Source == slowConsumerSource {
+		// Event documents are always JSON, and tagged on a copy of the
+		// container's cached tags.
+		e.format = "json"
+		e.tags = append(e.tags[:len(e.tags):len(e.tags)], m.Source)
+	}
+	if m.Source == "stderr" {
+		if a.stderrTag != "" {
+			e.tags = append(e.tags[:len(e.tags):len(e.tags)], a.stderrTag)
+		}
+		e.severity = a.stderrSeverity
+		if a.stderrType != "" {
+			a.setStderrType(e)
 		}
+	}
+	if a.shipper != nil && !a.shipperMetadata {
+		e.shipper = a.shipper
+	}
+	if a.eventIDs {
+		e.eventID, e.sequence = newUUID(), a.sequences.next(m.Container.ID)
+	}
+	if a.containerState {
+		GetContainerState(m.Container, m.Time, &e.state)
+		e.docker.State = &e.state
+	}
+	return e
+}
 
-		tags := GetContainerTags(m.Container, a)
-		marathonData := GetMarathonData(m.Container)
+// serialize encodes an event as a newline terminated JSON document into d,
+// truncating its message if the document exceeds the maximum event size.
+func (a *LogstashAdapter) serialize(e *event, d *document) error {
+	if len(e.redact) > 0 {
+		e.line = redact(e.line, e.redact, a.redactMask)
+		e.text = e.line
+	}
+	err := a.encode(e, d)
+	if err != nil || a.maxEventSize <= 0 || d.Len() <= a.maxEventSize {
+		return err
+	}
+	if a.chunkOversize {
+		return a.chunk(e, d)
+	}
+	return a.truncate(e, d)
+}
 
-		var js []byte
-		var data map[string]interface{}
+// encode encodes an event as a newline terminated JSON document into d.
+func (a *LogstashAdapter) encode(e *event, d *document) error {
+	m := e.message
+	dockerInfo, tags, marathonData := e.docker, e.tags, e.marathon
 
-		// Parse JSON-encoded m.Data
-		if err := json.Unmarshal([]byte(m.Data), &data); err != nil {
-			// The message is not in JSON, make a new JSON message.
-			msg := LogstashMessage{
-				Message:  m.Data,
-				Docker:   dockerInfo,
-				Marathon: marathonData,
-				Stream:   m.Source,
-				Tags:     tags,
-			}
+	for k := range d.data {
+		delete(d.data, k)
+	}
 
-			if js, err = json.Marshal(msg); err != nil {
-				// Log error message and continue parsing next line, if marshalling fails
-				log.Println("logstash: could not marshal JSON:", err)
-				continue
+	invalidUTF8 := !utf8.ValidString(e.text)
+	if invalidUTF8 {
+		// Escapes would break JSON strings, which are always replaced.
+		e.text = sanitizeUTF8(e.text, a.utf8Escapes && !looksLikeJSON(e.text))
+	}
+	if a.stripANSI {
+		e.text = stripANSI(e.text)
+	}
+	var header map[string]interface{}
+	if a.syslogHeaders {
+		header, e.text = stripSyslogHeader(e.text)
+	}
+
+	// Parse JSON-encoded text, unless it obviously is not a JSON object
+	parsed := e.format != "text" && looksLikeJSON(e.text) && json.Unmarshal([]byte(e.text), &d.data) == nil && d.data != nil
+	if parsed {
+		decodeNested(d.data, a.nestedJSON)
+		if a.sanitizeKeys {
+			sanitizeKeys(d.data)
+		}
+		if a.flattenDepth > 0 {
+			flattenJSON(d.data, a.flattenDepth, a.flattenDelimiter)
+		}
+		if a.coerceJSON == "strings" {
+			stringValues(d.data)
+		}
+		if a.maxFields > 0 && limitFields(d.data, a.maxFields, a.overflowField) {
+			e.tags = append(e.tags[:len(e.tags):len(e.tags)], fieldLimitTag)
+			tags = e.tags
+		}
+	}
+	if parsed && a.jsonKey != "" {
+		nestJSON(d.data, a.jsonKey)
+	}
+	// Text in a structured format of its own is decoded under a key.
+	var decoded, extracted map[string]interface{}
+	var decodedKey string
+	if !parsed {
+		switch e.format {
+		case "logfmt":
+			decoded, _ = parseLogfmt(e.text)
+			decodedKey = a.logfmtKey
+			if decodedKey == "" {
+				decodedKey = "logfmt"
 			}
+		case "cef":
+			decoded, _ = parseCEF(e.text)
+			decodedKey = "cef"
+		case "leef":
+			decoded, _ = parseLEEF(e.text)
+			decodedKey = "leef"
+		}
+		if len(e.extract) > 0 {
+			extracted = extractFields(e.text, e.extract)
+		}
+	}
+	level := -1
+	if e.levels {
+		if parsed {
+			level = jsonLevel(d.data)
 		} else {
-			// The message is already in JSON, add the docker specific fields.
-			data["docker"] = dockerInfo
-			data["tags"] = tags
-			data["stream"] = m.Source
-			data["marathon"] = marathonData
-			// Return the JSON encoding
-			if js, err = json.Marshal(data); err != nil {
-				// Log error message and continue parsing next line, if marshalling fails
-				log.Println("logstash: could not marshal JSON:", err)
-				continue
-			}
+			level = textLevel(e.text)
+		}
+		if severity, ok := header["severity"].(int); ok && level < 0 {
+			level = severity
+		}
+	}
+
+	if !parsed && decoded == nil && extracted == nil && header == nil && len(e.blocks) == 0 && a.defaultLayout() {
+		// The message is not in JSON, make a new JSON message.
+		msg := LogstashMessage{
+			Timestamp:  a.timestamp(e, false),
+			Message:    e.text,
+			Docker:     dockerInfo,
+			Marathon:   marathonData,
+			Mesos:      e.mesos,
+			Chronos:    e.chronos,
+			DCOS:       e.dcos,
+			Swarm:      e.swarm,
+			Compose:    e.compose,
+			Rancher:    e.rancher,
+			Nomad:      e.nomad,
+			ECS:        e.ecs,
+			Cloud:      e.cloud,
+			Node:       e.node,
+			Shipper:    e.shipper,
+			SourceHost: e.sourceHost,
+			Stats:      e.stats,
+			Env:        e.env,
+			ImageMeta:  e.imageMeta,
+			Stream:     m.Source,
+			Severity:   e.severity,
+			Level:      levelName(level),
+			Code:       levelCode(level),
+			Type:       e.logType,
+			Metadata:   e.metadata,
+			EventID:    e.eventID,
+			Sequence:   e.sequence,
+			BadUTF8:    invalidUTF8,
+			OrigLength: e.origLength,
+			Chunk:      e.chunk,
+			Sampled:    e.sampleRate > 0,
+			SampleRate: e.sampleRate,
+			Repeats:    repeatCount(e.repeats),
+			Tags:       tags,
+			Fields:     e.fields,
 		}
 
 		// To work with tls and tcp transports via json_lines codec
-		js = append(js, byte('\n'))
+		d.scratch = append(msg.appendJSON(d.scratch[:0]), '\n')
+		_, err := d.Write(d.scratch)
+		return err
+	}
+
+	if !parsed {
+		// The message is not in JSON, but the layout is not the one of
+		// LogstashMessage: build the document as a map.
+		if d.data == nil {
+			d.data = make(map[string]interface{})
+		}
+		for k := range d.data {
+			delete(d.data, k)
+		}
+		d.data["message"] = e.text
+		if decoded != nil {
+			d.data[decodedKey] = decoded
+		}
+		for k, v := range extracted {
+			if _, ok := d.data[k]; !ok {
+				d.data[k] = v
+			}
+		}
+	}
+	if a.messageField != "" && a.messageField != "message" {
+		if _, ok := d.data[a.messageField]; !ok {
+			if msg, ok := d.data["message"]; ok {
+				d.data[a.messageField] = msg
+				delete(d.data, "message")
+			}
+		}
+	}
+
+	if parsed && a.rawMessageField != "" {
+		if _, ok := d.data[a.rawMessageField]; !ok {
+			d.data[a.rawMessageField] = e.line
+		}
+	}
+	if header != nil {
+		if _, ok := d.data["syslog"]; !ok {
+			d.data["syslog"] = header
+		}
+	}
+	if level >= 0 {
+		d.data["level"] = levelNames[level]
+		d.data["severity_code"] = level
+	}
+
+	// Add the docker specific fields, under the namespace if there is one.
+	added := d.data
+	if a.namespace != "" {
+		if d.namespace == nil {
+			d.namespace = make(map[string]interface{})
+		}
+		for k := range d.namespace {
+			delete(d.namespace, k)
+		}
+		added = d.namespace
+		d.data[a.namespace] = added
+	}
+	if a.layout == "upstream" {
+		added["docker"] = newUpstreamDockerInfo(&e.docker)
+		added["tags"] = tags
+		added["stream"] = m.Source
+	} else {
+		a.addBlocks(e, added)
+	}
+	for name, block := range e.blocks {
+		if builtinEnrichers[name] {
+			// The enricher of the block was replaced or disabled.
+			if block == nil {
+				delete(added, name)
+			} else {
+				added[name] = copyValue(block)
+			}
+		} else if _, ok := added[name]; !ok {
+			// The block is shared by all events of the container, and the
+			// layout may change the document in place.
+			added[name] = copyValue(block)
+		}
+	}
+	if e.sequence != 0 {
+		added["event_id"] = e.eventID
+		added["sequence"] = e.sequence
+	}
+	if invalidUTF8 {
+		added["invalid_utf8"] = true
+	}
+	if e.origLength > 0 {
+		added["original_length"] = e.origLength
+	}
+	if e.chunk != nil {
+		added["chunk"] = e.chunk
+	}
+	if e.sampleRate > 0 {
+		added["sampled"] = true
+		added["sample_rate"] = e.sampleRate
+	}
+	if e.repeats > 1 {
+		added["repeat_count"] = e.repeats
+	}
+	if a.timestamps && a.wireFormat != "gelf" {
+		field := a.timestampField
+		if field == "" {
+			field = "@timestamp"
+		}
+		if _, ok := d.data[field]; !ok {
+			d.data[field] = a.timestamp(e, parsed)
+		}
+	}
+	if _, ok := d.data["type"]; !ok && e.logType != "" {
+		d.data["type"] = e.logType
+	}
+	if _, ok := d.data["@metadata"]; !ok && len(e.metadata) > 0 {
+		d.data["@metadata"] = e.metadata
+	}
+	for k, v := range e.fields {
+		// Keys of the message itself win over static fields.
+		if _, ok := d.data[k]; !ok {
+			d.data[k] = v
+		}
+	}
+	if a.layout == "flat" {
+		flattenDocker(added)
+	}
+	renameFields(d.data, a.renames)
+	dropFields(d.data, a.dropFields)
+	applyRules(d.data, a.rules)
+	if a.omitEmpty {
+		omitEmpty(d.data)
+	}
+	if a.script != nil && !a.script.run(d) {
+		// Discarded events leave d empty.
+		return nil
+	}
+	if a.wireFormat == "gelf" {
+		return a.encodeGELF(e, d)
+	}
+	return d.enc.Encode(d.data)
+}
 
-		if _, err := a.conn.Write(js); err != nil {
-			// There is no retry option implemented yet
-			log.Fatal("logstash: could not write:", err)
+// timestamp returns the time a message was logged at, if timestamps are
+// added to events. That is the time found at the start of text messages if
+// they are parsed for one, or else the time Docker read the message at.
+func (a *LogstashAdapter) timestamp(e *event, parsed bool) string {
+	if !a.timestamps {
+		return ""
+	}
+	t := e.message.Time
+	if e.parseTime != nil && !parsed {
+		if logged, ok := e.parseTime(e.text, t); ok {
+			t = logged
 		}
 	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// encodeGELF encodes the document built in d as a GELF message. Messages
+// sent over stream transports are terminated by a null byte, as GELF
+// requires, and those sent over UDP are chunked if they are too large for a
+// datagram.
+func (a *LogstashAdapter) encodeGELF(e *event, d *document) error {
+	if d.gelf == nil {
+		d.gelf = make(map[string]interface{})
+	}
+	for k := range d.gelf {
+		delete(d.gelf, k)
+	}
+	toGELF(d.data, e, a.gelfHost, d.gelf)
+	start := d.Len()
+	if err := d.enc.Encode(d.gelf); err != nil {
+		return err
+	}
+	if a.gelfUDP {
+		return chunkGELF(d, start)
+	}
+	b := d.Bytes()
+	b[len(b)-1] = 0
+	return nil
+}
+
+// addBlocks adds the metadata blocks of an event to a document.
+func (a *LogstashAdapter) addBlocks(e *event, added map[string]interface{}) {
+	added["docker"] = e.docker
+	added["tags"] = e.tags
+	added["stream"] = e.message.Source
+	if e.marathon != nil {
+		added["marathon"] = e.marathon
+	}
+	if e.mesos != nil {
+		added["mesos"] = e.mesos
+	}
+	if e.chronos != nil {
+		added["chronos"] = e.chronos
+	}
+	if e.dcos != nil {
+		added["dcos"] = e.dcos
+	}
+	if e.swarm != nil {
+		added["swarm"] = e.swarm
+	}
+	if e.compose != nil {
+		added["compose"] = e.compose
+	}
+	if e.rancher != nil {
+		added["rancher"] = e.rancher
+	}
+	if e.nomad != nil {
+		added["nomad"] = e.nomad
+	}
+	if e.ecs != nil {
+		added["ecs"] = e.ecs
+	}
+	if e.cloud != nil {
+		added["cloud"] = e.cloud
+	}
+	if e.node != nil {
+		added["node"] = e.node
+	}
+	if e.shipper != nil {
+		added["shipper"] = e.shipper
+	}
+	if e.sourceHost != "" {
+		added["source_host"] = e.sourceHost
+	}
+	if e.stats != nil {
+		added["stats"] = e.stats
+	}
+	if len(e.env) > 0 {
+		added["env"] = e.env
+	}
+	if len(e.imageMeta) > 0 {
+		added["image_meta"] = e.imageMeta
+	}
+	if _, ok := added["severity"]; !ok && e.severity != "" {
+		added["severity"] = e.severity
+	}
+}
+
+// defaultLayout reports whether documents have the layout of
+// LogstashMessage, which plain text messages are encoded with directly.
+func (a *LogstashAdapter) defaultLayout() bool {
+	return a.wireFormat != "gelf" && (a.timestampField == "" || a.timestampField == "@timestamp") && (a.messageField == "" || a.messageField == "message") && len(a.renames) == 0 && len(a.dropFields) == 0 && !a.omitEmpty && (a.layout == "" || a.layout == "nested") && a.namespace == "" && len(a.rules) == 0 && a.script == nil
+}
+
+// looksLikeJSON reports whether s could be a JSON object, which is far
+// cheaper than a failing json.Unmarshal on plain text lines.
+func looksLikeJSON(s string) bool {
+	s = strings.TrimSpace(s)
+	return len(s) >= 2 && s[0] == '{' && s[len(s)-1] == '}'
+}
+
+// writeErrorSource counts the events discarded after a write error, in
+// droppedEvents.
+const writeErrorSource = "write_error"
+
+// writeFailed reports whether a write of the adapter failed before.
+func (a *LogstashAdapter) writeFailed() bool {
+	select {
+	case <-a.failed:
+		return true
+	default:
+		return false
+	}
+}
+
+// flush writes out all batched messages. A write error is fatal, unless the
+// adapter has a failed channel, which is then closed and the batches of
+// which are discarded from then on.
+func (a *LogstashAdapter) flush() {
+	if len(a.batch) == 0 {
+		return
+	}
+	if a.writeFailed() {
+		droppedEvents.Add(writeErrorSource, int64(len(a.batch)))
+		a.batch = a.batch[:0]
+		a.arena = a.arena[:0]
+		return
+	}
+
+	start := time.Now()
+	var err error
+	if a.packets != nil {
+		a.throttle(len(a.batch), len(a.arena))
+		err = writePacketBatch(a.packets, a.batch)
+	} else if a.compressor != nil {
+		var frame []byte
+		if frame, err = a.compressor.compress(a.batch); err == nil {
+			a.throttle(len(a.batch), len(frame))
+			_, err = a.conn.Write(frame)
+		}
+	} else {
+		a.throttle(len(a.batch), len(a.arena))
+		for _, js := range a.batch {
+			if _, err = a.conn.Write(js); err != nil {
+				break
+			}
+		}
+	}
+	countStage("write", len(a.batch), start, err)
+	if err != nil && a.failed != nil {
+		log.Println("logstash: could not write:", err)
+		close(a.failed)
+		droppedEvents.Add(writeErrorSource, int64(len(a.batch)))
+		a.batch = a.batch[:0]
+		a.arena = a.arena[:0]
+		return
+	}
+	if err != nil {
+		// There is no retry option implemented yet
+		log.Fatal("logstash: could not write:", err)
+	}
+	if a.mirror != nil {
+		a.mirror.send(a.batch)
+	}
+
+	a.batch = a.batch[:0]
+	a.arena = a.arena[:0]
 }
 
 type DockerInfo struct {
-	Name     string `json:"name"`
-	ID       string `json:"id"`
-	Image    string `json:"image"`
-	Hostname string `json:"hostname"`
+	Name        string            `json:"name"`
+	ID          string            `json:"id"`
+	Image       string            `json:"image"`
+	Hostname    string            `json:"hostname"`
+	ImageID     string            `json:"image_id,omitempty"`
+	ImageDigest string            `json:"image_digest,omitempty"`
+	Created     string            `json:"created,omitempty"`
+	Entrypoint  string            `json:"entrypoint,omitempty"`
+	Command     string            `json:"command,omitempty"`
+	Networks    []string          `json:"networks,omitempty"`
+	IPAddresses []string          `json:"ip_addresses,omitempty"`
+	Ports       []PortMapping     `json:"ports,omitempty"`
+	State       *ContainerState   `json:"state,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
 }
 
 // LogstashMessage is a simple JSON input to Logstash.
 type LogstashMessage struct {
-	Message string     `json:"message"`
-	Stream  string     `json:"stream"`
-	Docker  DockerInfo `json:"docker"`
+	Timestamp string     `json:"@timestamp,omitempty"`
+	Message   string     `json:"message"`
+	Stream    string     `json:"stream"`
+	Docker    DockerInfo `json:"docker"`
 	// Marathon map[string]string `json:"marathon"`
-	Marathon MarathonData `json:"marathon,omitempty"`
-	// Mesos    MesosData    `json:"mesos,omitempty"`
-	Tags []string `json:"tags"`
+	Marathon   *MarathonData     `json:"marathon,omitempty"`
+	Mesos      *MesosData        `json:"mesos,omitempty"`
+	Chronos    *ChronosData      `json:"chronos,omitempty"`
+	DCOS       *DCOSData         `json:"dcos,omitempty"`
+	Swarm      *SwarmData        `json:"swarm,omitempty"`
+	Compose    *ComposeData      `json:"compose,omitempty"`
+	Rancher    *RancherData      `json:"rancher,omitempty"`
+	Nomad      *NomadData        `json:"nomad,omitempty"`
+	ECS        *ECSData          `json:"ecs,omitempty"`
+	Cloud      *CloudData        `json:"cloud,omitempty"`
+	Node       *HostData         `json:"node,omitempty"`
+	Shipper    *ShipperInfo      `json:"shipper,omitempty"`
+	SourceHost string            `json:"source_host,omitempty"`
+	Stats      *ResourceUsage    `json:"stats,omitempty"`
+	Env        map[string]string `json:"env,omitempty"`
+	ImageMeta  map[string]string `json:"image_meta,omitempty"`
+	Severity   string            `json:"severity,omitempty"`
+	Level      string            `json:"level,omitempty"`
+	Code       *int              `json:"severity_code,omitempty"`
+	Type       string            `json:"type,omitempty"`
+	Metadata   map[string]string `json:"@metadata,omitempty"`
+	EventID    string            `json:"event_id,omitempty"`
+	Sequence   uint64            `json:"sequence,omitempty"`
+	BadUTF8    bool              `json:"invalid_utf8,omitempty"`
+	OrigLength int               `json:"original_length,omitempty"`
+	Chunk      *ChunkInfo        `json:"chunk,omitempty"`
+	Sampled    bool              `json:"sampled,omitempty"`
+	SampleRate int               `json:"sample_rate,omitempty"`
+	Repeats    int               `json:"repeat_count,omitempty"`
+	Tags       []string          `json:"tags"`
+	// Fields are static top-level fields, encoded after all others.
+	Fields map[string]string `json:"-"`
 }
 
 /*
@@ -200,15 +1846,21 @@ type LogstashMessage struct {
 */
 
 type MarathonData struct {
-	Version  string            `json:"version,omitempty"`
-	Resource map[string]string `json:"resource,omitempty"`
-	ID       string            `json:"id,omitempty"`
-	Label    map[string]string `json:"label,omitempty"`
-	Image    string            `json:"image,omitempty"`
-}
-
-// type MesosData struct {
-// 	Sandbox       string
-// 	ContainerName string
-// 	Task          string
-// }
+	Version  string                 `json:"version,omitempty"`
+	Resource map[string]interface{} `json:"resource,omitempty"`
+	ID       string                 `json:"id,omitempty"`
+	Label    map[string]string      `json:"label,omitempty"`
+	Image    string                 `json:"image,omitempty"`
+}
+
+type MesosData struct {
+	Sandbox       string `json:"sandbox,omitempty"`
+	ContainerName string `json:"container_name,omitempty"`
+	Task          string `json:"task_id,omitempty"`
+}
+
+type ChronosData struct {
+	JobName  string                 `json:"job_name,omitempty"`
+	Owner    string                 `json:"owner,omitempty"`
+	Resource map[string]interface{} `json:"resource,omitempty"`
+}
@@ -1,8 +1,12 @@
 package logstash
 
 import (
+	"bytes"
 	"encoding/json"
 	"net"
+	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -49,15 +53,74 @@ func (m MockConn) SetWriteDeadline(t time.Time) error {
 	return nil
 }
 
+// BufferConn records everything written to it.
+type BufferConn struct {
+	MockConn
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (c *BufferConn) Write(b []byte) (n int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.Write(b)
+}
+
+// Lines returns the decoded JSON documents written so far.
+func (c *BufferConn) Lines() []map[string]interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(c.buf.String()), "\n") {
+		var data map[string]interface{}
+		if json.Unmarshal([]byte(line), &data) == nil {
+			lines = append(lines, data)
+		}
+	}
+	return lines
+}
+
+// MockTransport hands out a new BufferConn per dial.
+type MockTransport struct {
+	mu    sync.Mutex
+	conns []*BufferConn
+}
+
+func (t *MockTransport) Dial(addr string, options map[string]string) (net.Conn, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	conn := &BufferConn{}
+	t.conns = append(t.conns, conn)
+	return conn, nil
+}
+
+func TestRouteOpt(t *testing.T) {
+	assert := assert.New(t)
+
+	os.Setenv("LOGSTASH_LAYOUT", "flat")
+	defer os.Unsetenv("LOGSTASH_LAYOUT")
+	os.Setenv("LOGSTASH_DEFAULT_TAGS", "env")
+	defer os.Unsetenv("LOGSTASH_DEFAULT_TAGS")
+
+	route := &router.Route{Options: map[string]string{"layout": "upstream", "namespace": "", "tags": "prod,edge"}}
+	assert.Equal("upstream", routeopt(route, "LOGSTASH_LAYOUT", "nested"))
+	assert.Equal("", routeopt(route, "LOGSTASH_NAMESPACE", "logspout"))
+	assert.Equal("prod,edge", routeopt(route, "LOGSTASH_DEFAULT_TAGS", ""))
+	assert.Equal("1", routeopt(route, "LOGSTASH_BATCH_SIZE", "1"))
+
+	route = new(router.Route)
+	assert.Equal("flat", routeopt(route, "LOGSTASH_LAYOUT", "nested"))
+	assert.Equal("env", routeopt(route, "LOGSTASH_DEFAULT_TAGS", ""))
+}
+
 func TestStreamNotJsonWithoutLogstashTags(t *testing.T) {
 	assert := assert.New(t)
 
 	conn := MockConn{}
 
 	adapter := LogstashAdapter{
-		route:         new(router.Route),
-		conn:          conn,
-		containerTags: make(map[string][]string),
+		route: new(router.Route),
+		conn:  conn,
 	}
 
 	assert.NotNil(adapter)
@@ -96,6 +159,8 @@ func TestStreamNotJsonWithoutLogstashTags(t *testing.T) {
 
 	assert.Equal("foo bananas", data["message"])
 	assert.Equal([]interface{}{}, data["tags"])
+	assert.NotContains(data, "marathon")
+	assert.NotContains(data, "mesos")
 
 	var dockerInfo map[string]interface{}
 	dockerInfo = data["docker"].(map[string]interface{})
@@ -111,9 +176,8 @@ func TestStreamNotJsonWithLogstashTags(t *testing.T) {
 	conn := MockConn{}
 
 	adapter := LogstashAdapter{
-		route:         new(router.Route),
-		conn:          conn,
-		containerTags: make(map[string][]string),
+		route: new(router.Route),
+		conn:  conn,
 	}
 
 	assert.NotNil(adapter)
@@ -167,9 +231,8 @@ func TestStreamJsonWithoutLogstashTags(t *testing.T) {
 	conn := MockConn{}
 
 	adapter := LogstashAdapter{
-		route:         new(router.Route),
-		conn:          conn,
-		containerTags: make(map[string][]string),
+		route: new(router.Route),
+		conn:  conn,
 	}
 
 	assert.NotNil(adapter)
@@ -229,9 +292,8 @@ func TestStreamJsonWithLogstashTags(t *testing.T) {
 	conn := MockConn{}
 
 	adapter := LogstashAdapter{
-		route:         new(router.Route),
-		conn:          conn,
-		containerTags: make(map[string][]string),
+		route: new(router.Route),
+		conn:  conn,
 	}
 
 	assert.NotNil(adapter)
@@ -284,3 +346,568 @@ func TestStreamJsonWithLogstashTags(t *testing.T) {
 	assert.Equal("image", dockerInfo["image"])
 	assert.Equal("hostname", dockerInfo["hostname"])
 }
+
+func TestLooksLikeJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(looksLikeJSON(`{"a":1}`))
+	assert.True(looksLikeJSON("  {\"a\":1}\r\n"))
+	assert.False(looksLikeJSON(`foo bananas`))
+	assert.False(looksLikeJSON(`[1,2]`))
+	assert.False(looksLikeJSON(`{ truncated`))
+	assert.False(looksLikeJSON(`{`))
+}
+
+func TestStreamJsonWithTextFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route: new(router.Route),
+		conn:  conn,
+	}
+
+	containerConfig := docker.Config{}
+	containerConfig.Env = []string{"LOGSTASH_FORMAT=text"}
+
+	container := docker.Container{}
+	container.ID = "ID"
+	container.Config = &containerConfig
+
+	str := `{ "status": "200" }`
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: str, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	assert.Len(lines, 1)
+	assert.Equal(str, lines[0]["message"])
+	assert.Nil(lines[0]["status"])
+}
+
+func TestContainerFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	plain := docker.Container{ID: "plain", Config: &docker.Config{}}
+	optIn := docker.Container{ID: "in", Config: &docker.Config{Labels: map[string]string{"logstash.decode_json": "true"}}}
+	optOut := docker.Container{ID: "out", Config: &docker.Config{Env: []string{"LOGSTASH_DECODE_JSON=false"}}}
+	text := docker.Container{ID: "text", Config: &docker.Config{Env: []string{"LOGSTASH_FORMAT=text", "LOGSTASH_DECODE_JSON=true"}}}
+
+	adapter := LogstashAdapter{}
+	assert.Equal("auto", adapter.containerFormat(&plain))
+	assert.Equal("auto", adapter.containerFormat(&optIn))
+	assert.Equal("text", adapter.containerFormat(&optOut))
+	assert.Equal("text", adapter.containerFormat(&text))
+
+	adapter = LogstashAdapter{jsonDisabled: true}
+	assert.Equal("text", adapter.containerFormat(&plain))
+	assert.Equal("auto", adapter.containerFormat(&optIn))
+	assert.Equal("text", adapter.containerFormat(&optOut))
+}
+
+func TestStreamWithMesosData(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route: new(router.Route),
+		conn:  conn,
+	}
+
+	containerConfig := docker.Config{}
+	containerConfig.Env = []string{
+		"MESOS_TASK_ID=flapjack-notifier.c101b8cd-a1ca-11e6-a07b-024232c1c875",
+		"MESOS_SANDBOX=/mnt/mesos/sandbox",
+		"MESOS_CONTAINER_NAME=mesos-04fb9b4e-ccdd-4884-b2b6-11c88c04760c-S14.9ef25b40",
+	}
+
+	container := docker.Container{}
+	container.ID = "ID"
+	container.Config = &containerConfig
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: `foo bananas`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `{ "status": "200" }`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	assert.Len(lines, 2)
+	for _, data := range lines {
+		mesos := data["mesos"].(map[string]interface{})
+		assert.Equal("flapjack-notifier.c101b8cd-a1ca-11e6-a07b-024232c1c875", mesos["task_id"])
+		assert.Equal("/mnt/mesos/sandbox", mesos["sandbox"])
+		assert.Equal("mesos-04fb9b4e-ccdd-4884-b2b6-11c88c04760c-S14.9ef25b40", mesos["container_name"])
+	}
+}
+
+func TestMesosDataDisabled(t *testing.T) {
+	assert := assert.New(t)
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Env: []string{"MESOS_TASK_ID=task"}}}
+
+	adapter := LogstashAdapter{}
+	assert.Equal("task", adapter.mesosData(&container).Task)
+	assert.Nil(adapter.mesosData(&docker.Container{ID: "ID", Config: &docker.Config{}}))
+
+	adapter = LogstashAdapter{mesosDisabled: true}
+	assert.Nil(adapter.mesosData(&container))
+}
+
+func TestGetMarathonData(t *testing.T) {
+	assert := assert.New(t)
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Env: []string{
+		"MARATHON_APP_ID=/flapjack-notifier",
+		"MARATHON_APP_LABEL_ENVIRONMENT=prod",
+		"MARATHON_APP_LABEL_URL=http://example.com/?a=b",
+		"MARATHON_APP_RESOURCE_CPUS=0.01",
+	}}}
+
+	m := GetMarathonData(&container)
+	assert.Equal("/flapjack-notifier", m.ID)
+	assert.Equal("prod", m.Label["ENVIRONMENT"])
+	assert.Equal("http://example.com/?a=b", m.Label["URL"])
+	assert.Equal("0.01", m.Resource["cpus"])
+}
+
+func TestMarathonDataDisabled(t *testing.T) {
+	assert := assert.New(t)
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Env: []string{"MARATHON_APP_ID=/app"}}}
+
+	adapter := LogstashAdapter{}
+	assert.Equal("/app", adapter.marathonData(&container).ID)
+
+	assert.Nil(adapter.marathonData(&docker.Container{ID: "ID", Config: &docker.Config{}}))
+
+	adapter = LogstashAdapter{marathonDisabled: true}
+	assert.Nil(adapter.marathonData(&container))
+}
+
+func TestMarathonResourcesAsNumbers(t *testing.T) {
+	assert := assert.New(t)
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Env: []string{
+		"MARATHON_APP_RESOURCE_CPUS=0.01",
+		"MARATHON_APP_RESOURCE_MEM=128.0",
+		"MARATHON_APP_RESOURCE_DISK=lots",
+	}}}
+
+	adapter := LogstashAdapter{}
+	m := adapter.marathonData(&container)
+	assert.Equal(0.01, m.Resource["cpus"])
+	assert.Equal(128.0, m.Resource["mem"])
+	assert.Equal("lots", m.Resource["disk"])
+
+	adapter = LogstashAdapter{marathonResourceStrings: true}
+	m = adapter.marathonData(&container)
+	assert.Equal("0.01", m.Resource["cpus"])
+	assert.Equal("128.0", m.Resource["mem"])
+}
+
+func TestMarathonLabelsFiltering(t *testing.T) {
+	assert := assert.New(t)
+
+	listed := docker.Container{ID: "listed", Config: &docker.Config{Env: []string{
+		"MARATHON_APP_LABELS=VERSION ENVIRONMENT",
+		"MARATHON_APP_LABEL_VERSION=1.6",
+		"MARATHON_APP_LABEL_ENVIRONMENT=prod",
+		"MARATHON_APP_LABEL_INTERNAL=secret",
+	}}}
+	unlisted := docker.Container{ID: "unlisted", Config: &docker.Config{Env: []string{
+		"MARATHON_APP_LABEL_VERSION=1.6",
+	}}}
+
+	adapter := LogstashAdapter{}
+	assert.Equal(map[string]string{"VERSION": "1.6", "ENVIRONMENT": "prod"}, adapter.marathonData(&listed).Label)
+	assert.Equal(map[string]string{"VERSION": "1.6"}, adapter.marathonData(&unlisted).Label)
+
+	// Without its labels nothing is left of the Marathon data.
+	adapter = LogstashAdapter{marathonLabelsStrict: true}
+	assert.Nil(adapter.marathonData(&unlisted))
+
+	adapter = LogstashAdapter{
+		marathonLabelsAllow: map[string]bool{"VERSION": true, "ENVIRONMENT": true},
+		marathonLabelsDeny:  map[string]bool{"ENVIRONMENT": true},
+	}
+	assert.Equal(map[string]string{"VERSION": "1.6"}, adapter.marathonData(&listed).Label)
+}
+
+func TestStreamWithChronosData(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route: new(router.Route),
+		conn:  conn,
+	}
+
+	chronos := docker.Container{ID: "chronos", Config: &docker.Config{Env: []string{
+		"CHRONOS_JOB_NAME=nightly-report",
+		"CHRONOS_JOB_OWNER=ops@example.com",
+		"CHRONOS_RESOURCE_CPU=0.5",
+		"CHRONOS_RESOURCE_MEM=512.0",
+	}}}
+	other := docker.Container{ID: "other", Config: &docker.Config{}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &chronos, Data: `foo bananas`, Time: time.Now()}
+		logstream <- &router.Message{Container: &chronos, Data: `{ "status": "200" }`, Time: time.Now()}
+		logstream <- &router.Message{Container: &other, Data: `foo bananas`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	assert.Len(lines, 3)
+	for _, data := range lines[:2] {
+		chronos := data["chronos"].(map[string]interface{})
+		assert.Equal("nightly-report", chronos["job_name"])
+		assert.Equal("ops@example.com", chronos["owner"])
+		assert.Equal(map[string]interface{}{"cpu": 0.5, "mem": 512.0}, chronos["resource"])
+	}
+	assert.Nil(lines[2]["chronos"])
+}
+
+func TestDockerLabelsFiltering(t *testing.T) {
+	assert := assert.New(t)
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Labels: map[string]string{
+		"com.docker.compose.service": "web",
+		"com.example.team":           "payments",
+		"com.example.secret":         "hunter2",
+		"maintainer":                 "ops",
+	}}}
+
+	adapter := LogstashAdapter{}
+	assert.Nil(adapter.dockerLabels(&container))
+
+	allow, _ := newNameFilter(`/^com\./`)
+	deny, _ := newNameFilter(`com.example.secret`)
+	adapter = LogstashAdapter{
+		labels:      true,
+		labelsAllow: allow,
+		labelsDeny:  deny,
+	}
+	assert.Equal(map[string]string{
+		"com.docker.compose.service": "web",
+		"com.example.team":           "payments",
+	}, adapter.dockerLabels(&container))
+}
+
+func TestStreamWithEnvWhitelist(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:        new(router.Route),
+		conn:         conn,
+		envWhitelist: []string{"DEPLOY_ENV"},
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Env: []string{
+		"LOGSTASH_ENV_WHITELIST=SERVICE_NAME,GIT_SHA",
+		"SERVICE_NAME=web",
+		"GIT_SHA=abc123",
+		"DEPLOY_ENV=prod",
+		"SECRET_TOKEN=hunter2",
+	}}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: `foo bananas`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `{ "status": "200" }`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	assert.Len(lines, 2)
+	for _, data := range lines {
+		assert.Equal(map[string]interface{}{
+			"SERVICE_NAME": "web",
+			"GIT_SHA":      "abc123",
+			"DEPLOY_ENV":   "prod",
+		}, data["env"])
+	}
+}
+
+func TestGetContainerTagsFromLabel(t *testing.T) {
+	assert := assert.New(t)
+
+	adapter := LogstashAdapter{tagsLabel: "logstash.tags"}
+	labelled := docker.Container{ID: "ID", Config: &docker.Config{Labels: map[string]string{"logstash.tags": "web,prod"}}}
+	assert.Equal([]string{"web", "prod"}, GetContainerTags(&labelled, &adapter))
+
+	both := docker.Container{ID: "ID", Config: &docker.Config{
+		Labels: map[string]string{"logstash.tags": "web,prod"},
+		Env:    []string{"LOGSTASH_TAGS=api"},
+	}}
+	assert.Equal([]string{"api"}, GetContainerTags(&both, &adapter))
+
+	adapter.tagsLabel = "com.example.tags"
+	assert.Equal([]string{}, GetContainerTags(&labelled, &adapter))
+}
+
+func TestDefaultTags(t *testing.T) {
+	assert := assert.New(t)
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Env: []string{"LOGSTASH_TAGS=web,prod"}}}
+	adapter := LogstashAdapter{}
+	assert.Equal([]string{"web", "prod"}, adapter.containerTags(&container))
+
+	adapter.defaultTags = []string{"prod", "edge"}
+	assert.Equal([]string{"prod", "edge", "web"}, adapter.containerTags(&container))
+
+	untagged := docker.Container{ID: "other", Config: &docker.Config{}}
+	assert.Equal([]string{"prod", "edge"}, adapter.containerTags(&untagged))
+}
+
+func TestStreamStderr(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:          new(router.Route),
+		conn:           conn,
+		stderrTag:      "stderr",
+		stderrSeverity: "error",
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Env: []string{"LOGSTASH_TAGS=web"}}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Source: "stdout", Data: `listening`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Source: "stderr", Data: `panic`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Source: "stderr", Data: `{"severity":"warning"}`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 3) {
+		assert.Equal([]interface{}{"web"}, lines[0]["tags"])
+		assert.Nil(lines[0]["severity"])
+		assert.Equal([]interface{}{"web", "stderr"}, lines[1]["tags"])
+		assert.Equal("error", lines[1]["severity"])
+		assert.Equal([]interface{}{"web", "stderr"}, lines[2]["tags"])
+		assert.Equal("warning", lines[2]["severity"])
+	}
+}
+
+func TestStreamWithType(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:       new(router.Route),
+		conn:        conn,
+		defaultType: "plain",
+	}
+
+	env := docker.Container{ID: "env", Config: &docker.Config{
+		Env:    []string{"LOGSTASH_TYPE=nginx-access"},
+		Labels: map[string]string{"logstash.type": "ignored"},
+	}}
+	label := docker.Container{ID: "label", Config: &docker.Config{Labels: map[string]string{"logstash.type": "java"}}}
+	untyped := docker.Container{ID: "untyped", Config: &docker.Config{}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &env, Data: `GET / 200`, Time: time.Now()}
+		logstream <- &router.Message{Container: &label, Data: `{"message":"started"}`, Time: time.Now()}
+		logstream <- &router.Message{Container: &label, Data: `{"type":"audit"}`, Time: time.Now()}
+		logstream <- &router.Message{Container: &untyped, Data: `started`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 4) {
+		assert.Equal("nginx-access", lines[0]["type"])
+		assert.Equal("java", lines[1]["type"])
+		assert.Equal("audit", lines[2]["type"])
+		assert.Equal("plain", lines[3]["type"])
+	}
+}
+
+func TestStreamWithTypeField(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:     new(router.Route),
+		conn:      conn,
+		typeField: hintField{key: "type", metadata: true},
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Env: []string{"LOGSTASH_TYPE=nginx-access"}}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: `GET / 200`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 1) {
+		assert.Nil(lines[0]["type"])
+		assert.Equal(map[string]interface{}{"type": "nginx-access"}, lines[0]["@metadata"])
+	}
+}
+
+func TestStreamWithMessageField(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:        new(router.Route),
+		conn:         conn,
+		messageField: "log",
+	}
+
+	container := docker.Container{ID: "ID", Name: "/name", Config: &docker.Config{Env: []string{"LOGSTASH_TAGS=web"}}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Source: "stdout", Data: `foo bananas`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Source: "stdout", Data: `{"message":"started","status":"200"}`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Source: "stdout", Data: `{"message":"kept","log":"own"}`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Source: "stdout", Data: `null`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 4) {
+		assert.Equal("foo bananas", lines[0]["log"])
+		assert.Nil(lines[0]["message"])
+		assert.Equal("/name", lines[0]["docker"].(map[string]interface{})["name"])
+		assert.Equal([]interface{}{"web"}, lines[0]["tags"])
+		assert.Equal("stdout", lines[0]["stream"])
+
+		assert.Equal("started", lines[1]["log"])
+		assert.Nil(lines[1]["message"])
+		assert.Equal("200", lines[1]["status"])
+
+		assert.Equal("own", lines[2]["log"])
+		assert.Equal("kept", lines[2]["message"])
+
+		assert.Equal("null", lines[3]["log"])
+	}
+}
+
+func TestStreamWithRawMessage(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:           new(router.Route),
+		conn:            conn,
+		rawMessageField: "raw_message",
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: `foo bananas`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `{"message":"started", "status":"200"}`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `{"raw_message":"own"}`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 3) {
+		assert.Nil(lines[0]["raw_message"])
+		assert.Equal("started", lines[1]["message"])
+		assert.Equal(`{"message":"started", "status":"200"}`, lines[1]["raw_message"])
+		assert.Equal("own", lines[2]["raw_message"])
+	}
+}
+
+func TestStreamWithTimestamp(t *testing.T) {
+	assert := assert.New(t)
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{}}
+	at := time.Date(2016, 10, 20, 15, 25, 13, 627000123, time.FixedZone("CEST", 2*3600))
+
+	for _, field := range []string{"@timestamp", "time"} {
+		conn := &BufferConn{}
+		adapter := LogstashAdapter{
+			route:          new(router.Route),
+			conn:           conn,
+			timestamps:     true,
+			timestampField: field,
+		}
+
+		logstream := make(chan *router.Message)
+		go func() {
+			logstream <- &router.Message{Container: &container, Data: `foo bananas`, Time: at}
+			logstream <- &router.Message{Container: &container, Data: `{"status":"200"}`, Time: at}
+			logstream <- &router.Message{Container: &container, Data: `{"` + field + `":"own"}`, Time: at}
+			close(logstream)
+		}()
+
+		adapter.Stream(logstream)
+
+		lines := conn.Lines()
+		if assert.Len(lines, 3) {
+			assert.Equal("2016-10-20T13:25:13.627000123Z", lines[0][field])
+			assert.Equal("2016-10-20T13:25:13.627000123Z", lines[1][field])
+			assert.Equal("own", lines[2][field])
+		}
+	}
+}
+
+func TestStreamWithEventIDs(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:    new(router.Route),
+		conn:     conn,
+		eventIDs: true,
+	}
+
+	shop := docker.Container{ID: "shop", Config: &docker.Config{}}
+	cart := docker.Container{ID: "cart", Config: &docker.Config{}}
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &shop, Data: `foo`}
+		logstream <- &router.Message{Container: &cart, Data: `bar`}
+		logstream <- &router.Message{Container: &shop, Data: `{"status":"200"}`}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 3) {
+		assert.Equal(float64(1), lines[0]["sequence"])
+		assert.Equal(float64(1), lines[1]["sequence"])
+		assert.Equal(float64(2), lines[2]["sequence"])
+		assert.Len(lines[0]["event_id"], 36)
+		assert.NotEqual(lines[0]["event_id"], lines[2]["event_id"])
+	}
+}
@@ -0,0 +1,99 @@
+package logstash
+
+import (
+	"log"
+	"net"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// mirror copies the documents the adapter writes to a second Logstash, on
+// a best-effort basis: documents are queued apart from those of the route,
+// dropped when the queue is full, and lost when writing them fails. Errors
+// of the mirror never hold up the route.
+type mirror struct {
+	transport router.AdapterTransport
+	address   string
+	options   map[string]string
+	queue     chan [][]byte
+	conn      net.Conn
+}
+
+// mirrorQueueSize is the number of batches a mirror queues.
+const mirrorQueueSize = 256
+
+// newMirror starts a mirror to address with a queue of size batches.
+func newMirror(transport router.AdapterTransport, address string, options map[string]string, size int) *mirror {
+	m := &mirror{
+		transport: transport,
+		address:   address,
+		options:   options,
+		queue:     make(chan [][]byte, size),
+	}
+	go m.run()
+	return m
+}
+
+// send queues a copy of a batch of documents, or drops it if the queue is
+// full.
+func (m *mirror) send(batch [][]byte) {
+	size := 0
+	for _, js := range batch {
+		size += len(js)
+	}
+	arena := make([]byte, 0, size)
+	docs := make([][]byte, len(batch))
+	for i, js := range batch {
+		start := len(arena)
+		arena = append(arena, js...)
+		docs[i] = arena[start:len(arena):len(arena)]
+	}
+
+	select {
+	case m.queue <- docs:
+	default:
+		stageMetrics.Add("mirror.dropped", int64(len(docs)))
+	}
+}
+
+// closeMirror stops the mirror of the adapter, if it has one.
+func (a *LogstashAdapter) closeMirror() {
+	if a.mirror != nil {
+		a.mirror.close()
+		a.mirror = nil
+	}
+}
+
+// close stops the mirror once the batches queued so far are written.
+func (m *mirror) close() {
+	close(m.queue)
+}
+
+// run writes the queued batches, connecting again after errors.
+func (m *mirror) run() {
+	defer func() {
+		if m.conn != nil {
+			m.conn.Close()
+		}
+	}()
+	for docs := range m.queue {
+		if m.conn == nil {
+			conn, err := m.transport.Dial(m.address, m.options)
+			if err != nil {
+				log.Println("logstash: could not connect to mirror:", err)
+				stageMetrics.Add("mirror.dropped", int64(len(docs)))
+				continue
+			}
+			m.conn = conn
+		}
+		for i, js := range docs {
+			if _, err := m.conn.Write(js); err != nil {
+				log.Println("logstash: could not write to mirror:", err)
+				stageMetrics.Add("mirror.dropped", int64(len(docs)-i))
+				m.conn.Close()
+				m.conn = nil
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package logstash
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+// FailingTransport cannot connect anywhere.
+type FailingTransport struct{}
+
+func (FailingTransport) Dial(addr string, options map[string]string) (net.Conn, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestStreamWithMirror(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &MockTransport{}
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:  new(router.Route),
+		conn:   conn,
+		mirror: newMirror(transport, "mirror:5000", nil, 16),
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: "first", Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `{"msg":"second"}`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	assert.Len(conn.Lines(), 2)
+	assert.Eventually(func() bool {
+		transport.mu.Lock()
+		defer transport.mu.Unlock()
+		return len(transport.conns) == 1 && len(transport.conns[0].Lines()) == 2
+	}, time.Second, time.Millisecond)
+	assert.Equal(conn.Lines(), transport.conns[0].Lines())
+}
+
+func TestMirrorIsBestEffort(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &mirror{transport: FailingTransport{}, queue: make(chan [][]byte, 1)}
+	arena := []byte("onetwo")
+	m.send([][]byte{arena[:3], arena[3:]})
+	m.send([][]byte{arena[:3]})
+	copy(arena, "xxxxxx")
+
+	docs := <-m.queue
+	assert.Equal([][]byte{[]byte("one"), []byte("two")}, docs)
+	assert.Empty(m.queue)
+
+	// Batches the mirror cannot write are skipped.
+	m.queue <- docs
+	close(m.queue)
+	m.run()
+	assert.Nil(m.conn)
+}
+
+func TestCloseMirror(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &FlakyTransport{dials: 1}
+	adapter := LogstashAdapter{mirror: newMirror(transport, "mirror:5000", nil, 16)}
+	adapter.mirror.send([][]byte{[]byte("{}\n")})
+	adapter.closeMirror()
+	assert.Nil(adapter.mirror)
+	adapter.closeMirror()
+
+	assert.Eventually(func() bool {
+		conns := transport.opened()
+		return len(conns) == 1 && conns[0].isClosed()
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,194 @@
+package logstash
+
+import (
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+)
+
+// multilineRule tells the lines that continue an event, such as the frames
+// of a stack trace, from those that start a new one.
+type multilineRule struct {
+	pattern *regexp.Regexp
+	// negate makes pattern match the lines that start events instead.
+	negate bool
+	// after are lines that only continue an event right after certain
+	// others, such as the blank line after a Go panic message.
+	after []lineAfter
+}
+
+// lineAfter matches lines that continue an event whose last line is prev.
+type lineAfter struct {
+	prev, line *regexp.Regexp
+}
+
+// continues reports whether line belongs to the event whose last line is
+// prev.
+func (r *multilineRule) continues(prev, line string) bool {
+	if r.pattern.MatchString(line) != r.negate {
+		return true
+	}
+	for _, after := range r.after {
+		if after.line.MatchString(line) && after.prev.MatchString(prev) {
+			return true
+		}
+	}
+	return false
+}
+
+// multilinePresets are the multiline rules of the stack traces of common
+// runtimes.
+var multilinePresets = map[string]*multilineRule{
+	// Frames, causes and suppressed exceptions, and the exception itself
+	// when it follows the line that logged it.
+	"java": {
+		pattern: regexp.MustCompile(`^(\s+at |\s+\.\.\. \d+ (more|common frames omitted)$|\s*Caused by: |\s+Suppressed: |([\w$]+\.)+[\w$]*(Exception|Error|Throwable)(: |$))`),
+	},
+	// Tracebacks, their indented frames and the exceptions they end with,
+	// including those chained to them.
+	"python": {
+		pattern: regexp.MustCompile(`^(Traceback \(most recent call last\):$|\s+\S|During handling of the above exception|The above exception was the direct cause)`),
+		after: []lineAfter{
+			{prev: regexp.MustCompile(`^\s`), line: regexp.MustCompile(`^[\w.]+(: |$)`)},
+			{prev: regexp.MustCompile(`^[\w.]+(: |$)|^(During handling of the above exception|The above exception was the direct cause)`), line: regexp.MustCompile(`^$`)},
+		},
+	},
+	// Panics and fatal errors with the stacks of all goroutines.
+	"go": {
+		pattern: regexp.MustCompile(`^(goroutine \d+ \[.*\]:$|\t|created by |\[signal )`),
+		after: []lineAfter{
+			{prev: regexp.MustCompile(`^(panic|fatal error): |^\[signal |^\t`), line: regexp.MustCompile(`^$`)},
+			{prev: regexp.MustCompile(`^goroutine \d+ \[|^\t`), line: regexp.MustCompile(`^\S+\(.*\)$`)},
+		},
+	},
+}
+
+// GetMultilineRule returns the multiline rule of a container, from the
+// LOGSTASH_MULTILINE_PATTERN and LOGSTASH_MULTILINE_NEGATE environment
+// variables or the logstash.multiline_pattern and logstash.multiline_negate
+// labels, or else the preset named by LOGSTASH_MULTILINE or the
+// logstash.multiline label. It returns nil if the lines of the container
+// are all events of their own.
+func GetMultilineRule(c *docker.Container) *multilineRule {
+	pattern := containerSetting(c, "LOGSTASH_MULTILINE_PATTERN", "logstash.multiline_pattern")
+	if pattern == "" {
+		preset := containerSetting(c, "LOGSTASH_MULTILINE", "logstash.multiline")
+		if preset == "" {
+			return nil
+		}
+		rule, ok := multilinePresets[strings.ToLower(preset)]
+		if !ok {
+			log.Println("logstash: unknown LOGSTASH_MULTILINE of container", c.ID+":", preset)
+		}
+		return rule
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		log.Println("logstash: invalid LOGSTASH_MULTILINE_PATTERN of container", c.ID+":", err)
+		return nil
+	}
+	return &multilineRule{
+		pattern: re,
+		negate:  containerBool(c, "LOGSTASH_MULTILINE_NEGATE", "logstash.multiline_negate", false),
+	}
+}
+
+// partialLineSize is the size of the chunks Docker splits long lines into.
+// Only the last chunk of a line ends with a newline, so the others reach
+// the adapter as messages of exactly this size.
+const partialLineSize = 16 * 1024
+
+// maxJoinedLineSize bounds the lines joined from chunks, so that a container
+// writing without ever ending its line cannot use up memory. Lines that
+// reach it are shipped as they are, and the next chunk starts a new line.
+const maxJoinedLineSize = 64 * partialLineSize
+
+// pendingLines is an event being joined from several lines.
+type pendingLines struct {
+	message *router.Message
+	lines   []string
+	updated time.Time
+	// partial is set while the last line is missing the rest Docker split
+	// off it.
+	partial bool
+}
+
+// lineJoiner joins the chunks of long lines that Docker split, and
+// continuation lines into the events they belong to. The lines of each
+// container and stream are joined separately.
+type lineJoiner struct {
+	maxLines     int
+	partialLines bool
+	pending      map[string]*pendingLines
+}
+
+// add hands m to emit, unless it may be continued by the lines after it,
+// in which case it is held back until an event is complete. Events are
+// complete when a line that does not continue them arrives, or once they
+// have maxLines lines.
+func (j *lineJoiner) add(m *router.Message, rule *multilineRule, emit func(*router.Message)) {
+	key := m.Container.ID + "\x00" + m.Source
+	partial := j.partialLines && len(m.Data) == partialLineSize
+	p := j.pending[key]
+	if p != nil && p.partial {
+		p.lines[len(p.lines)-1] += m.Data
+		p.partial = partial && len(p.lines[len(p.lines)-1]) < maxJoinedLineSize
+		p.updated = time.Now()
+		if !p.partial && rule == nil {
+			delete(j.pending, key)
+			emit(p.join())
+		}
+		return
+	}
+	if p != nil {
+		if rule != nil && rule.continues(p.lines[len(p.lines)-1], m.Data) && (j.maxLines <= 0 || len(p.lines) < j.maxLines) {
+			p.lines = append(p.lines, m.Data)
+			p.partial = partial
+			p.updated = time.Now()
+			return
+		}
+		delete(j.pending, key)
+		emit(p.join())
+	}
+	if rule == nil && !partial {
+		emit(m)
+		return
+	}
+	if j.pending == nil {
+		j.pending = make(map[string]*pendingLines)
+	}
+	j.pending[key] = &pendingLines{message: m, lines: []string{m.Data}, updated: time.Now(), partial: partial}
+}
+
+// expire emits the events that have not been continued since before.
+func (j *lineJoiner) expire(before time.Time, emit func(*router.Message)) {
+	for key, p := range j.pending {
+		if p.updated.Before(before) {
+			delete(j.pending, key)
+			emit(p.join())
+		}
+	}
+}
+
+// flush emits all pending events.
+func (j *lineJoiner) flush(emit func(*router.Message)) {
+	for key, p := range j.pending {
+		delete(j.pending, key)
+		emit(p.join())
+	}
+}
+
+// join returns the message of the event, with the time of its first line.
+func (p *pendingLines) join() *router.Message {
+	data := strings.Join(p.lines, "\n")
+	if data == p.message.Data {
+		return p.message
+	}
+	m := *p.message
+	m.Data = data
+	return &m
+}
@@ -0,0 +1,260 @@
+package logstash
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMultilineRule(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(GetMultilineRule(&docker.Container{Config: &docker.Config{}}))
+	assert.Nil(GetMultilineRule(&docker.Container{Config: &docker.Config{Env: []string{"LOGSTASH_MULTILINE_PATTERN=("}}}))
+
+	rule := GetMultilineRule(&docker.Container{Config: &docker.Config{Env: []string{`LOGSTASH_MULTILINE_PATTERN=^\s`}}})
+	if assert.NotNil(rule) {
+		assert.True(rule.continues("", "\tat Main.main(Main.java:3)"))
+		assert.False(rule.continues("", "Exception in thread \"main\""))
+	}
+
+	rule = GetMultilineRule(&docker.Container{Config: &docker.Config{
+		Env:    []string{"LOGSTASH_MULTILINE_NEGATE=true"},
+		Labels: map[string]string{"logstash.multiline_pattern": `^\d{4}-`},
+	}})
+	if assert.NotNil(rule) {
+		assert.False(rule.continues("", "2016-10-20 started"))
+		assert.True(rule.continues("", "details"))
+	}
+}
+
+func TestLineJoiner(t *testing.T) {
+	assert := assert.New(t)
+
+	rule := &multilineRule{pattern: regexp.MustCompile(`^\s`)}
+	a := &docker.Container{ID: "a"}
+	b := &docker.Container{ID: "b"}
+
+	var emitted []string
+	emit := func(m *router.Message) { emitted = append(emitted, m.Container.ID+":"+m.Data) }
+
+	j := lineJoiner{maxLines: 3}
+	j.add(&router.Message{Container: a, Data: "first"}, rule, emit)
+	j.add(&router.Message{Container: a, Data: " 1"}, rule, emit)
+	j.add(&router.Message{Container: b, Data: "other"}, nil, emit)
+	j.add(&router.Message{Container: a, Data: "stderr", Source: "stderr"}, rule, emit)
+	j.add(&router.Message{Container: a, Data: " 2"}, rule, emit)
+	j.add(&router.Message{Container: a, Data: " 3"}, rule, emit)
+	j.add(&router.Message{Container: a, Data: "second"}, rule, emit)
+	assert.Equal([]string{"b:other", "a:first\n 1\n 2", "a: 3"}, emitted)
+
+	emitted = nil
+	j.expire(time.Now().Add(-time.Hour), emit)
+	assert.Empty(emitted)
+	j.flush(emit)
+	assert.ElementsMatch([]string{"a:stderr", "a:second"}, emitted)
+	assert.Empty(j.pending)
+}
+
+func TestStreamWithMultiline(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:             new(router.Route),
+		conn:              conn,
+		timestamps:        true,
+		multilineTimeout:  10 * time.Millisecond,
+		multilineMaxLines: 500,
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Env: []string{`LOGSTASH_MULTILINE_PATTERN=^(\s|Caused by:)`}}}
+	start := time.Now()
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: "Exception in thread \"main\" java.lang.IllegalStateException", Time: start}
+		logstream <- &router.Message{Container: &container, Data: "\tat Main.main(Main.java:3)", Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: "Caused by: java.io.IOException", Time: time.Now()}
+		time.Sleep(100 * time.Millisecond)
+		logstream <- &router.Message{Container: &container, Data: "\tat late", Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: "done", Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 3) {
+		assert.Equal("Exception in thread \"main\" java.lang.IllegalStateException\n\tat Main.main(Main.java:3)\nCaused by: java.io.IOException", lines[0]["message"])
+		assert.Equal(start.UTC().Format(time.RFC3339Nano), lines[0]["@timestamp"])
+		assert.Equal("\tat late", lines[1]["message"])
+		assert.Equal("done", lines[2]["message"])
+	}
+}
+
+func TestMultilinePresets(t *testing.T) {
+	assert := assert.New(t)
+
+	for preset, lines := range map[string][]string{
+		"java": {
+			"2016-10-20 13:25:13 ERROR request failed",
+			"java.lang.IllegalStateException: closed",
+			"\tat com.example.Pool.get(Pool.java:42)",
+			"\tat com.example.Main.main(Main.java:7)",
+			"Caused by: java.io.IOException: broken pipe",
+			"\tat com.example.Conn.write(Conn.java:12)",
+			"\t... 2 more",
+		},
+		"python": {
+			"Traceback (most recent call last):",
+			`  File "app.py", line 3, in <module>`,
+			"    main()",
+			"KeyError: 'user'",
+			"",
+			"During handling of the above exception, another exception occurred:",
+			"",
+			"Traceback (most recent call last):",
+			`  File "app.py", line 5, in <module>`,
+			"ValueError",
+		},
+		"go": {
+			"panic: runtime error: index out of range [3] with length 3",
+			"",
+			"goroutine 1 [running]:",
+			"main.main()",
+			"\t/app/main.go:8 +0x1d",
+			"",
+			"goroutine 6 [chan receive]:",
+			"main.worker(0xc000010000)",
+			"\t/app/main.go:14 +0x2a",
+			"created by main.main in goroutine 1",
+			"\t/app/main.go:6 +0x17",
+		},
+	} {
+		rule := GetMultilineRule(&docker.Container{Config: &docker.Config{Labels: map[string]string{"logstash.multiline": preset}}})
+		if !assert.NotNil(rule, preset) {
+			continue
+		}
+
+		var emitted []string
+		emit := func(m *router.Message) { emitted = append(emitted, m.Data) }
+		j := lineJoiner{}
+		for _, line := range append(lines, "next event") {
+			j.add(&router.Message{Container: &docker.Container{}, Data: line}, rule, emit)
+		}
+		j.flush(emit)
+		assert.Equal([]string{strings.Join(lines, "\n"), "next event"}, emitted, preset)
+	}
+
+	assert.Nil(GetMultilineRule(&docker.Container{Config: &docker.Config{Env: []string{"LOGSTASH_MULTILINE=ruby"}}}))
+	assert.NotNil(GetMultilineRule(&docker.Container{Config: &docker.Config{Env: []string{"LOGSTASH_MULTILINE=Go"}}}))
+}
+
+func TestLineJoinerPartialLines(t *testing.T) {
+	assert := assert.New(t)
+
+	c := &docker.Container{ID: "a"}
+	chunk := strings.Repeat("x", partialLineSize)
+
+	var emitted []string
+	emit := func(m *router.Message) { emitted = append(emitted, m.Data) }
+
+	j := lineJoiner{partialLines: true}
+	j.add(&router.Message{Container: c, Data: chunk}, nil, emit)
+	j.add(&router.Message{Container: c, Data: chunk}, nil, emit)
+	assert.Empty(emitted)
+	j.add(&router.Message{Container: c, Data: "end"}, nil, emit)
+	j.add(&router.Message{Container: c, Data: "short"}, nil, emit)
+	assert.Equal([]string{chunk + chunk + "end", "short"}, emitted)
+
+	// Chunks of continuation lines are joined before the next line is
+	// matched against the rule.
+	emitted = nil
+	rule := &multilineRule{pattern: regexp.MustCompile(`^\s`)}
+	j.add(&router.Message{Container: c, Data: "first"}, rule, emit)
+	j.add(&router.Message{Container: c, Data: " " + chunk[1:]}, rule, emit)
+	j.add(&router.Message{Container: c, Data: "rest"}, rule, emit)
+	j.add(&router.Message{Container: c, Data: "second"}, rule, emit)
+	assert.Equal([]string{"first\n " + chunk[1:] + "rest"}, emitted)
+
+	emitted = nil
+	j = lineJoiner{}
+	j.add(&router.Message{Container: c, Data: chunk}, nil, emit)
+	assert.Equal([]string{chunk}, emitted)
+
+	// A line that never ends is shipped in pieces of the maximum size.
+	emitted = nil
+	j = lineJoiner{partialLines: true}
+	for i := 0; i < maxJoinedLineSize/partialLineSize+1; i++ {
+		j.add(&router.Message{Container: c, Data: chunk}, nil, emit)
+	}
+	if assert.Len(emitted, 1) {
+		assert.Len(emitted[0], maxJoinedLineSize)
+	}
+	assert.Len(j.pending, 1)
+}
+
+func TestStreamWithPartialLines(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:        new(router.Route),
+		conn:         conn,
+		partialLines: true,
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{}}
+	line := `{"payload":"` + strings.Repeat("x", partialLineSize) + `"}`
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: line[:partialLineSize], Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: line[partialLineSize:], Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 1) {
+		assert.Equal(strings.Repeat("x", partialLineSize), lines[0]["payload"])
+	}
+}
+
+func TestStreamWithExactChunkSizeLine(t *testing.T) {
+	assert := assert.New(t)
+
+	adapter, err := NewLogstashAdapter(&router.Route{Adapter: "logstash+debug"})
+	if !assert.Nil(err) {
+		return
+	}
+	assert.False(adapter.(*LogstashAdapter).partialLines)
+
+	conn := &BufferConn{}
+	a := LogstashAdapter{route: new(router.Route), conn: conn}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{}}
+	line := strings.Repeat("x", partialLineSize)
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: line, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: "next", Time: time.Now()}
+		close(logstream)
+	}()
+
+	a.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 2) {
+		assert.Equal(line, lines[0]["message"])
+		assert.Equal("next", lines[1]["message"])
+	}
+}
@@ -0,0 +1,82 @@
+package logstash
+
+import (
+	"sort"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// GetNetworkInfo returns the sorted names of the networks a container is
+// attached to, and its IPv4 and IPv6 addresses on them.
+func GetNetworkInfo(c *docker.Container) (networks []string, ips []string) {
+	settings := c.NetworkSettings
+	if settings == nil {
+		return nil, nil
+	}
+
+	for name := range settings.Networks {
+		networks = append(networks, name)
+	}
+	sort.Strings(networks)
+
+	seen := make(map[string]bool)
+	add := func(ip string) {
+		if ip != "" && !seen[ip] {
+			seen[ip] = true
+			ips = append(ips, ip)
+		}
+	}
+	for _, name := range networks {
+		add(settings.Networks[name].IPAddress)
+		add(settings.Networks[name].GlobalIPv6Address)
+	}
+	// Older daemons only report the address on the default bridge.
+	add(settings.IPAddress)
+	add(settings.GlobalIPv6Address)
+
+	return networks, ips
+}
+
+// PortMapping is a published port: traffic to HostIP:HostPort on the node is
+// forwarded to ContainerPort inside the container.
+type PortMapping struct {
+	HostIP        string `json:"host_ip,omitempty"`
+	HostPort      string `json:"host_port"`
+	ContainerPort string `json:"container_port"`
+	Protocol      string `json:"protocol"`
+}
+
+// GetPortMappings returns the container's published port bindings, sorted by
+// container port, protocol and host address. Exposed ports that are not
+// bound on the host are left out.
+func GetPortMappings(c *docker.Container) []PortMapping {
+	if c.NetworkSettings == nil {
+		return nil
+	}
+
+	var ports []PortMapping
+	for port, bindings := range c.NetworkSettings.Ports {
+		for _, b := range bindings {
+			ports = append(ports, PortMapping{
+				HostIP:        b.HostIP,
+				HostPort:      b.HostPort,
+				ContainerPort: port.Port(),
+				Protocol:      port.Proto(),
+			})
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		a, b := ports[i], ports[j]
+		if a.ContainerPort != b.ContainerPort {
+			return a.ContainerPort < b.ContainerPort
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		if a.HostIP != b.HostIP {
+			return a.HostIP < b.HostIP
+		}
+		return a.HostPort < b.HostPort
+	})
+	return ports
+}
@@ -0,0 +1,48 @@
+package logstash
+
+import (
+	"testing"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetNetworkInfo(t *testing.T) {
+	assert := assert.New(t)
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{}}
+	networks, ips := GetNetworkInfo(&container)
+	assert.Nil(networks)
+	assert.Nil(ips)
+
+	container.NetworkSettings = &docker.NetworkSettings{
+		IPAddress: "172.17.0.2",
+		Networks: map[string]docker.ContainerNetwork{
+			"frontend": {IPAddress: "10.0.1.5", GlobalIPv6Address: "fd00::5"},
+			"bridge":   {IPAddress: "172.17.0.2"},
+		},
+	}
+	networks, ips = GetNetworkInfo(&container)
+	assert.Equal([]string{"bridge", "frontend"}, networks)
+	assert.Equal([]string{"172.17.0.2", "10.0.1.5", "fd00::5"}, ips)
+}
+
+func TestGetPortMappings(t *testing.T) {
+	assert := assert.New(t)
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{}}
+	assert.Nil(GetPortMappings(&container))
+
+	container.NetworkSettings = &docker.NetworkSettings{
+		Ports: map[docker.Port][]docker.PortBinding{
+			"8080/tcp": {{HostIP: "0.0.0.0", HostPort: "32768"}, {HostIP: "::", HostPort: "32768"}},
+			"53/udp":   {{HostIP: "10.0.0.1", HostPort: "53"}},
+			"9000/tcp": nil,
+		},
+	}
+	assert.Equal([]PortMapping{
+		{HostIP: "10.0.0.1", HostPort: "53", ContainerPort: "53", Protocol: "udp"},
+		{HostIP: "0.0.0.0", HostPort: "32768", ContainerPort: "8080", Protocol: "tcp"},
+		{HostIP: "::", HostPort: "32768", ContainerPort: "8080", Protocol: "tcp"},
+	}, GetPortMappings(&container))
+}
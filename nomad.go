@@ -0,0 +1,54 @@
+package logstash
+
+import (
+	"strings"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// NomadData describes the Nomad allocation a container belongs to.
+type NomadData struct {
+	AllocID    string `json:"alloc_id,omitempty"`
+	JobName    string `json:"job_name,omitempty"`
+	TaskName   string `json:"task_name,omitempty"`
+	GroupName  string `json:"group_name,omitempty"`
+	Datacenter string `json:"datacenter,omitempty"`
+}
+
+// GetNomadData returns the Nomad allocation information found in the
+// container environment, or nil if the container was not started by Nomad.
+func GetNomadData(c *docker.Container) *NomadData {
+	var d NomadData
+	for _, e := range c.Config.Env {
+		if !strings.HasPrefix(e, "NOMAD_") {
+			continue
+		}
+		if strings.HasPrefix(e, "NOMAD_ALLOC_ID=") {
+			d.AllocID = strings.TrimPrefix(e, "NOMAD_ALLOC_ID=")
+		} else if strings.HasPrefix(e, "NOMAD_JOB_NAME=") {
+			d.JobName = strings.TrimPrefix(e, "NOMAD_JOB_NAME=")
+		} else if strings.HasPrefix(e, "NOMAD_TASK_NAME=") {
+			d.TaskName = strings.TrimPrefix(e, "NOMAD_TASK_NAME=")
+		} else if strings.HasPrefix(e, "NOMAD_GROUP_NAME=") {
+			d.GroupName = strings.TrimPrefix(e, "NOMAD_GROUP_NAME=")
+		} else if strings.HasPrefix(e, "NOMAD_DC=") {
+			d.Datacenter = strings.TrimPrefix(e, "NOMAD_DC=")
+		}
+	}
+	if d == (NomadData{}) {
+		return nil
+	}
+	return &d
+}
+
+// appendJSON appends the JSON encoding of d to dst.
+func (d *NomadData) appendJSON(dst []byte) []byte {
+	dst = append(dst, '{')
+	n := len(dst)
+	dst = appendJSONStringField(dst, n, "alloc_id", d.AllocID)
+	dst = appendJSONStringField(dst, n, "job_name", d.JobName)
+	dst = appendJSONStringField(dst, n, "task_name", d.TaskName)
+	dst = appendJSONStringField(dst, n, "group_name", d.GroupName)
+	dst = appendJSONStringField(dst, n, "datacenter", d.Datacenter)
+	return append(dst, '}')
+}
@@ -0,0 +1,32 @@
+package logstash
+
+import (
+	"testing"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetNomadData(t *testing.T) {
+	assert := assert.New(t)
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Env: []string{
+		"PATH=/usr/bin",
+		"NOMAD_ALLOC_ID=5d2bd4b8-2b2c-4d8e-a7e6-2f4cca0a9e7f",
+		"NOMAD_JOB_NAME=shop",
+		"NOMAD_TASK_NAME=web",
+		"NOMAD_GROUP_NAME=frontend",
+		"NOMAD_DC=eu-west-1",
+		"NOMAD_CPU_LIMIT=500",
+	}}}
+	assert.Equal(&NomadData{
+		AllocID:    "5d2bd4b8-2b2c-4d8e-a7e6-2f4cca0a9e7f",
+		JobName:    "shop",
+		TaskName:   "web",
+		GroupName:  "frontend",
+		Datacenter: "eu-west-1",
+	}, GetNomadData(&container))
+
+	container.Config.Env = []string{"PATH=/usr/bin"}
+	assert.Nil(GetNomadData(&container))
+}
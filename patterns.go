@@ -0,0 +1,22 @@
+package logstash
+
+import "regexp"
+
+// accessLogPattern matches the combined log format of nginx and Apache, and
+// the common log format it extends.
+var accessLogPattern = regexp.MustCompile(`^(?P<client_ip>\S+) \S+ (?P<remote_user>\S+) \[(?P<time_local>[^\]]+)\] "(?P<method>[A-Z]+) (?P<path>[^ "]*)(?: (?P<http_version>HTTP/[0-9.]+))?" (?P<status>\d{3}) (?P<bytes>\d+|-)(?: "(?P<referrer>[^"]*)" "(?P<user_agent>[^"]*)")?`)
+
+// patterns are the built-in extractors containers select with
+// LOGSTASH_PATTERN, for the logs of common infrastructure.
+var patterns = map[string]*regexp.Regexp{
+	"nginx":  accessLogPattern,
+	"apache": accessLogPattern,
+	"nginx_error": regexp.MustCompile(
+		`^(?P<time>\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}) \[(?P<level>\w+)\] (?P<pid>\d+)#(?P<tid>\d+): (?:\*(?P<connection_id>\d+) )?(?P<error>.*)`),
+	"haproxy": regexp.MustCompile(
+		`(?P<client_ip>[0-9A-Fa-f.:]+):(?P<client_port>\d+) \[(?P<accept_date>[^\]]+)\] (?P<frontend>\S+) (?P<backend>[^/ ]+)/(?P<server>\S+) (?P<time_request>-?\d+)/(?P<time_queue>-?\d+)/(?P<time_connect>-?\d+)/(?P<time_response>-?\d+)/\+?(?P<time_total>\d+) (?P<status>-?\d+) \+?(?P<bytes>\d+)[^"]*(?:"(?P<method>[A-Z]+) (?P<path>[^ "]+)[^"]*")?`),
+	"postgres": regexp.MustCompile(
+		`^(?P<time>\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}(?:\.\d+)?(?: \w+)?) \[(?P<pid>\d+)\] (?:(?P<user>[^@ ]+)@(?P<database>\S+) )?(?P<level>[A-Z]+[0-9]?):\s+(?P<log_message>.*)`),
+	"jvm_gc": regexp.MustCompile(
+		`GC\((?P<gc_id>\d+)\) (?P<gc_event>.+?) (?P<heap_before>\d+[KMG])->(?P<heap_after>\d+[KMG])\((?P<heap_total>\d+[KMG])\) (?P<duration_ms>[0-9.]+)ms`),
+}
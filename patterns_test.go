@@ -0,0 +1,61 @@
+package logstash
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatterns(t *testing.T) {
+	assert := assert.New(t)
+
+	for name, tc := range map[string]struct {
+		line     string
+		expected map[string]interface{}
+	}{
+		"nginx": {
+			`172.17.0.1 - alice [20/Oct/2016:13:25:13 +0000] "GET /index.html?q=1 HTTP/1.1" 200 612 "-" "curl/7.50.1"`,
+			map[string]interface{}{"client_ip": "172.17.0.1", "remote_user": "alice", "time_local": "20/Oct/2016:13:25:13 +0000", "method": "GET", "path": "/index.html?q=1", "http_version": "HTTP/1.1", "status": "200", "bytes": "612", "referrer": "-", "user_agent": "curl/7.50.1"},
+		},
+		"apache": {
+			`::1 - - [20/Oct/2016:13:25:13 +0000] "POST /login HTTP/1.0" 302 -`,
+			map[string]interface{}{"client_ip": "::1", "remote_user": "-", "time_local": "20/Oct/2016:13:25:13 +0000", "method": "POST", "path": "/login", "http_version": "HTTP/1.0", "status": "302", "bytes": "-"},
+		},
+		"nginx_error": {
+			`2016/10/20 13:25:13 [error] 7#7: *12 open() "/usr/share/nginx/html/x" failed (2: No such file or directory)`,
+			map[string]interface{}{"time": "2016/10/20 13:25:13", "level": "error", "pid": "7", "tid": "7", "connection_id": "12", "error": `open() "/usr/share/nginx/html/x" failed (2: No such file or directory)`},
+		},
+		"haproxy": {
+			`10.0.1.2:33317 [20/Oct/2016:13:25:13.627] http-in static/srv1 10/0/30/69/109 200 2750 - - ---- 1/1/1/1/0 0/0 "GET /index.html HTTP/1.1"`,
+			map[string]interface{}{"client_ip": "10.0.1.2", "client_port": "33317", "accept_date": "20/Oct/2016:13:25:13.627", "frontend": "http-in", "backend": "static", "server": "srv1", "time_request": "10", "time_queue": "0", "time_connect": "30", "time_response": "69", "time_total": "109", "status": "200", "bytes": "2750", "method": "GET", "path": "/index.html"},
+		},
+		"postgres": {
+			`2016-10-20 13:25:13.627 UTC [42] app@shop ERROR:  relation "users" does not exist`,
+			map[string]interface{}{"time": "2016-10-20 13:25:13.627 UTC", "pid": "42", "user": "app", "database": "shop", "level": "ERROR", "log_message": `relation "users" does not exist`},
+		},
+		"jvm_gc": {
+			`[2016-10-20T13:25:13.627+0000][info][gc] GC(3) Pause Young (Normal) (G1 Evacuation Pause) 24M->4M(256M) 3.456ms`,
+			map[string]interface{}{"gc_id": "3", "gc_event": "Pause Young (Normal) (G1 Evacuation Pause)", "heap_before": "24M", "heap_after": "4M", "heap_total": "256M", "duration_ms": "3.456"},
+		},
+	} {
+		assert.Equal(tc.expected, extractFields(tc.line, []*regexp.Regexp{patterns[name]}), name)
+	}
+}
+
+func TestGetContainerPattern(t *testing.T) {
+	assert := assert.New(t)
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{
+		Env:    []string{`LOGSTASH_EXTRACT=took (?P<took>\d+)ms`},
+		Labels: map[string]string{"logstash.pattern": "Nginx"},
+	}}
+	extractors := GetContainerExtractors(&container)
+	if assert.Len(extractors, 2) {
+		assert.True(extractors[0] == patterns["nginx"])
+	}
+
+	container.Config.Labels["logstash.pattern"] = "unknown"
+	assert.Len(GetContainerExtractors(&container), 1)
+}
@@ -0,0 +1,143 @@
+package logstash
+
+import (
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// containerQueue feeds the messages of one container, or tenant, to its own
+// adapter, which owns a separate connection to Logstash.
+type containerQueue struct {
+	messages chan *router.Message
+	failed   chan struct{}
+	lastSeen time.Time
+	dropped  int
+}
+
+// queueFullSource counts the messages dropped because the queue of their
+// container, or tenant, was full, in droppedEvents.
+const queueFullSource = "queue_full"
+
+// close closes the queue, logging the messages it dropped since it last
+// took one.
+func (q *containerQueue) close(kind, key string) {
+	close(q.messages)
+	if q.dropped > 0 {
+		log.Println("logstash: dropped", q.dropped, "messages from "+kind, key)
+	}
+}
+
+// streamPerContainer fans messages out to one adapter and connection per
+// container, so a container whose output backs up cannot delay the others.
+func (a *LogstashAdapter) streamPerContainer(logstream chan *router.Message) {
+	a.streamIsolated(logstream, "container", func(m *router.Message) string { return m.Container.ID })
+}
+
+// streamIsolated fans messages out to one adapter and connection per key,
+// the kind of which is named for logging. Messages arriving while the queue
+// of their key is full are dropped. Queues that have been idle for longer
+// than the idle timeout are closed along with their connection, as are those
+// whose adapter could not write; the next message of their key dials anew.
+func (a *LogstashAdapter) streamIsolated(logstream chan *router.Message, kind string, key func(*router.Message) string) {
+	queues := make(map[string]*containerQueue)
+	var wg sync.WaitGroup
+
+	ticker := time.NewTicker(a.idleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case m, ok := <-logstream:
+			if !ok {
+				for k, q := range queues {
+					q.close(kind, k)
+				}
+				wg.Wait()
+				return
+			}
+
+			k := key(m)
+			q, found := queues[k]
+			if found && q.writeFailed() {
+				q.close(kind, k)
+				delete(queues, k)
+				found = false
+			}
+			if !found {
+				conn, err := a.transport.Dial(a.endpointFor(k), a.route.Options)
+				if err != nil {
+					log.Println("logstash: could not connect for "+kind, k, err)
+					continue
+				}
+
+				q = &containerQueue{messages: make(chan *router.Message, a.queueSize), failed: make(chan struct{})}
+				queues[k] = q
+
+				child := a.withConn(conn)
+				child.failed = q.failed
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					child.Stream(q.messages)
+					conn.Close()
+				}()
+			}
+
+			q.lastSeen = time.Now()
+			select {
+			case q.messages <- m:
+				if q.dropped > 0 {
+					log.Println("logstash: dropped", q.dropped, "messages from "+kind, k)
+					q.dropped = 0
+				}
+			default:
+				q.dropped++
+				droppedEvents.Add(queueFullSource, 1)
+			}
+		case now := <-ticker.C:
+			for k, q := range queues {
+				if now.Sub(q.lastSeen) > a.idleTimeout || q.writeFailed() {
+					q.close(kind, k)
+					delete(queues, k)
+				}
+			}
+		}
+	}
+}
+
+// writeFailed reports whether the adapter of the queue could not write.
+func (q *containerQueue) writeFailed() bool {
+	select {
+	case <-q.failed:
+		return true
+	default:
+		return false
+	}
+}
+
+// withConn returns a copy of the adapter that writes to conn with its own
+// batch and per-container state. Docker events reach it through the stream
+// of the parent, which alone listens for them.
+func (a *LogstashAdapter) withConn(conn net.Conn) *LogstashAdapter {
+	if a.eventIDs && a.sequences == nil {
+		a.sequences = new(sequencer)
+	}
+	child := *a
+	child.conn = conn
+	child.packets = newPacketBatchWriter(conn)
+	// The configuration was validated when the adapter was created.
+	child.compressor, _ = newCompressor(a.compression, a.compressionLevel)
+	child.containers = nil
+	child.imageDigests = nil
+	child.batch = nil
+	child.events = nil
+	child.perContainer = false
+	child.tenantIsolation = false
+	child.shards = nil
+	child.failed = nil
+	return &child
+}
@@ -0,0 +1,201 @@
+package logstash
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamPerContainerConnections(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &MockTransport{}
+	adapter := LogstashAdapter{
+		route:         new(router.Route),
+		conn:          &BufferConn{},
+		transport:     transport,
+		batchSize:     1,
+		flushInterval: time.Second,
+		perContainer:  true,
+		queueSize:     16,
+		idleTimeout:   time.Minute,
+	}
+
+	first := docker.Container{ID: "first", Config: &docker.Config{}}
+	second := docker.Container{ID: "second", Config: &docker.Config{}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &first, Data: "a"}
+		logstream <- &router.Message{Container: &second, Data: "b"}
+		logstream <- &router.Message{Container: &first, Data: "c"}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	assert.Len(transport.conns, 2)
+	var messages []interface{}
+	for _, line := range transport.conns[0].Lines() {
+		assert.Equal("first", line["docker"].(map[string]interface{})["id"])
+		messages = append(messages, line["message"])
+	}
+	assert.Equal([]interface{}{"a", "c"}, messages)
+
+	lines := transport.conns[1].Lines()
+	assert.Len(lines, 1)
+	assert.Equal("b", lines[0]["message"])
+}
+
+func TestStreamPerContainerDockerEvents(t *testing.T) {
+	assert := assert.New(t)
+
+	container := &docker.Container{ID: "ID", Name: "/shop", Config: &docker.Config{}}
+	client := &MockDockerClient{containers: map[string]*docker.Container{"ID": container}}
+	transport := &MockTransport{}
+	adapter := LogstashAdapter{
+		route:         new(router.Route),
+		conn:          &BufferConn{},
+		transport:     transport,
+		batchSize:     1,
+		flushInterval: time.Second,
+		perContainer:  true,
+		queueSize:     16,
+		idleTimeout:   time.Minute,
+		stageBuffer:   16,
+		events:        client,
+		dockerEvents:  map[string]bool{"DIE": true},
+	}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: container, Data: "stopping", Time: time.Now()}
+		// Let the container get its own adapter before the event arrives.
+		time.Sleep(50 * time.Millisecond)
+		client.broadcast(&docker.APIEvents{Action: "die", Type: "container", Actor: docker.APIActor{ID: "ID"}})
+		time.Sleep(50 * time.Millisecond)
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	var events int
+	for _, conn := range transport.conns {
+		for _, line := range conn.Lines() {
+			if line["stream"] == dockerEventSource {
+				events++
+			}
+		}
+	}
+	assert.Equal(1, events)
+}
+
+func TestStreamPerContainerSequences(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &MockTransport{}
+	adapter := LogstashAdapter{
+		route:         new(router.Route),
+		conn:          &BufferConn{},
+		transport:     transport,
+		batchSize:     1,
+		flushInterval: time.Second,
+		perContainer:  true,
+		queueSize:     16,
+		idleTimeout:   20 * time.Millisecond,
+		eventIDs:      true,
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: "a"}
+		// The idle connection of the container is closed in the meantime.
+		time.Sleep(100 * time.Millisecond)
+		logstream <- &router.Message{Container: &container, Data: "b"}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	if assert.Len(transport.conns, 2) {
+		assert.Equal(float64(1), transport.conns[0].Lines()[0]["sequence"])
+		assert.Equal(float64(2), transport.conns[1].Lines()[0]["sequence"])
+	}
+}
+
+// BrokenConn fails to write.
+type BrokenConn struct {
+	BufferConn
+}
+
+func (c *BrokenConn) Write(b []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+// BreakingTransport makes a broken connection first, and then good ones.
+type BreakingTransport struct {
+	MockTransport
+	broken bool
+}
+
+func (t *BreakingTransport) Dial(addr string, options map[string]string) (net.Conn, error) {
+	t.mu.Lock()
+	if !t.broken {
+		t.broken = true
+		t.mu.Unlock()
+		return &BrokenConn{}, nil
+	}
+	t.mu.Unlock()
+	return t.MockTransport.Dial(addr, options)
+}
+
+func TestStreamPerContainerWriteError(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &BreakingTransport{}
+	adapter := LogstashAdapter{
+		route:         new(router.Route),
+		conn:          &BufferConn{},
+		transport:     transport,
+		batchSize:     1,
+		flushInterval: time.Second,
+		perContainer:  true,
+		queueSize:     16,
+		idleTimeout:   time.Minute,
+	}
+
+	first := docker.Container{ID: "first", Config: &docker.Config{}}
+	second := docker.Container{ID: "second", Config: &docker.Config{}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &first, Data: "lost"}
+		logstream <- &router.Message{Container: &second, Data: "a"}
+		// The write of the first container fails in the meantime.
+		time.Sleep(50 * time.Millisecond)
+		logstream <- &router.Message{Container: &first, Data: "b"}
+		logstream <- &router.Message{Container: &second, Data: "c"}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	if assert.Len(transport.conns, 2) {
+		var messages []interface{}
+		for _, line := range transport.conns[0].Lines() {
+			messages = append(messages, line["message"])
+		}
+		assert.Equal([]interface{}{"a", "c"}, messages)
+		lines := transport.conns[1].Lines()
+		if assert.Len(lines, 1) {
+			assert.Equal("b", lines[0]["message"])
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package logstash
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gliderlabs/logspout/router"
+)
+
+// stageMetrics holds per-stage counters of all adapters, published under
+// "logstash" in expvar. For every stage it counts the messages that went
+// through it, the errors it hit and the nanoseconds it spent working.
+var stageMetrics = expvar.NewMap("logstash")
+
+func countStage(stage string, n int, start time.Time, err error) {
+	stageMetrics.Add(stage+".messages", int64(n))
+	stageMetrics.Add(stage+".nanoseconds", int64(time.Since(start)))
+	if err != nil {
+		stageMetrics.Add(stage+".errors", 1)
+	}
+}
+
+// eventPool and documentPool recycle the per-message allocations of the
+// pipeline, which otherwise dominate garbage collection on busy hosts.
+var eventPool = sync.Pool{
+	New: func() interface{} { return new(event) },
+}
+
+var documentPool = sync.Pool{
+	New: func() interface{} {
+		d := &document{data: make(map[string]interface{})}
+		d.enc = json.NewEncoder(&d.Buffer)
+		return d
+	},
+}
+
+// document is a serialized event, along with the encoder, scratch space
+// and the maps used to produce it.
+type document struct {
+	bytes.Buffer
+	enc       *json.Encoder
+	scratch   []byte
+	data      map[string]interface{}
+	namespace map[string]interface{}
+	gelf      map[string]interface{}
+
+	// ends are the offsets at which the documents in the buffer end, but
+	// for the last one, when it holds the chunks of an event.
+	ends []int
+}
+
+// reset empties d, so that an event can be encoded into it anew.
+func (d *document) reset() {
+	d.Reset()
+	d.ends = d.ends[:0]
+}
+
+// enrichStage joins multiline events, drops those that drop rules match,
+// collapses repeated ones, drops those that sampling leaves out or rate
+// limits suppress, and attaches container metadata to every other message
+// from logstream. It closes events once logstream is closed.
+//
+// If configured to, it also backfills the lines each container logged
+// before its first message, sheds events by priority as events fills up,
+// and warns when the backlog of events and of the documents written reports
+// keeps growing. Unless suppressed events are dropped outright, it reports
+// them every rateLimitInterval.
+func (a *LogstashAdapter) enrichStage(logstream chan *router.Message, events chan<- *event, written func() int) {
+	defer close(events)
+	send := func(m *router.Message, repeats int) {
+		if a.priorities {
+			p := a.containerMeta(m.Container).priority
+			if m.Source == "stderr" && p < priorityHigh {
+				p++
+			}
+			if shed(p, len(events), cap(events)) {
+				droppedEvents.Add("backpressure_"+p.String(), 1)
+				return
+			}
+		}
+		start := time.Now()
+		e := a.enrich(m)
+		e.repeats = repeats
+		countStage("enrich", 1, start, nil)
+		events <- e
+	}
+	report := func(m *router.Message) { send(m, 1) }
+	ship := func(m *router.Message, repeats int) {
+		meta := a.containerMeta(m.Container)
+		if sampledOut(meta.sample) {
+			return
+		}
+		if meta.limit != nil && !meta.limit.allow(time.Now()) {
+			return
+		}
+		send(m, repeats)
+	}
+	dups := deduper{window: a.dedupWindow}
+	emit := func(m *router.Message) {
+		meta := a.containerMeta(m.Container)
+		if dropped(m, meta.drop) {
+			return
+		}
+		if meta.dedup {
+			dups.add(m, time.Now(), ship)
+			return
+		}
+		ship(m, 1)
+	}
+
+	var expire <-chan time.Time
+	if a.multilineTimeout > 0 {
+		ticker := time.NewTicker(a.multilineTimeout / 2)
+		defer ticker.Stop()
+		expire = ticker.C
+	}
+
+	var summarize <-chan time.Time
+	if !a.rateLimitDrop {
+		ticker := time.NewTicker(rateLimitInterval)
+		defer ticker.Stop()
+		summarize = ticker.C
+	}
+
+	var check <-chan time.Time
+	slow := slowConsumer{interval: a.slowConsumerInterval, capacity: 2 * cap(events)}
+	if a.slowConsumerInterval > 0 {
+		ticker := time.NewTicker(a.slowConsumerInterval)
+		defer ticker.Stop()
+		check = ticker.C
+	}
+
+	var dedupExpire <-chan time.Time
+	if a.dedupWindow > 0 {
+		ticker := time.NewTicker(a.dedupWindow / 2)
+		defer ticker.Stop()
+		dedupExpire = ticker.C
+	}
+
+	sweep := time.NewTicker(containerCacheSweep)
+	defer sweep.Stop()
+
+	lines := lineJoiner{maxLines: a.multilineMaxLines, partialLines: a.partialLines}
+
+	// Containers are backfilled in the background, and their history is
+	// joined on its own, so it does not mix with the lines coming in. Their
+	// lines are held back until then, so the history goes first.
+	backfilled := make(map[string]bool)
+	held := make(map[string][]*router.Message)
+	release := func(id string) {
+		for _, m := range held[id] {
+			lines.add(m, a.containerMeta(m.Container).multiline, emit)
+		}
+		delete(held, id)
+	}
+	history := make(chan backfillResult)
+	done := make(chan struct{})
+	defer close(done)
+
+	for {
+		select {
+		case m, ok := <-logstream:
+			if !ok {
+				for id := range held {
+					release(id)
+				}
+				lines.flush(emit)
+				dups.flush(ship)
+				if !a.rateLimitDrop {
+					a.summarizeSuppressed(time.Now(), report)
+				}
+				return
+			}
+			meta := a.containerMeta(m.Container)
+			if meta.excluded {
+				continue
+			}
+			if waiting, ok := held[m.Container.ID]; ok {
+				if len(waiting) < maxHeldLines {
+					held[m.Container.ID] = append(waiting, m)
+					continue
+				}
+				// The history takes too long to wait for.
+				release(m.Container.ID)
+			}
+			if a.history != nil && !backfilled[m.Container.ID] {
+				backfilled[m.Container.ID] = true
+				held[m.Container.ID] = []*router.Message{m}
+				go func(first *router.Message) {
+					select {
+					case history <- backfillResult{first.Container.ID, a.backfill(first)}:
+					case <-done:
+					}
+				}(m)
+				continue
+			}
+			lines.add(m, meta.multiline, emit)
+		case r := <-history:
+			old := lineJoiner{maxLines: a.multilineMaxLines, partialLines: a.partialLines}
+			for _, m := range r.messages {
+				old.add(m, a.containerMeta(m.Container).multiline, emit)
+			}
+			old.flush(emit)
+			release(r.container)
+		case now := <-expire:
+			lines.expire(now.Add(-a.multilineTimeout), emit)
+		case now := <-dedupExpire:
+			dups.expire(now, ship)
+		case now := <-check:
+			if m := slow.check(len(events)+written(), now); m != nil {
+				report(m)
+			}
+		case now := <-summarize:
+			a.summarizeSuppressed(now, report)
+		case <-sweep.C:
+			a.sweepContainers()
+		}
+	}
+}
+
+// serializeStage encodes every event as JSON. It closes docs once events is
+// closed. With more than one worker, events are spread over the workers by
+// container, so the messages of one container keep their order.
+func (a *LogstashAdapter) serializeStage(events <-chan *event, docs chan<- *document) {
+	defer close(docs)
+	if a.serializeWorkers <= 1 {
+		a.serializeWorker(events, docs)
+		return
+	}
+
+	queues := make([]chan *event, a.serializeWorkers)
+	var wg sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan *event, a.stageBuffer)
+		wg.Add(1)
+		go func(q <-chan *event) {
+			defer wg.Done()
+			a.serializeWorker(q, docs)
+		}(queues[i])
+	}
+
+	for e := range events {
+		queues[hashKey(e.message.Container.ID)%uint32(len(queues))] <- e
+	}
+
+	for _, q := range queues {
+		close(q)
+	}
+	wg.Wait()
+}
+
+func (a *LogstashAdapter) serializeWorker(events <-chan *event, docs chan<- *document) {
+	for e := range events {
+		start := time.Now()
+		d := documentPool.Get().(*document)
+		err := a.serialize(e, d)
+		countStage("serialize", 1, start, err)
+		*e = event{}
+		eventPool.Put(e)
+		if err != nil {
+			// Log error message and continue parsing next line, if marshalling fails
+			log.Println("logstash: could not marshal JSON:", err)
+			d.reset()
+			documentPool.Put(d)
+			continue
+		}
+		if d.Len() == 0 {
+			// The event was discarded.
+			d.reset()
+			documentPool.Put(d)
+			continue
+		}
+		docs <- d
+	}
+}
+
+// writeStage batches documents and writes them to the connection, until docs
+// is closed and the last batch is flushed. Documents are copied into the
+// batch arena and handed back to documentPool right away.
+func (a *LogstashAdapter) writeStage(docs <-chan *document) {
+	var flush <-chan time.Time
+	if a.batchSize > 1 {
+		ticker := time.NewTicker(a.flushInterval)
+		defer ticker.Stop()
+		flush = ticker.C
+	}
+
+	for {
+		select {
+		case d, ok := <-docs:
+			if !ok {
+				a.flush()
+				return
+			}
+			offset := len(a.arena)
+			a.arena = append(a.arena, d.Bytes()...)
+			start := offset
+			for _, end := range d.ends {
+				a.batch = append(a.batch, a.arena[start:offset+end:offset+end])
+				start = offset + end
+			}
+			a.batch = append(a.batch, a.arena[start:len(a.arena):len(a.arena)])
+			d.reset()
+			documentPool.Put(d)
+			if len(a.batch) >= a.batchSize {
+				a.flush()
+			}
+		case <-flush:
+			a.flush()
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package logstash
+
+import (
+	"encoding/json"
+	"expvar"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamPipelinePreservesOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:         new(router.Route),
+		conn:          conn,
+		batchSize:     4,
+		flushInterval: time.Hour,
+		stageBuffer:   2,
+	}
+
+	before := int64(0)
+	if written, ok := stageMetrics.Get("write.messages").(*expvar.Int); ok {
+		before = written.Value()
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{}}
+	logstream := make(chan *router.Message)
+	go func() {
+		for i := 0; i < 10; i++ {
+			logstream <- &router.Message{Container: &container, Data: strconv.Itoa(i)}
+		}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	assert.Len(lines, 10)
+	for i, line := range lines {
+		assert.Equal(strconv.Itoa(i), line["message"])
+	}
+
+	after := stageMetrics.Get("write.messages").(*expvar.Int).Value()
+	assert.Equal(int64(10), after-before)
+}
+
+func TestStreamSerializeWorkersPreserveContainerOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:            new(router.Route),
+		conn:             conn,
+		batchSize:        1,
+		flushInterval:    time.Hour,
+		stageBuffer:      8,
+		serializeWorkers: 4,
+	}
+
+	containers := make([]docker.Container, 8)
+	for i := range containers {
+		containers[i] = docker.Container{ID: "container" + strconv.Itoa(i), Config: &docker.Config{}}
+	}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		for i := 0; i < 400; i++ {
+			logstream <- &router.Message{Container: &containers[i%8], Data: strconv.Itoa(i)}
+		}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	assert.Len(lines, 400)
+	last := make(map[string]int)
+	for _, line := range lines {
+		id := line["docker"].(map[string]interface{})["id"].(string)
+		n, _ := strconv.Atoi(line["message"].(string))
+		if prev, ok := last[id]; ok {
+			assert.True(n > prev, "messages of %s out of order", id)
+		}
+		last[id] = n
+	}
+}
+
+func TestSerializeReusesDocuments(t *testing.T) {
+	assert := assert.New(t)
+
+	adapter := LogstashAdapter{}
+	container := docker.Container{ID: "ID", Config: &docker.Config{}}
+
+	d := documentPool.Get().(*document)
+	for _, data := range []string{`{"a":1}`, `{"b":2}`, `null`} {
+		d.Reset()
+		e := adapter.enrich(&router.Message{Container: &container, Data: data})
+		assert.Nil(adapter.serialize(e, d))
+
+		var out map[string]interface{}
+		assert.Nil(json.Unmarshal(d.Bytes(), &out))
+		switch data {
+		case `{"b":2}`:
+			// Keys of the previous message must not leak into this one.
+			assert.Nil(out["a"])
+			assert.Equal(float64(2), out["b"])
+		case `null`:
+			assert.Equal("null", out["message"])
+		}
+	}
+}
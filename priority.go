@@ -0,0 +1,66 @@
+package logstash
+
+import (
+	"log"
+	"strings"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// priority is how long the events of a container are kept when the
+// pipeline cannot keep up: low ones are shed first, high ones never.
+type priority int
+
+const (
+	priorityLow    priority = -1
+	priorityNormal priority = 0
+	priorityHigh   priority = 1
+)
+
+var priorityNames = map[string]priority{
+	"low":    priorityLow,
+	"normal": priorityNormal,
+	"high":   priorityHigh,
+}
+
+func (p priority) String() string {
+	switch p {
+	case priorityLow:
+		return "low"
+	case priorityHigh:
+		return "high"
+	}
+	return "normal"
+}
+
+// containerPriority returns the priority of a container, from the
+// LOGSTASH_PRIORITY environment variable or the logstash.priority label.
+func containerPriority(c *docker.Container) priority {
+	s := containerSetting(c, "LOGSTASH_PRIORITY", "logstash.priority")
+	if s == "" {
+		return priorityNormal
+	}
+	p, ok := priorityNames[strings.ToLower(s)]
+	if !ok {
+		log.Println("logstash: invalid LOGSTASH_PRIORITY of container", c.ID+":", s)
+		return priorityNormal
+	}
+	return p
+}
+
+// shed reports whether an event of priority p is dropped while queued of
+// capacity events wait to be serialized. Low events are dropped once the
+// queue is half full, normal ones once it is full, and high ones wait for
+// room.
+func shed(p priority, queued, capacity int) bool {
+	if capacity == 0 {
+		return false
+	}
+	switch p {
+	case priorityLow:
+		return queued*2 >= capacity
+	case priorityNormal:
+		return queued >= capacity
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package logstash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerPriority(t *testing.T) {
+	assert := assert.New(t)
+
+	for expected, c := range map[priority]*docker.Container{
+		priorityNormal: {Config: &docker.Config{Env: []string{"LOGSTASH_PRIORITY=urgent"}}},
+		priorityLow:    {Config: &docker.Config{Env: []string{"LOGSTASH_PRIORITY=low"}}},
+		priorityHigh:   {Config: &docker.Config{Labels: map[string]string{"logstash.priority": "High"}}},
+	} {
+		assert.Equal(expected, containerPriority(c))
+	}
+	assert.Equal(priorityNormal, containerPriority(&docker.Container{Config: &docker.Config{}}))
+}
+
+func TestShed(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.False(shed(priorityLow, 3, 8))
+	assert.True(shed(priorityLow, 4, 8))
+	assert.False(shed(priorityNormal, 7, 8))
+	assert.True(shed(priorityNormal, 8, 8))
+	assert.False(shed(priorityHigh, 8, 8))
+	assert.False(shed(priorityLow, 0, 0))
+}
+
+func TestEnrichStageSheds(t *testing.T) {
+	assert := assert.New(t)
+
+	adapter := LogstashAdapter{
+		route:      new(router.Route),
+		priorities: true,
+	}
+
+	low := docker.Container{ID: "low", Config: &docker.Config{Env: []string{"LOGSTASH_PRIORITY=low"}}}
+	normal := docker.Container{ID: "normal", Config: &docker.Config{}}
+	high := docker.Container{ID: "high", Config: &docker.Config{Labels: map[string]string{"logstash.priority": "high"}}}
+
+	logstream := make(chan *router.Message)
+	events := make(chan *event, 4)
+	go func() {
+		for _, m := range []*router.Message{
+			{Container: &high, Data: "audit"},
+			{Container: &low, Data: "debug 1"},
+			{Container: &low, Data: "debug 2"},
+			{Container: &low, Data: "debug 3", Source: "stderr"},
+			{Container: &normal, Data: "info 1"},
+			{Container: &normal, Data: "info 2"},
+			{Container: &low, Data: "debug 4", Source: "stderr"},
+		} {
+			m.Time = time.Now()
+			logstream <- m
+		}
+		close(logstream)
+	}()
+
+	adapter.enrichStage(logstream, events, func() int { return 0 })
+
+	var shipped []string
+	for e := range events {
+		shipped = append(shipped, e.message.Data)
+	}
+	assert.Equal([]string{"audit", "debug 1", "debug 3", "info 1"}, shipped)
+}
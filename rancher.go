@@ -0,0 +1,46 @@
+package logstash
+
+import (
+	"strings"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// RancherData describes the Rancher 1.x (Cattle) stack and service a
+// container belongs to.
+type RancherData struct {
+	Stack           string `json:"stack,omitempty"`
+	Service         string `json:"service,omitempty"`
+	ContainerName   string `json:"container_name,omitempty"`
+	EnvironmentUUID string `json:"environment_uuid,omitempty"`
+}
+
+// GetRancherData returns the Rancher information found in the io.rancher.*
+// container labels, or nil if the container is not managed by Rancher.
+func GetRancherData(c *docker.Container) *RancherData {
+	labels := c.Config.Labels
+	d := RancherData{
+		Stack:           labels["io.rancher.stack.name"],
+		ContainerName:   labels["io.rancher.container.name"],
+		EnvironmentUUID: labels["io.rancher.environment.uuid"],
+	}
+	// The service label is qualified with the stack, as in "stack/service".
+	if service := labels["io.rancher.stack_service.name"]; service != "" {
+		d.Service = service[strings.LastIndex(service, "/")+1:]
+	}
+	if d == (RancherData{}) {
+		return nil
+	}
+	return &d
+}
+
+// appendJSON appends the JSON encoding of d to dst.
+func (d *RancherData) appendJSON(dst []byte) []byte {
+	dst = append(dst, '{')
+	n := len(dst)
+	dst = appendJSONStringField(dst, n, "stack", d.Stack)
+	dst = appendJSONStringField(dst, n, "service", d.Service)
+	dst = appendJSONStringField(dst, n, "container_name", d.ContainerName)
+	dst = appendJSONStringField(dst, n, "environment_uuid", d.EnvironmentUUID)
+	return append(dst, '}')
+}
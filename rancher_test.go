@@ -0,0 +1,28 @@
+package logstash
+
+import (
+	"testing"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetRancherData(t *testing.T) {
+	assert := assert.New(t)
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Labels: map[string]string{
+		"io.rancher.stack.name":         "shop",
+		"io.rancher.stack_service.name": "shop/web",
+		"io.rancher.container.name":     "shop-web-1",
+		"io.rancher.environment.uuid":   "adminProject",
+	}}}
+	assert.Equal(&RancherData{
+		Stack:           "shop",
+		Service:         "web",
+		ContainerName:   "shop-web-1",
+		EnvironmentUUID: "adminProject",
+	}, GetRancherData(&container))
+
+	container.Config.Labels = map[string]string{"com.docker.compose.project": "shop"}
+	assert.Nil(GetRancherData(&container))
+}
@@ -0,0 +1,104 @@
+package logstash
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+)
+
+// rateLimitSource is the stream of the messages that report the events a
+// rate limit suppressed.
+const rateLimitSource = "rate_limit"
+
+// rateLimitInterval is how often the suppressed events of containers are
+// reported.
+const rateLimitInterval = time.Minute
+
+// rateLimiter is a token bucket that limits the events of a container to
+// rate per second, with bursts of up to burst events.
+type rateLimiter struct {
+	rate, burst float64
+	tokens      float64
+	last        time.Time
+	// suppressed counts the events left out since they were last reported.
+	suppressed int
+	container  *docker.Container
+}
+
+// containerRateLimiter returns the rate limiter of a container, from the
+// LOGSTASH_RATE_LIMIT and LOGSTASH_RATE_BURST environment variables or the
+// logstash.rate_limit and logstash.rate_burst labels, or else the limits of
+// the adapter. It returns nil if the events of the container are not
+// limited.
+func (a *LogstashAdapter) containerRateLimiter(c *docker.Container) *rateLimiter {
+	rate, burst := a.rateLimit, a.rateBurst
+	if s := containerSetting(c, "LOGSTASH_RATE_LIMIT", "logstash.rate_limit"); s != "" {
+		r, err := strconv.ParseFloat(s, 64)
+		if err != nil || r < 0 || math.IsInf(r, 0) || math.IsNaN(r) {
+			log.Println("logstash: invalid LOGSTASH_RATE_LIMIT of container", c.ID+":", s)
+		} else {
+			rate, burst = r, 0
+		}
+	}
+	if s := containerSetting(c, "LOGSTASH_RATE_BURST", "logstash.rate_burst"); s != "" {
+		b, err := strconv.Atoi(s)
+		if err != nil || b < 1 {
+			log.Println("logstash: invalid LOGSTASH_RATE_BURST of container", c.ID+":", s)
+		} else {
+			burst = b
+		}
+	}
+	if rate <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = int(math.Max(1, math.Ceil(rate)))
+	}
+	return &rateLimiter{rate: rate, burst: float64(burst), tokens: float64(burst), container: c}
+}
+
+// allow reports whether an event at now is within the limit, taking a token
+// for it if so, or else counts it as suppressed.
+func (l *rateLimiter) allow(now time.Time) bool {
+	if !l.last.IsZero() {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return true
+	}
+	l.suppressed++
+	droppedEvents.Add(rateLimitSource, 1)
+	return false
+}
+
+// carry takes over the tokens and suppressed events of the limiter of an
+// earlier run of the container, so restarts do not reset the limit.
+func (l *rateLimiter) carry(old *rateLimiter) {
+	l.tokens = math.Min(l.burst, old.tokens)
+	l.last = old.last
+	l.suppressed = old.suppressed
+}
+
+// summarizeSuppressed emits a message for every container with events
+// suppressed since the last call, telling how many.
+func (a *LogstashAdapter) summarizeSuppressed(now time.Time, emit func(*router.Message)) {
+	for _, meta := range a.containers {
+		l := meta.limit
+		if l == nil || l.suppressed == 0 {
+			continue
+		}
+		emit(&router.Message{
+			Container: l.container,
+			Source:    rateLimitSource,
+			Data:      "suppressed " + strconv.Itoa(l.suppressed) + " lines in the last minute",
+			Time:      now,
+		})
+		l.suppressed = 0
+	}
+}
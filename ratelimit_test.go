@@ -0,0 +1,98 @@
+package logstash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerRateLimiter(t *testing.T) {
+	assert := assert.New(t)
+
+	adapter := &LogstashAdapter{rateLimit: 100, rateBurst: 500}
+	for _, c := range []struct {
+		env         []string
+		labels      map[string]string
+		rate, burst float64
+	}{
+		{nil, nil, 100, 500},
+		{[]string{"LOGSTASH_RATE_LIMIT=2.5"}, nil, 2.5, 3},
+		{nil, map[string]string{"logstash.rate_limit": "10", "logstash.rate_burst": "50"}, 10, 50},
+		{[]string{"LOGSTASH_RATE_BURST=1000"}, nil, 100, 1000},
+		{[]string{"LOGSTASH_RATE_LIMIT=fast", "LOGSTASH_RATE_BURST=-1"}, nil, 100, 500},
+	} {
+		l := adapter.containerRateLimiter(&docker.Container{Config: &docker.Config{Env: c.env, Labels: c.labels}})
+		if assert.NotNil(l, c.env) {
+			assert.Equal(c.rate, l.rate, c.env)
+			assert.Equal(c.burst, l.burst, c.env)
+			assert.Equal(c.burst, l.tokens, c.env)
+		}
+	}
+
+	assert.Nil(adapter.containerRateLimiter(&docker.Container{Config: &docker.Config{Env: []string{"LOGSTASH_RATE_LIMIT=0"}}}))
+	assert.Nil((&LogstashAdapter{}).containerRateLimiter(&docker.Container{Config: &docker.Config{}}))
+}
+
+func TestRateLimiterAllow(t *testing.T) {
+	assert := assert.New(t)
+
+	l := &rateLimiter{rate: 2, burst: 3, tokens: 3}
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.True(l.allow(now))
+	}
+	assert.False(l.allow(now))
+	assert.False(l.allow(now.Add(400 * time.Millisecond)))
+	assert.True(l.allow(now.Add(500 * time.Millisecond)))
+	assert.False(l.allow(now.Add(500 * time.Millisecond)))
+	assert.Equal(3, l.suppressed)
+
+	// Tokens do not pile up beyond the burst.
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		assert.True(l.allow(now))
+	}
+	assert.False(l.allow(now))
+}
+
+func TestStreamWithRateLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:     new(router.Route),
+		conn:      conn,
+		rateLimit: 0.001,
+		rateBurst: 2,
+	}
+
+	noisy := docker.Container{ID: "noisy", Config: &docker.Config{}}
+	free := docker.Container{ID: "free", Config: &docker.Config{Env: []string{"LOGSTASH_RATE_LIMIT=0"}}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		for i := 0; i < 5; i++ {
+			logstream <- &router.Message{Container: &noisy, Data: "retrying", Time: time.Now()}
+			logstream <- &router.Message{Container: &free, Data: "ok", Time: time.Now()}
+		}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	var noisyLines []string
+	freeLines := 0
+	for _, line := range conn.Lines() {
+		switch line["docker"].(map[string]interface{})["id"] {
+		case "noisy":
+			noisyLines = append(noisyLines, line["stream"].(string)+": "+line["message"].(string))
+		case "free":
+			freeLines++
+		}
+	}
+	assert.Equal(5, freeLines)
+	assert.Equal([]string{": retrying", ": retrying", "rate_limit: suppressed 3 lines in the last minute"}, noisyLines)
+}
@@ -0,0 +1,116 @@
+package logstash
+
+import (
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// redactor finds sensitive data in lines, such as card numbers or secrets.
+type redactor struct {
+	pattern *regexp.Regexp
+	// valid, if set, rules out matches that are not what is looked for,
+	// such as numbers that fail the checksum of card numbers.
+	valid func(match string) bool
+}
+
+// redactors are the built-in redactors, selected by name.
+var redactors = map[string]*redactor{
+	"credit_card": {
+		pattern: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		valid:   luhn,
+	},
+	"email": {
+		pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+	},
+	"bearer": {
+		pattern: regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]+=*`),
+	},
+}
+
+// luhn reports whether the digits of s pass the Luhn checksum of card
+// numbers. Other characters are skipped.
+func luhn(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] < '0' || s[i] > '9' {
+			continue
+		}
+		d := int(s[i] - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// containerRedactors returns the redactors of a container: the built-in
+// ones the adapter and the container select with LOGSTASH_REDACT or the
+// logstash.redact label, then the regular expressions of its
+// logstash.redact_pattern label and LOGSTASH_REDACT_PATTERN environment
+// variable, as well as any LOGSTASH_REDACT_PATTERN_ variables. Unknown names
+// and invalid expressions are logged and left out.
+func (a *LogstashAdapter) containerRedactors(c *docker.Container) []*redactor {
+	names := append([]string(nil), a.redact...)
+	names = append(names, splitList(containerSetting(c, "LOGSTASH_REDACT", "logstash.redact"))...)
+
+	var rs []*redactor
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if r, ok := redactors[name]; ok {
+			rs = append(rs, r)
+		} else {
+			log.Println("logstash: unknown LOGSTASH_REDACT of container", c.ID+":", name)
+		}
+	}
+
+	var exprs []string
+	if expr := c.Config.Labels["logstash.redact_pattern"]; expr != "" {
+		exprs = append(exprs, expr)
+	}
+	for _, e := range c.Config.Env {
+		if strings.HasPrefix(e, "LOGSTASH_REDACT_PATTERN=") || strings.HasPrefix(e, "LOGSTASH_REDACT_PATTERN_") {
+			if i := strings.IndexByte(e, '='); i >= 0 && i < len(e)-1 {
+				exprs = append(exprs, e[i+1:])
+			}
+		}
+	}
+
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			log.Println("logstash: invalid LOGSTASH_REDACT_PATTERN of container", c.ID+":", err)
+			continue
+		}
+		rs = append(rs, &redactor{pattern: re})
+	}
+	return rs
+}
+
+// redact replaces what the redactors find in line with mask.
+func redact(line string, rs []*redactor, mask string) string {
+	for _, r := range rs {
+		if r.valid == nil {
+			line = r.pattern.ReplaceAllLiteralString(line, mask)
+			continue
+		}
+		line = r.pattern.ReplaceAllStringFunc(line, func(match string) string {
+			if r.valid(match) {
+				return mask
+			}
+			return match
+		})
+	}
+	return line
+}
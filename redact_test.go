@@ -0,0 +1,77 @@
+package logstash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	assert := assert.New(t)
+
+	all := []*redactor{redactors["credit_card"], redactors["email"], redactors["bearer"]}
+	for line, expected := range map[string]string{
+		"paid with 4111 1111 1111 1111 today":       "paid with * today",
+		"card 4111-1111-1111-1111":                  "card *",
+		"order 4111111111111112 shipped":            "order 4111111111111112 shipped",
+		"mail jane.doe+test@mail.example.com now":   "mail * now",
+		"Authorization: Bearer eyJhbGciOi.eyJzdW.x": "Authorization: *",
+		`{"user":"jane@example.com","id":42}`:       `{"user":"*","id":42}`,
+		"nothing to hide":                           "nothing to hide",
+	} {
+		assert.Equal(expected, redact(line, all, "*"), line)
+	}
+}
+
+func TestContainerRedactors(t *testing.T) {
+	assert := assert.New(t)
+
+	adapter := LogstashAdapter{redact: []string{"email"}}
+	c := &docker.Container{Config: &docker.Config{
+		Env:    []string{"LOGSTASH_REDACT=EMAIL,bearer,unknown", "LOGSTASH_REDACT_PATTERN=password=\\S+", "LOGSTASH_REDACT_PATTERN_2=("},
+		Labels: map[string]string{"logstash.redact_pattern": "secret"},
+	}}
+
+	rs := adapter.containerRedactors(c)
+	if assert.Len(rs, 4) {
+		assert.Equal(redactors["email"], rs[0])
+		assert.Equal(redactors["bearer"], rs[1])
+		assert.Equal("secret", rs[2].pattern.String())
+		assert.Equal(`password=\S+`, rs[3].pattern.String())
+	}
+	assert.Empty((&LogstashAdapter{}).containerRedactors(&docker.Container{Config: &docker.Config{}}))
+}
+
+func TestStreamWithRedaction(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:           new(router.Route),
+		conn:            conn,
+		redact:          []string{"email"},
+		redactMask:      "[REDACTED]",
+		rawMessageField: "raw",
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Env: []string{"LOGSTASH_REDACT_PATTERN=token=\\w+"}}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: "login jane@example.com token=abc123", Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `{"user":"jane@example.com"}`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 2) {
+		assert.Equal("login [REDACTED] [REDACTED]", lines[0]["message"])
+		assert.Equal("[REDACTED]", lines[1]["user"])
+		assert.Equal(`{"user":"[REDACTED]"}`, lines[1]["raw"])
+	}
+}
@@ -0,0 +1,178 @@
+package logstash
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+)
+
+// messageRoute sends the messages it matches to an adapter of their own,
+// with its own connection to another Logstash.
+type messageRoute struct {
+	match   func(m *router.Message) bool
+	adapter *LogstashAdapter
+}
+
+// routeRule sends the containers a selector matches to address.
+type routeRule struct {
+	terms   []labelTerm
+	address string
+}
+
+// parseRoutes parses routing rules separated by semicolons, each a selector
+// and the address of the Logstash that the containers it matches ship to,
+// such as team=payments=>payments.example.com:5000. Selectors are label
+// selectors, whose terms can also be tags written tag:name or !tag:name.
+func parseRoutes(s string) ([]routeRule, error) {
+	var rules []routeRule
+	for _, rule := range strings.Split(s, ";") {
+		if strings.TrimSpace(rule) == "" {
+			continue
+		}
+		i := strings.LastIndex(rule, "=>")
+		if i < 0 || strings.TrimSpace(rule[i+2:]) == "" {
+			return nil, errors.New("invalid route " + rule)
+		}
+		terms, err := parseLabelSelector(rule[:i])
+		if err != nil {
+			return nil, err
+		}
+		if len(terms) == 0 {
+			return nil, errors.New("invalid route " + rule)
+		}
+		for _, t := range terms {
+			if strings.HasPrefix(t.key, "tag:") && (t.hasValue || t.key == "tag:") {
+				return nil, errors.New("invalid tag in route " + rule)
+			}
+		}
+		rules = append(rules, routeRule{terms: terms, address: strings.TrimSpace(rule[i+2:])})
+	}
+	return rules, nil
+}
+
+// containerRoute returns a match of the messages of containers whose labels
+// and tags satisfy all terms. What it finds for a container is kept while
+// the container logs, as both are fixed when the container is created.
+func (a *LogstashAdapter) containerRoute(terms []labelTerm) func(m *router.Message) bool {
+	var matched containerFlags
+	return func(m *router.Message) bool {
+		return matched.get(m.Container.ID, func() bool { return a.routeMatches(terms, m.Container) })
+	}
+}
+
+// routeMatches reports whether the labels and tags of c satisfy all terms.
+func (a *LogstashAdapter) routeMatches(terms []labelTerm, c *docker.Container) bool {
+	var tags []string
+	for _, t := range terms {
+		if !strings.HasPrefix(t.key, "tag:") {
+			if !t.matches(c.Config.Labels) {
+				return false
+			}
+			continue
+		}
+		if tags == nil {
+			tags = a.containerTags(c)
+		}
+		found := false
+		for _, tag := range tags {
+			if tag == t.key[len("tag:"):] {
+				found = true
+				break
+			}
+		}
+		if found == t.not {
+			return false
+		}
+	}
+	return true
+}
+
+// isStderr matches the messages written to stderr.
+func isStderr(m *router.Message) bool {
+	return m.Source == "stderr"
+}
+
+// setStderrType gives an event written to stderr the stderr type, in the
+// type field or wherever else the adapter puts types.
+func (a *LogstashAdapter) setStderrType(e *event) {
+	if a.typeField.key == "" {
+		e.logType = a.stderrType
+		return
+	}
+	fields := &e.fields
+	if a.typeField.metadata {
+		fields = &e.metadata
+	}
+	// The map is shared by all events of the container.
+	copied := make(map[string]string, len(*fields)+1)
+	for k, v := range *fields {
+		copied[k] = v
+	}
+	copied[a.typeField.key] = a.stderrType
+	*fields = copied
+}
+
+// routeTo returns a copy of the adapter that ships to address instead.
+func (a *LogstashAdapter) routeTo(address string) (*LogstashAdapter, error) {
+	conn, err := a.transport.Dial(address, a.route.Options)
+	if err != nil {
+		return nil, err
+	}
+	child := a.withConn(conn)
+	child.events = nil
+	child.routes = nil
+	return child, nil
+}
+
+// closeRoutes closes the connections of the routes.
+func (a *LogstashAdapter) closeRoutes() {
+	for _, r := range a.routes {
+		r.adapter.conn.Close()
+	}
+	a.routes = nil
+}
+
+// streamRoutes sends every message to the adapter of the first route that
+// matches it, or else on to the adapter's own connections. Each adapter
+// streams in a goroutine of its own.
+func (a *LogstashAdapter) streamRoutes(logstream chan *router.Message) {
+	var wg sync.WaitGroup
+	stream := func(adapter *LogstashAdapter) chan *router.Message {
+		q := make(chan *router.Message, a.queueSize)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			adapter.Stream(q)
+		}()
+		return q
+	}
+
+	queues := make([]chan *router.Message, len(a.routes))
+	for i, r := range a.routes {
+		queues[i] = stream(r.adapter)
+	}
+	rest := *a
+	rest.events = nil
+	rest.routes = nil
+	fallback := stream(&rest)
+
+	for m := range logstream {
+		q := fallback
+		for i, r := range a.routes {
+			if r.match(m) {
+				q = queues[i]
+				break
+			}
+		}
+		q <- m
+	}
+
+	for _, q := range queues {
+		close(q)
+	}
+	close(fallback)
+	wg.Wait()
+}
@@ -0,0 +1,204 @@
+package logstash
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamWithStderrRoute(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	errConn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:      new(router.Route),
+		conn:       conn,
+		queueSize:  16,
+		stderrTag:  "stderr",
+		stderrType: "error",
+	}
+	stderr := adapter
+	stderr.conn = errConn
+	adapter.routes = []messageRoute{{match: isStderr, adapter: &stderr}}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Env: []string{"LOGSTASH_TYPE=access"}}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: "GET / 200", Source: "stdout", Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: "upstream timed out", Source: "stderr", Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: "GET /cart 200", Source: "stdout", Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 2) {
+		assert.Equal("GET / 200", lines[0]["message"])
+		assert.Equal("access", lines[0]["type"])
+		assert.Equal("GET /cart 200", lines[1]["message"])
+	}
+	errLines := errConn.Lines()
+	if assert.Len(errLines, 1) {
+		assert.Equal("upstream timed out", errLines[0]["message"])
+		assert.Equal("error", errLines[0]["type"])
+		assert.Equal([]interface{}{"stderr"}, errLines[0]["tags"])
+	}
+}
+
+func TestSetStderrType(t *testing.T) {
+	assert := assert.New(t)
+
+	shared := map[string]string{"type": "access", "index": "logs"}
+	adapter := &LogstashAdapter{stderrType: "error", typeField: hintField{key: "type", metadata: true}}
+	e := &event{metadata: shared}
+	adapter.setStderrType(e)
+	assert.Equal(map[string]string{"type": "error", "index": "logs"}, e.metadata)
+	assert.Equal("access", shared["type"])
+
+	adapter.typeField = hintField{}
+	adapter.setStderrType(e)
+	assert.Equal("error", e.logType)
+}
+
+func TestParseRoutes(t *testing.T) {
+	assert := assert.New(t)
+
+	rules, err := parseRoutes("team=payments,!canary=>payments.example.com:5000; tag:audit => audit.example.com:5000;")
+	if assert.Nil(err) && assert.Len(rules, 2) {
+		assert.Equal([]labelTerm{{key: "team", value: "payments", hasValue: true}, {key: "canary", not: true}}, rules[0].terms)
+		assert.Equal("payments.example.com:5000", rules[0].address)
+		assert.Equal([]labelTerm{{key: "tag:audit"}}, rules[1].terms)
+		assert.Equal("audit.example.com:5000", rules[1].address)
+	}
+
+	rules, err = parseRoutes("")
+	assert.Nil(err)
+	assert.Empty(rules)
+
+	for _, s := range []string{"team=payments", "=>logstash:5000", "team=payments=>", "tag:audit=yes=>logstash:5000", "tag:=>logstash:5000"} {
+		_, err := parseRoutes(s)
+		assert.NotNil(err, s)
+	}
+}
+
+func TestStreamWithRoutes(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	paymentsConn := &BufferConn{}
+	auditConn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:     new(router.Route),
+		conn:      conn,
+		queueSize: 16,
+	}
+	payments, audit := adapter, adapter
+	payments.conn = paymentsConn
+	audit.conn = auditConn
+	rules, err := parseRoutes("team=payments=>payments:5000;!tag:public,tag:audit=>audit:5000")
+	if !assert.Nil(err) {
+		return
+	}
+	adapter.routes = []messageRoute{
+		{match: adapter.containerRoute(rules[0].terms), adapter: &payments},
+		{match: adapter.containerRoute(rules[1].terms), adapter: &audit},
+	}
+
+	checkout := docker.Container{ID: "checkout", Config: &docker.Config{Labels: map[string]string{"team": "payments"}}}
+	login := docker.Container{ID: "login", Config: &docker.Config{Env: []string{"LOGSTASH_TAGS=audit"}}}
+	landing := docker.Container{ID: "landing", Config: &docker.Config{Env: []string{"LOGSTASH_TAGS=audit,public"}}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		for _, c := range []*docker.Container{&checkout, &login, &landing, &checkout} {
+			logstream <- &router.Message{Container: c, Data: c.ID, Time: time.Now()}
+		}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	messages := func(conn *BufferConn) []interface{} {
+		var messages []interface{}
+		for _, line := range conn.Lines() {
+			messages = append(messages, line["message"])
+		}
+		return messages
+	}
+	assert.Equal([]interface{}{"checkout", "checkout"}, messages(paymentsConn))
+	assert.Equal([]interface{}{"login"}, messages(auditConn))
+	assert.Equal([]interface{}{"landing"}, messages(conn))
+}
+
+// ClosingConn records whether it was closed.
+type ClosingConn struct {
+	BufferConn
+	closed bool
+}
+
+func (c *ClosingConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *ClosingConn) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// FlakyTransport connects a number of times, and then fails.
+type FlakyTransport struct {
+	mu    sync.Mutex
+	dials int
+	conns []*ClosingConn
+}
+
+func (t *FlakyTransport) Dial(addr string, options map[string]string) (net.Conn, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.conns) == t.dials {
+		return nil, errors.New("connection refused")
+	}
+	conn := &ClosingConn{}
+	t.conns = append(t.conns, conn)
+	return conn, nil
+}
+
+// opened returns the connections made so far.
+func (t *FlakyTransport) opened() []*ClosingConn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*ClosingConn(nil), t.conns...)
+}
+
+func TestNewAdapterClosesRoutes(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &FlakyTransport{dials: 2}
+	router.AdapterTransports.Register(transport, "flaky")
+
+	// The route connects, but the second endpoint does not.
+	_, err := NewLogstashAdapter(&router.Route{
+		Adapter: "logstash+flaky",
+		Address: "a:5000",
+		Options: map[string]string{"endpoints": "a:5000,b:5000", "stderr_address": "errors:5000"},
+	})
+	assert.NotNil(err)
+	if assert.Len(transport.conns, 2) {
+		for _, conn := range transport.conns {
+			assert.True(conn.isClosed())
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package logstash
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strings"
+)
+
+// fieldRule is a transformation of the fields of every document: copy or
+// move the value at from to to, rename the key at from to the key to, or
+// delete the value at path.
+type fieldRule struct {
+	op       string
+	from, to []string
+	path     []string
+}
+
+// loadRules reads a JSON array of rules from a file, such as
+//
+//	[{"op": "move", "from": "$.docker.labels['com.example.team']", "to": "team"},
+//	 {"op": "delete", "path": "docker.hostname"}]
+func loadRules(path string) ([]fieldRule, error) {
+	js, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var specs []struct {
+		Op, From, To, Path string
+	}
+	if err := json.Unmarshal(js, &specs); err != nil {
+		return nil, err
+	}
+
+	rules := make([]fieldRule, len(specs))
+	for i, spec := range specs {
+		rule := &rules[i]
+		rule.op = strings.ToLower(spec.Op)
+		switch rule.op {
+		case "copy", "move":
+			if rule.from, err = parsePath(spec.From); err == nil {
+				rule.to, err = parsePath(spec.To)
+			}
+			if err == nil && rule.op == "move" && within(rule.to, rule.from) {
+				err = errors.New("cannot move " + spec.From + " into itself: " + spec.To)
+			}
+		case "rename":
+			if rule.from, err = parsePath(spec.From); err == nil && (spec.To == "" || strings.ContainsAny(spec.To, ".[]$")) {
+				err = errors.New("invalid key to rename " + spec.From + " to: " + spec.To)
+			}
+			rule.to = []string{spec.To}
+		case "delete":
+			rule.path, err = parsePath(spec.Path)
+		default:
+			err = errors.New("unknown op " + spec.Op)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+// parsePath parses a path expression: keys separated by dots, such as
+// docker.name, optionally starting with the $ of JSONPath. Keys that contain
+// dots are quoted in brackets, as in docker.labels['com.example.team'].
+func parsePath(s string) ([]string, error) {
+	rest := strings.TrimPrefix(strings.TrimPrefix(s, "$"), ".")
+	var path []string
+	for rest != "" {
+		if rest[0] == '[' {
+			if len(rest) < 4 || (rest[1] != '\'' && rest[1] != '"') {
+				return nil, errors.New("invalid path " + s)
+			}
+			end := strings.Index(rest[2:], string(rest[1])+"]")
+			if end < 0 {
+				return nil, errors.New("invalid path " + s)
+			}
+			path = append(path, rest[2:2+end])
+			rest = rest[2+end+2:]
+		} else {
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, errors.New("invalid path " + s)
+			}
+			path = append(path, rest[:end])
+			rest = rest[end:]
+		}
+		if strings.HasPrefix(rest, ".") {
+			if rest = rest[1:]; rest == "" {
+				return nil, errors.New("invalid path " + s)
+			}
+		} else if rest != "" && rest[0] != '[' {
+			return nil, errors.New("invalid path " + s)
+		}
+	}
+	if len(path) == 0 {
+		return nil, errors.New("invalid path " + s)
+	}
+	return path, nil
+}
+
+// within reports whether path is below parent in the document.
+func within(path, parent []string) bool {
+	if len(path) <= len(parent) {
+		return false
+	}
+	for i, key := range parent {
+		if path[i] != key {
+			return false
+		}
+	}
+	return true
+}
+
+// applyRules applies rules to a document in order. Rules whose source is
+// missing are skipped; copies and moves replace their target.
+func applyRules(data map[string]interface{}, rules []fieldRule) {
+	for _, r := range rules {
+		if r.op == "delete" {
+			dropFields(data, [][]string{r.path})
+			continue
+		}
+
+		from, ok := object(data, r.from[:len(r.from)-1], false)
+		if !ok {
+			continue
+		}
+		key := r.from[len(r.from)-1]
+		v, ok := from[key]
+		if !ok {
+			continue
+		}
+
+		switch r.op {
+		case "rename":
+			delete(from, key)
+			from[r.to[0]] = v
+		case "copy", "move":
+			to, ok := object(data, r.to[:len(r.to)-1], true)
+			if !ok {
+				continue
+			}
+			if r.op == "move" {
+				delete(from, key)
+			} else {
+				v = copyValue(v)
+			}
+			to[r.to[len(r.to)-1]] = v
+		}
+	}
+}
+
+// copyValue returns a copy of v that can be changed without changing v.
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for k, elem := range v {
+			obj[k] = copyValue(elem)
+		}
+		return obj
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, elem := range v {
+			values[i] = copyValue(elem)
+		}
+		return values
+	}
+	return v
+}
@@ -0,0 +1,134 @@
+package logstash
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePath(t *testing.T) {
+	assert := assert.New(t)
+
+	for s, expected := range map[string][]string{
+		"message":                            {"message"},
+		"docker.name":                        {"docker", "name"},
+		"$.docker.name":                      {"docker", "name"},
+		"$.docker.labels['com.example.app']": {"docker", "labels", "com.example.app"},
+		`labels["a.b"].c`:                    {"labels", "a.b", "c"},
+		"['@metadata']":                      {"@metadata"},
+	} {
+		path, err := parsePath(s)
+		assert.Nil(err, s)
+		assert.Equal(expected, path, s)
+	}
+
+	for _, s := range []string{"", "$", "a.", ".", "a..b", "a['b'", "a[b]", "a['b']c", "a[0]"} {
+		_, err := parsePath(s)
+		assert.NotNil(err, s)
+	}
+}
+
+func TestApplyRules(t *testing.T) {
+	assert := assert.New(t)
+
+	rules := []fieldRule{
+		{op: "copy", from: []string{"docker", "labels", "team"}, to: []string{"team"}},
+		{op: "move", from: []string{"docker", "image"}, to: []string{"container", "image"}},
+		{op: "rename", from: []string{"level"}, to: []string{"severity"}},
+		{op: "delete", path: []string{"docker", "hostname"}},
+		{op: "move", from: []string{"missing"}, to: []string{"level"}},
+		{op: "copy", from: []string{"request"}, to: []string{"copied"}},
+	}
+	data := map[string]interface{}{
+		"level":   "info",
+		"request": map[string]interface{}{"path": "/"},
+		"docker":  DockerInfo{Name: "/web", Image: "nginx", Hostname: "web", Labels: map[string]string{"team": "payments"}},
+	}
+	applyRules(data, rules)
+	data["copied"].(map[string]interface{})["path"] = "/changed"
+
+	assert.Equal(map[string]interface{}{
+		"severity":  "info",
+		"team":      "payments",
+		"container": map[string]interface{}{"image": "nginx"},
+		"docker":    map[string]interface{}{"name": "/web", "id": "", "labels": map[string]interface{}{"team": "payments"}},
+		"request":   map[string]interface{}{"path": "/"},
+		"copied":    map[string]interface{}{"path": "/changed"},
+	}, data)
+}
+
+func TestLoadRules(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "rules")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rules.json")
+
+	assert.Nil(ioutil.WriteFile(path, []byte(`[{"op":"Move","from":"$.docker.name","to":"container"},{"op":"delete","path":"stream"}]`), 0644))
+	rules, err := loadRules(path)
+	assert.Nil(err)
+	assert.Equal([]fieldRule{
+		{op: "move", from: []string{"docker", "name"}, to: []string{"container"}},
+		{op: "delete", path: []string{"stream"}},
+	}, rules)
+
+	for _, js := range []string{
+		`{"op":"delete","path":"stream"}`,
+		`[{"op":"upsert","path":"stream"}]`,
+		`[{"op":"rename","from":"level","to":"a.b"}]`,
+		`[{"op":"copy","from":"level"}]`,
+		`[{"op":"delete"}]`,
+		`[{"op":"move","from":"a.b","to":"a.b.c"}]`,
+		`[{"op":"move","from":"a","to":"$['a'].b"}]`,
+	} {
+		assert.Nil(ioutil.WriteFile(path, []byte(js), 0644))
+		_, err := loadRules(path)
+		assert.NotNil(err, js)
+	}
+
+	// Copies of a path into itself and moves next to it are fine.
+	assert.Nil(ioutil.WriteFile(path, []byte(`[{"op":"copy","from":"a.b","to":"a.b.c"},{"op":"move","from":"a.b","to":"a.bc"}]`), 0644))
+	_, err = loadRules(path)
+	assert.Nil(err)
+
+	_, err = loadRules(filepath.Join(dir, "missing.json"))
+	assert.NotNil(err)
+}
+
+func TestStreamWithRules(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route: new(router.Route),
+		conn:  conn,
+		rules: []fieldRule{
+			{op: "move", from: []string{"docker", "name"}, to: []string{"container"}},
+			{op: "delete", path: []string{"docker"}},
+		},
+	}
+
+	container := docker.Container{ID: "ID", Name: "/web", Config: &docker.Config{}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: "started", Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 1) {
+		assert.Equal("started", lines[0]["message"])
+		assert.Equal("/web", lines[0]["container"])
+		assert.Nil(lines[0]["docker"])
+	}
+}
@@ -0,0 +1,35 @@
+package logstash
+
+import (
+	"log"
+	"math/rand"
+	"strconv"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// containerSampleRate returns N of a container that ships only 1 in N of its
+// events, from the LOGSTASH_SAMPLE_RATE environment variable or the
+// logstash.sample_rate label. It returns 0 if all events are shipped.
+func containerSampleRate(c *docker.Container) int {
+	s := containerSetting(c, "LOGSTASH_SAMPLE_RATE", "logstash.sample_rate")
+	if s == "" {
+		return 0
+	}
+	rate, err := strconv.Atoi(s)
+	if err != nil || rate < 1 {
+		log.Println("logstash: invalid LOGSTASH_SAMPLE_RATE of container", c.ID+":", s)
+		return 0
+	}
+	if rate == 1 {
+		return 0
+	}
+	return rate
+}
+
+// sampledOut reports whether an event of a container that ships 1 in rate
+// of its events is left out. Each event is kept with a probability of
+// 1/rate, so that bursts are sampled as evenly as steady streams.
+func sampledOut(rate int) bool {
+	return rate > 1 && rand.Intn(rate) != 0
+}
@@ -0,0 +1,74 @@
+package logstash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerSampleRate(t *testing.T) {
+	assert := assert.New(t)
+
+	for expected, c := range map[int]*docker.Container{
+		0:  {Config: &docker.Config{}},
+		10: {Config: &docker.Config{Env: []string{"LOGSTASH_SAMPLE_RATE=10"}}},
+		5:  {Config: &docker.Config{Labels: map[string]string{"logstash.sample_rate": "5"}}},
+	} {
+		assert.Equal(expected, containerSampleRate(c))
+	}
+	for _, rate := range []string{"1", "0", "-3", "0.5", "often"} {
+		assert.Equal(0, containerSampleRate(&docker.Container{Config: &docker.Config{Env: []string{"LOGSTASH_SAMPLE_RATE=" + rate}}}), rate)
+	}
+}
+
+func TestSampledOut(t *testing.T) {
+	assert := assert.New(t)
+
+	kept := 0
+	for i := 0; i < 10000; i++ {
+		assert.False(sampledOut(0))
+		if !sampledOut(4) {
+			kept++
+		}
+	}
+	assert.InDelta(2500, kept, 250)
+}
+
+func TestStreamWithSampling(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route: new(router.Route),
+		conn:  conn,
+	}
+
+	chatty := docker.Container{ID: "chatty", Config: &docker.Config{Env: []string{"LOGSTASH_SAMPLE_RATE=10"}}}
+	quiet := docker.Container{ID: "quiet", Config: &docker.Config{}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		for i := 0; i < 2000; i++ {
+			logstream <- &router.Message{Container: &chatty, Data: "debug", Time: time.Now()}
+		}
+		logstream <- &router.Message{Container: &quiet, Data: `{"msg":"started"}`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.InDelta(201, len(lines), 60) {
+		for _, line := range lines[:len(lines)-1] {
+			assert.Equal(true, line["sampled"])
+			assert.Equal(10.0, line["sample_rate"])
+		}
+		last := lines[len(lines)-1]
+		assert.Equal("started", last["msg"])
+		assert.Nil(last["sampled"])
+		assert.Nil(last["sample_rate"])
+	}
+}
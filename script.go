@@ -0,0 +1,180 @@
+package logstash
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"sort"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// scriptMaxSteps is the number of Starlark steps a script may take to load,
+// or to transform an event, so that one that loops forever cannot hold up
+// the events of every container.
+const scriptMaxSteps = 1000000
+
+// script is a Starlark transform function that events are passed through
+// before they are shipped.
+type script struct {
+	transform starlark.Callable
+}
+
+// loadScript loads the Starlark file at path, which must define a function
+// transform(event). The function gets every event as a dict, and returns the
+// event to ship, or None to discard it.
+func loadScript(path string) (*script, error) {
+	thread := &starlark.Thread{Name: "load"}
+	thread.SetMaxExecutionSteps(scriptMaxSteps)
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	globals.Freeze()
+
+	transform, ok := globals["transform"].(starlark.Callable)
+	if !ok {
+		return nil, errors.New(path + " does not define a transform function")
+	}
+	return &script{transform: transform}, nil
+}
+
+// run passes the event in d.data through the script. It reports false if the
+// script discards the event. Events the script fails on, or takes more than
+// scriptMaxSteps steps on, are shipped as they are.
+func (s *script) run(d *document) bool {
+	// The event holds structs of the adapter, go through JSON to get plain
+	// values.
+	js, err := json.Marshal(d.data)
+	if err != nil {
+		log.Println("logstash: could not pass event to script:", err)
+		return true
+	}
+	var event map[string]interface{}
+	if err := json.Unmarshal(js, &event); err != nil {
+		log.Println("logstash: could not pass event to script:", err)
+		return true
+	}
+
+	thread := &starlark.Thread{Name: "transform"}
+	thread.SetMaxExecutionSteps(scriptMaxSteps)
+	result, err := starlark.Call(thread, s.transform, starlark.Tuple{toStarlark(event)}, nil)
+	if err != nil && thread.ExecutionSteps() >= scriptMaxSteps {
+		log.Println("logstash: script took more than", scriptMaxSteps, "steps, shipping event untransformed")
+		return true
+	}
+	if err != nil {
+		log.Println("logstash: script failed:", err)
+		return true
+	}
+	if result == starlark.None {
+		return false
+	}
+	transformed, err := fromStarlark(result)
+	if err != nil {
+		log.Println("logstash: script returned an event that cannot be shipped:", err)
+		return true
+	}
+	data, ok := transformed.(map[string]interface{})
+	if !ok {
+		log.Println("logstash: script did not return a dict or None:", result.Type())
+		return true
+	}
+
+	for k := range d.data {
+		delete(d.data, k)
+	}
+	for k, v := range data {
+		d.data[k] = v
+	}
+	return true
+}
+
+// toStarlark converts a decoded JSON value to Starlark. Whole numbers become
+// ints, so that scripts can do arithmetic on counts and codes.
+func toStarlark(v interface{}) starlark.Value {
+	switch v := v.(type) {
+	case bool:
+		return starlark.Bool(v)
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return starlark.MakeInt64(int64(v))
+		}
+		return starlark.Float(v)
+	case string:
+		return starlark.String(v)
+	case []interface{}:
+		elems := make([]starlark.Value, len(v))
+		for i, elem := range v {
+			elems[i] = toStarlark(elem)
+		}
+		return starlark.NewList(elems)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		dict := starlark.NewDict(len(v))
+		for _, k := range keys {
+			dict.SetKey(starlark.String(k), toStarlark(v[k]))
+		}
+		return dict
+	}
+	return starlark.None
+}
+
+// fromStarlark converts a Starlark value back to one that encodes as JSON.
+func fromStarlark(v starlark.Value) (interface{}, error) {
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(v), nil
+	case starlark.Int:
+		if i, ok := v.Int64(); ok {
+			return i, nil
+		}
+		return float64(v.Float()), nil
+	case starlark.Float:
+		return float64(v), nil
+	case starlark.String:
+		return string(v), nil
+	case *starlark.List:
+		return fromStarlarkIterable(v, v.Len())
+	case starlark.Tuple:
+		return fromStarlarkIterable(v, v.Len())
+	case *starlark.Dict:
+		m := make(map[string]interface{}, v.Len())
+		for _, item := range v.Items() {
+			k, ok := item[0].(starlark.String)
+			if !ok {
+				return nil, errors.New("dict key is not a string: " + item[0].String())
+			}
+			value, err := fromStarlark(item[1])
+			if err != nil {
+				return nil, err
+			}
+			m[string(k)] = value
+		}
+		return m, nil
+	}
+	return nil, errors.New("cannot encode " + v.Type())
+}
+
+func fromStarlarkIterable(v starlark.Iterable, n int) (interface{}, error) {
+	values := make([]interface{}, 0, n)
+	iter := v.Iterate()
+	defer iter.Done()
+	var elem starlark.Value
+	for iter.Next(&elem) {
+		value, err := fromStarlark(elem)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
@@ -0,0 +1,133 @@
+package logstash
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeScript(t *testing.T, src string) string {
+	dir, err := ioutil.TempDir("", "script")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "transform.star")
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadScript(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := loadScript(writeScript(t, "def transform(event):\n    return event\n"))
+	assert.Nil(err)
+	_, err = loadScript(writeScript(t, "def other(event):\n    return event\n"))
+	assert.NotNil(err)
+	_, err = loadScript(writeScript(t, "def transform(event)\n"))
+	assert.NotNil(err)
+	_, err = loadScript("/nonexistent.star")
+	assert.NotNil(err)
+}
+
+func TestStreamWithScript(t *testing.T) {
+	assert := assert.New(t)
+
+	path := writeScript(t, `
+def transform(event):
+    if event["message"] == "healthcheck":
+        return None
+    if event["message"] == "broken":
+        return event["missing"]
+    event["container"] = event.pop("docker")["name"]
+    event["doubled"] = event.get("count", 0) * 2
+    event["tags"].append("scripted")
+    return event
+`)
+	transform, err := loadScript(path)
+	if !assert.Nil(err) {
+		return
+	}
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:  new(router.Route),
+		conn:   conn,
+		script: transform,
+	}
+
+	container := docker.Container{ID: "ID", Name: "/web", Config: &docker.Config{}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: "healthcheck", Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `{"message":"done","count":21}`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: "broken", Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 2) {
+		assert.Equal("done", lines[0]["message"])
+		assert.Equal("/web", lines[0]["container"])
+		assert.Nil(lines[0]["docker"])
+		assert.Equal(42.0, lines[0]["doubled"])
+		assert.Equal([]interface{}{"scripted"}, lines[0]["tags"])
+		// The script fails on the event, which is shipped as it is.
+		assert.Equal("broken", lines[1]["message"])
+		assert.NotNil(lines[1]["docker"])
+	}
+}
+
+func TestStreamWithLoopingScript(t *testing.T) {
+	assert := assert.New(t)
+
+	transform, err := loadScript(writeScript(t, `
+def transform(event):
+    if event["message"] == "loop":
+        for i in range(1 << 60):
+            pass
+    event["transformed"] = True
+    return event
+`))
+	if !assert.Nil(err) {
+		return
+	}
+	_, err = loadScript(writeScript(t, "for i in range(1 << 60):\n    pass\ndef transform(event):\n    return event\n"))
+	assert.NotNil(err)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:  new(router.Route),
+		conn:   conn,
+		script: transform,
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: "loop", Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: "next", Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 2) {
+		assert.Equal("loop", lines[0]["message"])
+		assert.Nil(lines[0]["transformed"])
+		assert.Equal(true, lines[1]["transformed"])
+	}
+}
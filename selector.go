@@ -0,0 +1,118 @@
+package logstash
+
+import (
+	"errors"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// containerFilter selects containers by name, image and labels.
+type containerFilter struct {
+	names  []string
+	image  *regexp.Regexp
+	labels []labelTerm
+}
+
+// labelTerm is a term of a label selector: a label that must, or with not
+// set must not, be present, with value if it is given.
+type labelTerm struct {
+	key, value string
+	hasValue   bool
+	not        bool
+}
+
+// parseGlobs parses a list of glob patterns such as web-*,api.
+func parseGlobs(list string) ([]string, error) {
+	globs := splitList(list)
+	for _, glob := range globs {
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, errors.New("invalid pattern " + glob)
+		}
+	}
+	return globs, nil
+}
+
+// parseLabelSelector parses a label selector in the style of Kubernetes,
+// such as app=web,tier!=cache,canary,!legacy.
+func parseLabelSelector(s string) ([]labelTerm, error) {
+	var terms []labelTerm
+	for _, term := range splitList(s) {
+		var t labelTerm
+		if i := strings.Index(term, "!="); i >= 0 {
+			t = labelTerm{key: term[:i], value: term[i+2:], hasValue: true, not: true}
+		} else if i := strings.IndexByte(term, '='); i >= 0 {
+			t = labelTerm{key: term[:i], value: term[i+1:], hasValue: true}
+		} else if strings.HasPrefix(term, "!") {
+			t = labelTerm{key: term[1:], not: true}
+		} else {
+			t = labelTerm{key: term}
+		}
+		if t.key = strings.TrimSpace(t.key); t.key == "" {
+			return nil, errors.New("invalid label selector " + term)
+		}
+		t.value = strings.TrimSpace(t.value)
+		terms = append(terms, t)
+	}
+	return terms, nil
+}
+
+// matches reports whether the labels of a container satisfy the term.
+func (t labelTerm) matches(labels map[string]string) bool {
+	value, ok := labels[t.key]
+	if t.hasValue {
+		ok = ok && value == t.value
+	}
+	return ok != t.not
+}
+
+// matchName reports whether the name of c matches one of the globs.
+func (f *containerFilter) matchName(c *docker.Container) bool {
+	name := strings.TrimPrefix(c.Name, "/")
+	for _, glob := range f.names {
+		if ok, _ := path.Match(glob, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchLabels reports whether the labels of c satisfy all terms.
+func (f *containerFilter) matchLabels(c *docker.Container) bool {
+	for _, t := range f.labels {
+		if !t.matches(c.Config.Labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// optedOut reports whether a container excludes itself from shipping, with
+// LOGSTASH_DISABLE or the logstash.disable label, or with LOGSPOUT=ignore
+// as logspout itself knows it.
+func optedOut(c *docker.Container) bool {
+	if strings.EqualFold(containerSetting(c, "LOGSPOUT", ""), "ignore") {
+		return true
+	}
+	return containerBool(c, "LOGSTASH_DISABLE", "logstash.disable", false)
+}
+
+// shipped reports whether the events of a container are shipped. It must
+// not opt out, match everything the include filter selects on, and nothing
+// the exclude filter selects on.
+func (a *LogstashAdapter) shipped(c *docker.Container) bool {
+	if optedOut(c) {
+		return false
+	}
+	in, ex := &a.include, &a.exclude
+	if len(in.names) > 0 && !in.matchName(c) ||
+		in.image != nil && !in.image.MatchString(c.Config.Image) ||
+		len(in.labels) > 0 && !in.matchLabels(c) {
+		return false
+	}
+	return !(len(ex.names) > 0 && ex.matchName(c) ||
+		ex.image != nil && ex.image.MatchString(c.Config.Image) ||
+		len(ex.labels) > 0 && ex.matchLabels(c))
+}
@@ -0,0 +1,111 @@
+package logstash
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	assert := assert.New(t)
+
+	terms, err := parseLabelSelector("app=web, tier!=cache,canary,!legacy")
+	assert.Nil(err)
+	assert.Equal([]labelTerm{
+		{key: "app", value: "web", hasValue: true},
+		{key: "tier", value: "cache", hasValue: true, not: true},
+		{key: "canary"},
+		{key: "legacy", not: true},
+	}, terms)
+
+	for _, s := range []string{"=web", "!", "!=x"} {
+		_, err := parseLabelSelector(s)
+		assert.NotNil(err, s)
+	}
+	_, err = parseGlobs("web-*,[")
+	assert.NotNil(err)
+}
+
+func TestShipped(t *testing.T) {
+	assert := assert.New(t)
+
+	web := &docker.Container{Name: "/web-1", Config: &docker.Config{Image: "nginx:1.19", Labels: map[string]string{"team": "payments"}}}
+	api := &docker.Container{Name: "/api", Config: &docker.Config{Image: "registry/api:2", Labels: map[string]string{"team": "payments", "canary": ""}}}
+	db := &docker.Container{Name: "/db", Config: &docker.Config{Image: "postgres:12"}}
+
+	labels, _ := parseLabelSelector("team=payments,!canary")
+	for _, c := range []struct {
+		adapter      LogstashAdapter
+		web, api, db bool
+	}{
+		{LogstashAdapter{}, true, true, true},
+		{LogstashAdapter{include: containerFilter{names: []string{"web-*", "db"}}}, true, false, true},
+		{LogstashAdapter{include: containerFilter{image: regexp.MustCompile(`^(nginx|postgres):`)}}, true, false, true},
+		{LogstashAdapter{include: containerFilter{labels: labels}}, true, false, false},
+		{LogstashAdapter{include: containerFilter{names: []string{"*"}, labels: labels}}, true, false, false},
+		{LogstashAdapter{exclude: containerFilter{names: []string{"db"}}}, true, true, false},
+		{LogstashAdapter{exclude: containerFilter{labels: labels}}, false, true, true},
+		{LogstashAdapter{include: containerFilter{names: []string{"web-*"}}, exclude: containerFilter{image: regexp.MustCompile(`nginx`)}}, false, false, false},
+	} {
+		assert.Equal(c.web, c.adapter.shipped(web))
+		assert.Equal(c.api, c.adapter.shipped(api))
+		assert.Equal(c.db, c.adapter.shipped(db))
+	}
+}
+
+func TestOptedOut(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, c := range []struct {
+		env      []string
+		labels   map[string]string
+		expected bool
+	}{
+		{nil, nil, false},
+		{[]string{"LOGSPOUT=ignore"}, nil, true},
+		{[]string{"LOGSPOUT=IGNORE"}, nil, true},
+		{[]string{"LOGSPOUT=route"}, nil, false},
+		{[]string{"LOGSTASH_DISABLE=true"}, nil, true},
+		{nil, map[string]string{"logstash.disable": "1"}, true},
+		{[]string{"LOGSTASH_DISABLE=false"}, map[string]string{"logstash.disable": "true"}, false},
+		{[]string{"LOGSTASH_DISABLE=maybe"}, nil, false},
+	} {
+		container := &docker.Container{Config: &docker.Config{Env: c.env, Labels: c.labels}}
+		assert.Equal(c.expected, optedOut(container), c.env)
+		assert.Equal(!c.expected, (&LogstashAdapter{}).shipped(container), c.env)
+	}
+}
+
+func TestStreamWithExcludedContainers(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:   new(router.Route),
+		conn:    conn,
+		exclude: containerFilter{names: []string{"noisy"}},
+	}
+
+	noisy := docker.Container{ID: "noisy", Name: "/noisy", Config: &docker.Config{}}
+	quiet := docker.Container{ID: "quiet", Name: "/quiet", Config: &docker.Config{}}
+	secret := docker.Container{ID: "secret", Name: "/secret", Config: &docker.Config{Labels: map[string]string{"logstash.disable": "true"}}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &noisy, Data: "spam", Time: time.Now()}
+		logstream <- &router.Message{Container: &secret, Data: "password", Time: time.Now()}
+		logstream <- &router.Message{Container: &quiet, Data: "started", Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 1) {
+		assert.Equal("started", lines[0]["message"])
+	}
+}
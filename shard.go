@@ -0,0 +1,126 @@
+package logstash
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+)
+
+// ringReplicas is the number of points each endpoint occupies on the hash
+// ring. More points give a more even spread of containers.
+const ringReplicas = 128
+
+// hashRing assigns keys to endpoints by consistent hashing, so adding or
+// removing an endpoint only moves the keys that belonged to it.
+type hashRing struct {
+	points []uint32
+	owners map[uint32]int
+}
+
+func newHashRing(endpoints []string) *hashRing {
+	r := &hashRing{owners: make(map[uint32]int)}
+	for i, endpoint := range endpoints {
+		for j := 0; j < ringReplicas; j++ {
+			p := hashKey(endpoint + "#" + strconv.Itoa(j))
+			if _, taken := r.owners[p]; taken {
+				continue
+			}
+			r.owners[p] = i
+			r.points = append(r.points, p)
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+	return r
+}
+
+// get returns the index of the endpoint that owns key.
+func (r *hashRing) get(key string) int {
+	h := hashKey(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]]
+}
+
+// hashKey hashes key the way ketama does, using the first four bytes of its
+// MD5 sum, which spreads similar keys far better than FNV.
+func hashKey(key string) uint32 {
+	sum := md5.Sum([]byte(key))
+	return binary.LittleEndian.Uint32(sum[:4])
+}
+
+// endpointFor returns the address that the container with the given ID
+// should be sent to.
+func (a *LogstashAdapter) endpointFor(id string) string {
+	if a.ring != nil {
+		return a.endpoints[a.ring.get(id)]
+	}
+	if len(a.endpoints) > 0 {
+		return a.endpoints[0]
+	}
+	return a.route.Address
+}
+
+// streamSharded sends each container's messages to the endpoint its ID
+// hashes to, and there to one of the endpoint's pool connections. Every
+// shard has its own connection and goroutine. Unless the pool is unordered,
+// a container always stays on the same shard, preserving the order of its
+// messages; an unordered pool spreads messages round-robin instead, except
+// those of containers that must stay together.
+func (a *LogstashAdapter) streamSharded(logstream chan *router.Message) {
+	queues := make([]chan *router.Message, len(a.shards))
+	var wg sync.WaitGroup
+	for i, shard := range a.shards {
+		queues[i] = make(chan *router.Message, a.queueSize)
+		wg.Add(1)
+		go func(shard *LogstashAdapter, q chan *router.Message) {
+			defer wg.Done()
+			shard.Stream(q)
+		}(shard, queues[i])
+	}
+
+	poolSize := a.poolSize
+	if poolSize < 1 {
+		poolSize = 1
+	}
+
+	next := 0
+	var together containerFlags
+	for m := range logstream {
+		endpoint := 0
+		if a.ring != nil {
+			endpoint = a.ring.get(m.Container.ID)
+		}
+
+		member := next % poolSize
+		keep := together.get(m.Container.ID, func() bool { return a.staysTogether(m.Container) })
+		if a.poolOrdered || keep {
+			member = int(hashKey(m.Container.ID) % uint32(poolSize))
+		} else {
+			next++
+		}
+
+		queues[endpoint*poolSize+member] <- m
+	}
+
+	for _, q := range queues {
+		close(q)
+	}
+	wg.Wait()
+}
+
+// staysTogether reports whether all messages of c must go to the same shard
+// even in an unordered pool, because joining its lines, collapsing its
+// repeats or limiting its rate takes seeing every one of them.
+func (a *LogstashAdapter) staysTogether(c *docker.Container) bool {
+	return a.partialLines ||
+		GetMultilineRule(c) != nil ||
+		a.containerRateLimiter(c) != nil ||
+		(a.dedupWindow > 0 && containerBool(c, "LOGSTASH_DEDUP", "logstash.dedup", true))
+}
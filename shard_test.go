@@ -0,0 +1,214 @@
+package logstash
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashRingConsistency(t *testing.T) {
+	assert := assert.New(t)
+
+	before := newHashRing([]string{"a:5000", "b:5000", "c:5000"})
+	after := newHashRing([]string{"a:5000", "b:5000", "c:5000", "d:5000"})
+
+	counts := make([]int, 4)
+	for i := 0; i < 10000; i++ {
+		key := "container" + strconv.Itoa(i)
+		owner := after.get(key)
+		counts[owner]++
+		// Keys either stay where they were or move to the new endpoint.
+		if owner != 3 {
+			assert.Equal(before.get(key), owner)
+		}
+	}
+	for _, n := range counts {
+		assert.InDelta(2500, n, 750)
+	}
+}
+
+func TestNewAdapterEndpoints(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &MockTransport{}
+	router.AdapterTransports.Register(transport, "endpoints")
+
+	adapter, err := NewLogstashAdapter(&router.Route{
+		Adapter: "logstash+endpoints",
+		Address: "a:5000",
+		Options: map[string]string{"endpoints": "a:5000, b:5000,"},
+	})
+	if assert.Nil(err) {
+		assert.Equal([]string{"a:5000", "b:5000"}, adapter.(*LogstashAdapter).endpoints)
+		assert.Len(transport.conns, 2)
+	}
+}
+
+func TestStreamSharded(t *testing.T) {
+	assert := assert.New(t)
+
+	endpoints := []string{"a:5000", "b:5000"}
+	adapter := LogstashAdapter{
+		route:         new(router.Route),
+		batchSize:     1,
+		flushInterval: time.Second,
+		queueSize:     16,
+		endpoints:     endpoints,
+		ring:          newHashRing(endpoints),
+	}
+	conns := []*BufferConn{{}, {}}
+	for _, conn := range conns {
+		adapter.shards = append(adapter.shards, adapter.withConn(conn))
+	}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		for i := 0; i < 20; i++ {
+			container := docker.Container{ID: "container" + strconv.Itoa(i%5), Config: &docker.Config{}}
+			logstream <- &router.Message{Container: &container, Data: strconv.Itoa(i)}
+		}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	total := 0
+	for i, conn := range conns {
+		for _, line := range conn.Lines() {
+			id := line["docker"].(map[string]interface{})["id"].(string)
+			assert.Equal(i, adapter.ring.get(id))
+			total++
+		}
+	}
+	assert.Equal(20, total)
+}
+
+func TestStreamPoolOrdered(t *testing.T) {
+	assert := assert.New(t)
+
+	adapter := LogstashAdapter{
+		route:         new(router.Route),
+		batchSize:     1,
+		flushInterval: time.Second,
+		queueSize:     16,
+		poolSize:      3,
+		poolOrdered:   true,
+	}
+	conns := []*BufferConn{{}, {}, {}}
+	for _, conn := range conns {
+		adapter.shards = append(adapter.shards, adapter.withConn(conn))
+	}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		for i := 0; i < 30; i++ {
+			container := docker.Container{ID: "container" + strconv.Itoa(i%6), Config: &docker.Config{}}
+			logstream <- &router.Message{Container: &container, Data: strconv.Itoa(i)}
+		}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	seen := make(map[string]int)
+	total := 0
+	for i, conn := range conns {
+		for _, line := range conn.Lines() {
+			id := line["docker"].(map[string]interface{})["id"].(string)
+			if member, ok := seen[id]; ok {
+				assert.Equal(member, i)
+			}
+			seen[id] = i
+			total++
+		}
+	}
+	assert.Equal(30, total)
+}
+
+func TestStreamPoolRoundRobin(t *testing.T) {
+	assert := assert.New(t)
+
+	adapter := LogstashAdapter{
+		route:         new(router.Route),
+		batchSize:     1,
+		flushInterval: time.Second,
+		queueSize:     16,
+		poolSize:      3,
+		eventIDs:      true,
+	}
+	conns := []*BufferConn{{}, {}, {}}
+	for _, conn := range conns {
+		adapter.shards = append(adapter.shards, adapter.withConn(conn))
+	}
+
+	container := docker.Container{ID: "container", Config: &docker.Config{}}
+	logstream := make(chan *router.Message)
+	go func() {
+		for i := 0; i < 9; i++ {
+			logstream <- &router.Message{Container: &container, Data: strconv.Itoa(i)}
+		}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	// The shards share the sequence of the container.
+	var sequences []float64
+	for _, conn := range conns {
+		lines := conn.Lines()
+		assert.Len(lines, 3)
+		for _, line := range lines {
+			sequences = append(sequences, line["sequence"].(float64))
+		}
+	}
+	assert.ElementsMatch([]float64{1, 2, 3, 4, 5, 6, 7, 8, 9}, sequences)
+}
+
+func TestStreamPoolRoundRobinStaysTogether(t *testing.T) {
+	assert := assert.New(t)
+
+	adapter := LogstashAdapter{
+		route:         new(router.Route),
+		batchSize:     1,
+		flushInterval: time.Second,
+		queueSize:     16,
+		poolSize:      3,
+	}
+	conns := []*BufferConn{{}, {}, {}}
+	for _, conn := range conns {
+		adapter.shards = append(adapter.shards, adapter.withConn(conn))
+	}
+
+	java := docker.Container{ID: "java", Config: &docker.Config{Env: []string{"LOGSTASH_MULTILINE=java"}}}
+	logstream := make(chan *router.Message)
+	go func() {
+		for i := 0; i < 3; i++ {
+			logstream <- &router.Message{Container: &java, Data: "ERROR request failed"}
+			logstream <- &router.Message{Container: &java, Data: "\tat com.example.Main.main(Main.java:7)"}
+		}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	var messages []interface{}
+	for _, conn := range conns {
+		for _, line := range conn.Lines() {
+			messages = append(messages, line["message"])
+		}
+	}
+	trace := "ERROR request failed\n\tat com.example.Main.main(Main.java:7)"
+	assert.Equal([]interface{}{trace, trace, trace}, messages)
+
+	assert.False(adapter.staysTogether(&docker.Container{ID: "plain", Config: &docker.Config{}}))
+	adapter.rateLimit = 10
+	assert.True(adapter.staysTogether(&docker.Container{ID: "limited", Config: &docker.Config{}}))
+	adapter.rateLimit = 0
+	adapter.dedupWindow = time.Second
+	assert.True(adapter.staysTogether(&docker.Container{ID: "repeated", Config: &docker.Config{}}))
+	assert.False(adapter.staysTogether(&docker.Container{ID: "unique", Config: &docker.Config{Env: []string{"LOGSTASH_DEDUP=false"}}}))
+}
@@ -0,0 +1,43 @@
+package logstash
+
+// Version is the version of the adapter reported in shipper fields. Release
+// builds set it with
+// -ldflags "-X github.com/looplab/logspout-logstash.Version=v1.2.3".
+var Version = "dev"
+
+// ShipperInfo identifies the logspout instance that shipped an event.
+type ShipperInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	ID      string `json:"id,omitempty"`
+}
+
+// GetShipperInfo returns the name and version of the adapter together with
+// the node ID, if any, configured with LOGSTASH_NODE_ID.
+func GetShipperInfo() ShipperInfo {
+	return ShipperInfo{
+		Name:    "logspout-logstash",
+		Version: Version,
+		ID:      getopt("LOGSTASH_NODE_ID", ""),
+	}
+}
+
+// setMetadata sets the shipper_name, shipper_version and shipper_id keys of
+// the @metadata of a container.
+func (s *ShipperInfo) setMetadata(meta *containerMeta) {
+	hintField{key: "shipper_name", metadata: true}.set(meta, s.Name)
+	hintField{key: "shipper_version", metadata: true}.set(meta, s.Version)
+	if s.ID != "" {
+		hintField{key: "shipper_id", metadata: true}.set(meta, s.ID)
+	}
+}
+
+// appendJSON appends the JSON encoding of s to dst.
+func (s *ShipperInfo) appendJSON(dst []byte) []byte {
+	dst = append(dst, `{"name":`...)
+	dst = appendJSONString(dst, s.Name)
+	dst = append(dst, `,"version":`...)
+	dst = appendJSONString(dst, s.Version)
+	dst = appendJSONStringField(dst, 0, "id", s.ID)
+	return append(dst, '}')
+}
@@ -0,0 +1,58 @@
+package logstash
+
+import (
+	"os"
+	"testing"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetShipperInfo(t *testing.T) {
+	os.Setenv("LOGSTASH_NODE_ID", "edge-7")
+	defer os.Unsetenv("LOGSTASH_NODE_ID")
+
+	assert.Equal(t, ShipperInfo{Name: "logspout-logstash", Version: Version, ID: "edge-7"}, GetShipperInfo())
+}
+
+func TestStreamWithShipper(t *testing.T) {
+	assert := assert.New(t)
+
+	shipper := ShipperInfo{Name: "logspout-logstash", Version: "v3.2.0", ID: "edge-7"}
+	container := docker.Container{ID: "ID", Config: &docker.Config{}}
+
+	for _, metadata := range []bool{false, true} {
+		conn := &BufferConn{}
+		adapter := LogstashAdapter{
+			route:           new(router.Route),
+			conn:            conn,
+			shipper:         &shipper,
+			shipperMetadata: metadata,
+		}
+
+		logstream := make(chan *router.Message)
+		go func() {
+			logstream <- &router.Message{Container: &container, Data: `foo`}
+			close(logstream)
+		}()
+
+		adapter.Stream(logstream)
+
+		lines := conn.Lines()
+		if !assert.Len(lines, 1) {
+			continue
+		}
+		if metadata {
+			assert.Nil(lines[0]["shipper"])
+			assert.Equal(map[string]interface{}{
+				"shipper_name":    "logspout-logstash",
+				"shipper_version": "v3.2.0",
+				"shipper_id":      "edge-7",
+			}, lines[0]["@metadata"])
+		} else {
+			assert.Equal(map[string]interface{}{"name": "logspout-logstash", "version": "v3.2.0", "id": "edge-7"}, lines[0]["shipper"])
+			assert.Nil(lines[0]["@metadata"])
+		}
+	}
+}
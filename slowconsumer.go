@@ -0,0 +1,78 @@
+package logstash
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+)
+
+// slowConsumerSource is the stream of the warnings that Logstash does not
+// keep up with the logs.
+const slowConsumerSource = "slow_consumer"
+
+// slowConsumerSamples is the number of checks in a row the backlog must
+// grow in before it is warned about.
+const slowConsumerSamples = 3
+
+// adapterContainer stands in for the container of the adapter's own
+// events.
+var adapterContainer = &docker.Container{ID: "logspout", Name: "/logspout", Config: &docker.Config{}}
+
+// SlowConsumerWarning is the JSON message of a slow consumer warning.
+type SlowConsumerWarning struct {
+	Message  string `json:"message"`
+	Level    string `json:"level"`
+	Backlog  int    `json:"backlog"`
+	Capacity int    `json:"capacity"`
+	Growth   int    `json:"growth"`
+	Seconds  int    `json:"seconds"`
+}
+
+// slowConsumer tells when the backlog of events waiting to be written keeps
+// growing, which means the write path is slower than the logs come in.
+type slowConsumer struct {
+	interval time.Duration
+	capacity int
+	samples  []int
+	warned   bool
+}
+
+// check records the backlog at a check, and returns a warning message if it
+// grew at each of the last slowConsumerSamples checks, once per episode of
+// growth.
+func (s *slowConsumer) check(backlog int, now time.Time) *router.Message {
+	if n := len(s.samples); n > 0 && backlog <= s.samples[n-1] {
+		s.samples = s.samples[:0]
+		s.warned = false
+	}
+	s.samples = append(s.samples, backlog)
+	if len(s.samples) > slowConsumerSamples+1 {
+		s.samples = s.samples[1:]
+	}
+	if len(s.samples) <= slowConsumerSamples || s.warned {
+		return nil
+	}
+	s.warned = true
+	stageMetrics.Add("slow_consumer.warnings", 1)
+
+	first := s.samples[len(s.samples)-1-slowConsumerSamples]
+	seconds := int((time.Duration(slowConsumerSamples) * s.interval).Seconds())
+	warning := SlowConsumerWarning{
+		Message:  "events are queued faster than they are written, backlog grew from " + strconv.Itoa(first) + " to " + strconv.Itoa(backlog) + " of " + strconv.Itoa(s.capacity) + " in " + strconv.Itoa(seconds) + "s",
+		Level:    "warning",
+		Backlog:  backlog,
+		Capacity: s.capacity,
+		Growth:   backlog - first,
+		Seconds:  seconds,
+	}
+	log.Println("logstash:", warning.Message)
+	js, err := json.Marshal(warning)
+	if err != nil {
+		return nil
+	}
+	return &router.Message{Container: adapterContainer, Source: slowConsumerSource, Data: string(js), Time: now}
+}
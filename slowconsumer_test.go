@@ -0,0 +1,83 @@
+package logstash
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlowConsumerCheck(t *testing.T) {
+	assert := assert.New(t)
+
+	s := slowConsumer{interval: 10 * time.Second, capacity: 2048}
+	now := time.Now()
+	for _, backlog := range []int{0, 100, 50, 200, 400} {
+		assert.Nil(s.check(backlog, now))
+	}
+	m := s.check(800, now)
+	if assert.NotNil(m) {
+		assert.Equal(slowConsumerSource, m.Source)
+		var warning SlowConsumerWarning
+		assert.Nil(json.Unmarshal([]byte(m.Data), &warning))
+		assert.Equal(SlowConsumerWarning{
+			Message:  "events are queued faster than they are written, backlog grew from 50 to 800 of 2048 in 30s",
+			Level:    "warning",
+			Backlog:  800,
+			Capacity: 2048,
+			Growth:   750,
+			Seconds:  30,
+		}, warning)
+	}
+
+	// Once per episode of growth.
+	assert.Nil(s.check(1600, now))
+	assert.Nil(s.check(1600, now))
+	for _, backlog := range []int{1700, 1800} {
+		assert.Nil(s.check(backlog, now))
+	}
+	assert.NotNil(s.check(1900, now))
+}
+
+func TestEnrichStageWarnsOfSlowConsumer(t *testing.T) {
+	assert := assert.New(t)
+
+	adapter := LogstashAdapter{
+		route:                new(router.Route),
+		slowConsumerInterval: 5 * time.Millisecond,
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{}}
+
+	logstream := make(chan *router.Message)
+	events := make(chan *event, 64)
+	written := 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		adapter.enrichStage(logstream, events, func() int {
+			written++
+			return written
+		})
+	}()
+	for i := 0; i < 40; i++ {
+		logstream <- &router.Message{Container: &container, Data: "line", Time: time.Now()}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(logstream)
+	<-done
+
+	warnings := 0
+	for e := range events {
+		if e.message.Source == slowConsumerSource {
+			warnings++
+			assert.Equal("json", e.format)
+			assert.Contains(e.tags, slowConsumerSource)
+		}
+	}
+	assert.Equal(1, warnings)
+}
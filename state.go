@@ -0,0 +1,31 @@
+package logstash
+
+import (
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// ContainerState describes how long the container has been running and how
+// often it was restarted, so crash loops show up in the log stream itself.
+type ContainerState struct {
+	RestartCount int    `json:"restart_count"`
+	StartedAt    string `json:"started_at,omitempty"`
+	Uptime       int64  `json:"uptime"`
+}
+
+// GetContainerState fills s from the container as logspout last inspected
+// it. logspout inspects a container again every time it (re)starts, so the
+// restart count stays current without extra Docker API calls; the uptime is
+// derived from the message time.
+func GetContainerState(c *docker.Container, at time.Time, s *ContainerState) {
+	*s = ContainerState{RestartCount: c.RestartCount}
+	started := c.State.StartedAt
+	if started.IsZero() {
+		return
+	}
+	s.StartedAt = started.UTC().Format(time.RFC3339Nano)
+	if uptime := at.Sub(started); uptime > 0 {
+		s.Uptime = int64(uptime / time.Second)
+	}
+}
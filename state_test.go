@@ -0,0 +1,28 @@
+package logstash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetContainerState(t *testing.T) {
+	assert := assert.New(t)
+
+	var state ContainerState
+	container := docker.Container{ID: "ID", Config: &docker.Config{}, RestartCount: 3}
+	GetContainerState(&container, time.Now(), &state)
+	assert.Equal(ContainerState{RestartCount: 3}, state)
+
+	started := time.Date(2016, 10, 20, 13, 25, 13, 0, time.FixedZone("CEST", 2*3600))
+	container.State.StartedAt = started
+	GetContainerState(&container, started.Add(90*time.Minute+500*time.Millisecond), &state)
+	assert.Equal(ContainerState{RestartCount: 3, StartedAt: "2016-10-20T11:25:13Z", Uptime: 5400}, state)
+
+	// Clock skew between the daemon and the message never yields a
+	// negative uptime.
+	GetContainerState(&container, started.Add(-time.Second), &state)
+	assert.Equal(int64(0), state.Uptime)
+}
@@ -0,0 +1,113 @@
+package logstash
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// ResourceUsage is the latest resource usage sample of a container.
+type ResourceUsage struct {
+	CPUPercent  float64 `json:"cpu_percent"`
+	MemoryRSS   uint64  `json:"memory_rss"`
+	MemoryLimit uint64  `json:"memory_limit,omitempty"`
+	SampledAt   string  `json:"sampled_at"`
+}
+
+// newResourceUsage summarizes a Docker stats sample. CPU usage is relative
+// to a single core, like docker stats shows it, so a busy container on four
+// cores can reach 400%.
+func newResourceUsage(s *docker.Stats) *ResourceUsage {
+	u := &ResourceUsage{
+		MemoryRSS:   s.MemoryStats.Stats.Rss,
+		MemoryLimit: s.MemoryStats.Limit,
+		SampledAt:   s.Read.UTC().Format(time.RFC3339Nano),
+	}
+	if u.MemoryRSS == 0 {
+		// cgroup v2 has no rss, anonymous memory is the equivalent.
+		u.MemoryRSS = s.MemoryStats.Stats.Anon
+	}
+
+	cpus := s.CPUStats.OnlineCPUs
+	if cpus == 0 {
+		cpus = uint64(len(s.CPUStats.CPUUsage.PercpuUsage))
+	}
+	cpu := s.CPUStats.CPUUsage.TotalUsage
+	system := s.CPUStats.SystemCPUUsage
+	if cpu > s.PreCPUStats.CPUUsage.TotalUsage && system > s.PreCPUStats.SystemCPUUsage {
+		ratio := float64(cpu-s.PreCPUStats.CPUUsage.TotalUsage) / float64(system-s.PreCPUStats.SystemCPUUsage)
+		u.CPUPercent = math.Round(ratio*float64(cpus)*10000) / 100
+	}
+	return u
+}
+
+// statsSampler polls the Docker stats of every container that logs through
+// the adapter. Containers are watched from their first message until the
+// Docker API stops answering for them, which happens once they are removed.
+type statsSampler struct {
+	client   dockerClient
+	interval time.Duration
+
+	mu      sync.Mutex
+	latest  map[string]*ResourceUsage
+	watched map[string]bool
+}
+
+func newStatsSampler(client dockerClient, interval time.Duration) *statsSampler {
+	return &statsSampler{
+		client:   client,
+		interval: interval,
+		latest:   make(map[string]*ResourceUsage),
+		watched:  make(map[string]bool),
+	}
+}
+
+// get returns the latest sample of a container, or nil if there is none yet.
+// The first call for a container starts watching it.
+func (s *statsSampler) get(id string) *ResourceUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.watched[id] {
+		s.watched[id] = true
+		go s.watch(id)
+	}
+	return s.latest[id]
+}
+
+func (s *statsSampler) watch(id string) {
+	for s.sample(id) {
+		time.Sleep(s.interval)
+	}
+	s.mu.Lock()
+	delete(s.latest, id)
+	delete(s.watched, id)
+	s.mu.Unlock()
+}
+
+// sample takes a single stats sample of a container, reporting whether it
+// succeeded. Stopped containers report samples without a read time.
+func (s *statsSampler) sample(id string) bool {
+	stats := make(chan *docker.Stats, 1)
+	err := s.client.Stats(docker.StatsOptions{ID: id, Stats: stats, Timeout: s.interval})
+	st, ok := <-stats
+	if err != nil || !ok || st.Read.IsZero() {
+		return false
+	}
+
+	usage := newResourceUsage(st)
+	s.mu.Lock()
+	s.latest[id] = usage
+	s.mu.Unlock()
+	return true
+}
+
+// resourceUsage returns the latest resource usage sample of a container, if
+// stats sampling is enabled.
+func (a *LogstashAdapter) resourceUsage(c *docker.Container) *ResourceUsage {
+	if a.stats == nil {
+		return nil
+	}
+	return a.stats.get(c.ID)
+}
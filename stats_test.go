@@ -0,0 +1,61 @@
+package logstash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewResourceUsage(t *testing.T) {
+	assert := assert.New(t)
+
+	var stats docker.Stats
+	stats.Read = time.Date(2016, 10, 20, 13, 25, 13, 0, time.UTC)
+	stats.MemoryStats.Stats.Rss = 1000
+	stats.MemoryStats.Limit = 4000
+	stats.CPUStats.OnlineCPUs = 4
+	stats.CPUStats.CPUUsage.TotalUsage = 300
+	stats.CPUStats.SystemCPUUsage = 1200
+	stats.PreCPUStats.CPUUsage.TotalUsage = 100
+	stats.PreCPUStats.SystemCPUUsage = 400
+
+	assert.Equal(&ResourceUsage{
+		CPUPercent:  100,
+		MemoryRSS:   1000,
+		MemoryLimit: 4000,
+		SampledAt:   "2016-10-20T13:25:13Z",
+	}, newResourceUsage(&stats))
+
+	// cgroup v2 and the first sample of a container, without a previous
+	// CPU reading.
+	stats.MemoryStats.Stats.Rss = 0
+	stats.MemoryStats.Stats.Anon = 2000
+	stats.PreCPUStats.CPUUsage.TotalUsage = 0
+	stats.PreCPUStats.SystemCPUUsage = 0
+	stats.CPUStats.SystemCPUUsage = 0
+	usage := newResourceUsage(&stats)
+	assert.Equal(uint64(2000), usage.MemoryRSS)
+	assert.Equal(float64(0), usage.CPUPercent)
+}
+
+func TestStatsSampler(t *testing.T) {
+	assert := assert.New(t)
+
+	var running, stopped docker.Stats
+	running.Read = time.Now()
+	running.MemoryStats.Stats.Rss = 1000
+	client := &MockDockerClient{stats: map[string]*docker.Stats{
+		"running": &running,
+		"stopped": &stopped,
+	}}
+	sampler := newStatsSampler(client, time.Hour)
+
+	assert.Nil(sampler.get("running"))
+	assert.True(sampler.sample("running"))
+	assert.Equal(uint64(1000), sampler.get("running").MemoryRSS)
+
+	assert.False(sampler.sample("stopped"))
+	assert.False(sampler.sample("removed"))
+}
@@ -0,0 +1,40 @@
+package logstash
+
+import (
+	"github.com/fsouza/go-dockerclient"
+)
+
+// SwarmData describes the Swarm service and stack a container belongs to.
+type SwarmData struct {
+	ServiceName string `json:"service_name,omitempty"`
+	Stack       string `json:"stack,omitempty"`
+	TaskID      string `json:"task_id,omitempty"`
+	Node        string `json:"node,omitempty"`
+}
+
+// GetSwarmData returns the Swarm information found in the container labels,
+// or nil if the container is not a Swarm task.
+func GetSwarmData(c *docker.Container) *SwarmData {
+	labels := c.Config.Labels
+	d := SwarmData{
+		ServiceName: labels["com.docker.swarm.service.name"],
+		Stack:       labels["com.docker.stack.namespace"],
+		TaskID:      labels["com.docker.swarm.task.id"],
+		Node:        labels["com.docker.swarm.node.id"],
+	}
+	if d == (SwarmData{}) {
+		return nil
+	}
+	return &d
+}
+
+// appendJSON appends the JSON encoding of d to dst.
+func (d *SwarmData) appendJSON(dst []byte) []byte {
+	dst = append(dst, '{')
+	n := len(dst)
+	dst = appendJSONStringField(dst, n, "service_name", d.ServiceName)
+	dst = appendJSONStringField(dst, n, "stack", d.Stack)
+	dst = appendJSONStringField(dst, n, "task_id", d.TaskID)
+	dst = appendJSONStringField(dst, n, "node", d.Node)
+	return append(dst, '}')
+}
@@ -0,0 +1,28 @@
+package logstash
+
+import (
+	"testing"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSwarmData(t *testing.T) {
+	assert := assert.New(t)
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Labels: map[string]string{
+		"com.docker.swarm.service.name": "shop_web",
+		"com.docker.stack.namespace":    "shop",
+		"com.docker.swarm.task.id":      "x2w3tpnfn8wbzi8x9vpc8rnza",
+		"com.docker.swarm.node.id":      "9f7y2ljfsg6bjp5m7pqtbvdsd",
+	}}}
+	assert.Equal(&SwarmData{
+		ServiceName: "shop_web",
+		Stack:       "shop",
+		TaskID:      "x2w3tpnfn8wbzi8x9vpc8rnza",
+		Node:        "9f7y2ljfsg6bjp5m7pqtbvdsd",
+	}, GetSwarmData(&container))
+
+	container.Config.Labels = map[string]string{"other": "label"}
+	assert.Nil(GetSwarmData(&container))
+}
@@ -0,0 +1,44 @@
+package logstash
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// syslogHeader matches an RFC 3164 header such as
+// <34>Oct 11 22:14:15 mymachine su[230]: with the priority and hostname
+// being optional, as syslog daemons writing to stdout often leave them out.
+var syslogHeader = regexp.MustCompile(`^(?:<(\d{1,3})>)?([A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}) (?:(\S+) )?([^\s:\[]+)(?:\[(\d+)\])?: ?`)
+
+// stripSyslogHeader removes the syslog header from the start of a line. It
+// returns the fields of the header, or nil if the line has none, and the
+// rest of the line.
+func stripSyslogHeader(line string) (map[string]interface{}, string) {
+	match := syslogHeader.FindStringSubmatchIndex(line)
+	if match == nil {
+		return nil, line
+	}
+	group := func(i int) string {
+		if match[2*i] < 0 {
+			return ""
+		}
+		return line[match[2*i]:match[2*i+1]]
+	}
+
+	header := map[string]interface{}{
+		"timestamp": group(2),
+		"program":   group(4),
+	}
+	if pri, err := strconv.Atoi(group(1)); err == nil && pri <= 191 {
+		header["priority"] = pri
+		header["facility"] = pri / 8
+		header["severity"] = pri % 8
+	}
+	if host := group(3); host != "" {
+		header["hostname"] = host
+	}
+	if pid, err := strconv.Atoi(group(5)); err == nil {
+		header["pid"] = pid
+	}
+	return header, line[match[1]:]
+}
@@ -0,0 +1,70 @@
+package logstash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripSyslogHeader(t *testing.T) {
+	assert := assert.New(t)
+
+	header, rest := stripSyslogHeader(`<34>Oct 11 22:14:15 mymachine su[230]: 'su root' failed`)
+	assert.Equal(map[string]interface{}{
+		"priority":  34,
+		"facility":  4,
+		"severity":  2,
+		"timestamp": "Oct 11 22:14:15",
+		"hostname":  "mymachine",
+		"program":   "su",
+		"pid":       230,
+	}, header)
+	assert.Equal(`'su root' failed`, rest)
+
+	header, rest = stripSyslogHeader(`Oct  1 02:04:05 cron: job done`)
+	assert.Equal(map[string]interface{}{"timestamp": "Oct  1 02:04:05", "program": "cron"}, header)
+	assert.Equal("job done", rest)
+
+	for _, line := range []string{"plain text", "2016-10-20 13:25:13 INFO started", "Oct 11 22:14:15 no colon"} {
+		header, rest := stripSyslogHeader(line)
+		assert.Nil(header, line)
+		assert.Equal(line, rest)
+	}
+}
+
+func TestStreamWithSyslogHeaders(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:         new(router.Route),
+		conn:          conn,
+		syslogHeaders: true,
+		stripANSI:     true,
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: "<14>Oct 11 22:14:15 web nginx[7]: \x1b[32mstarted\x1b[0m", Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `<14>Oct 11 22:14:15 web app: {"status":"200"}`, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `plain`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 3) {
+		assert.Equal("started", lines[0]["message"])
+		assert.Equal("nginx", lines[0]["syslog"].(map[string]interface{})["program"])
+		assert.Equal("200", lines[1]["status"])
+		assert.Equal("app", lines[1]["syslog"].(map[string]interface{})["program"])
+		assert.Equal("plain", lines[2]["message"])
+		assert.Nil(lines[2]["syslog"])
+	}
+}
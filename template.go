@@ -0,0 +1,84 @@
+package logstash
+
+import (
+	"log"
+	"strings"
+	"text/template"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// containerTemplateData is what tag and index templates are executed on, e.g.
+// {{.Container.Config.Image}} or {{.Env "DEPLOY_ENV"}}.
+type containerTemplateData struct {
+	Container *docker.Container
+}
+
+// Name returns the container name without its leading slash.
+func (d containerTemplateData) Name() string {
+	return strings.TrimPrefix(d.Container.Name, "/")
+}
+
+// Env returns the value of an environment variable of the container.
+func (d containerTemplateData) Env(name string) string {
+	for _, e := range d.Container.Config.Env {
+		if strings.HasPrefix(e, name+"=") {
+			return strings.TrimPrefix(e, name+"=")
+		}
+	}
+	return ""
+}
+
+// Label returns the value of a label of the container.
+func (d containerTemplateData) Label(name string) string {
+	return d.Container.Config.Labels[name]
+}
+
+// parseTagTemplate parses a tag as a template, or returns nil if it has no
+// template actions.
+func parseTagTemplate(tag string) (*template.Template, error) {
+	if !strings.Contains(tag, "{{") {
+		return nil, nil
+	}
+	return template.New("tag").Option("missingkey=zero").Parse(tag)
+}
+
+// expandTags expands the templates in tags for container c. Tags that expand
+// to nothing are dropped, tags that fail to expand are kept as they are.
+func expandTags(tags []string, c *docker.Container) []string {
+	var expanded []string
+	for i, tag := range tags {
+		tmpl, err := parseTagTemplate(tag)
+		if tmpl == nil && err == nil {
+			if expanded != nil {
+				expanded = append(expanded, tag)
+			}
+			continue
+		}
+		if expanded == nil {
+			expanded = append(make([]string, 0, len(tags)), tags[:i]...)
+		}
+
+		var s string
+		if err == nil {
+			s, err = executeTemplate(tmpl, c)
+		}
+		if err != nil {
+			log.Println("logstash: could not expand tag", tag+":", err)
+			expanded = append(expanded, tag)
+		} else if s != "" {
+			expanded = append(expanded, s)
+		}
+	}
+	if expanded == nil {
+		return tags
+	}
+	return expanded
+}
+
+// executeTemplate expands tmpl for container c.
+func executeTemplate(tmpl *template.Template, c *docker.Container) (string, error) {
+	var b strings.Builder
+	err := tmpl.Execute(&b, containerTemplateData{Container: c})
+	return b.String(), err
+}
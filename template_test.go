@@ -0,0 +1,48 @@
+package logstash
+
+import (
+	"testing"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandTags(t *testing.T) {
+	assert := assert.New(t)
+
+	container := docker.Container{ID: "ID", Name: "/shop-web-1", Config: &docker.Config{
+		Image:  "registry/shop:1.6",
+		Env:    []string{"DEPLOY_ENV=prod"},
+		Labels: map[string]string{"com.example.team": "payments"},
+	}}
+
+	plain := []string{"web", "prod"}
+	assert.Equal(plain, expandTags(plain, &container))
+
+	assert.Equal([]string{
+		"web",
+		"registry/shop:1.6",
+		"prod",
+		"team-payments",
+		"shop-web-1",
+		"{{.Broken",
+		"{{.Nope}}",
+	}, expandTags([]string{
+		"web",
+		"{{.Container.Config.Image}}",
+		`{{.Env "DEPLOY_ENV"}}`,
+		`team-{{.Label "com.example.team"}}`,
+		"{{.Name}}",
+		`{{.Env "UNSET"}}`,
+		"{{.Broken",
+		"{{.Nope}}",
+	}, &container))
+}
+
+func TestDefaultTagTemplates(t *testing.T) {
+	assert := assert.New(t)
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Env: []string{"LOGSTASH_TAGS={{.Env \"DEPLOY_ENV\"}}", "DEPLOY_ENV=prod"}}}
+	adapter := LogstashAdapter{defaultTags: []string{"edge", "{{.Env \"DEPLOY_ENV\"}}"}}
+	assert.Equal([]string{"edge", "prod"}, adapter.containerTags(&container))
+}
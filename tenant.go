@@ -0,0 +1,23 @@
+package logstash
+
+import (
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+)
+
+// containerTenant returns the tenant of a container, the value of its tenant
+// label, or "" if it has none.
+func (a *LogstashAdapter) containerTenant(c *docker.Container) string {
+	if a.tenantLabel == "" || c.Config == nil {
+		return ""
+	}
+	return c.Config.Labels[a.tenantLabel]
+}
+
+// streamPerTenant fans messages out to one adapter and connection per
+// tenant, so the logs of a tenant that back up are dropped from its own
+// queue and cannot crowd out those of other tenants. Containers without a
+// tenant share a queue.
+func (a *LogstashAdapter) streamPerTenant(logstream chan *router.Message) {
+	a.streamIsolated(logstream, "tenant", func(m *router.Message) string { return a.containerTenant(m.Container) })
+}
@@ -0,0 +1,95 @@
+package logstash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamPerTenant(t *testing.T) {
+	assert := assert.New(t)
+
+	transport := &MockTransport{}
+	adapter := LogstashAdapter{
+		route:           new(router.Route),
+		conn:            &BufferConn{},
+		transport:       transport,
+		batchSize:       1,
+		flushInterval:   time.Second,
+		tenantLabel:     "com.example.tenant",
+		tenantField:     hintField{key: "tenant"},
+		tenantIsolation: true,
+		queueSize:       16,
+		idleTimeout:     time.Minute,
+	}
+
+	acme := map[string]string{"com.example.tenant": "acme"}
+	web := docker.Container{ID: "web", Config: &docker.Config{Labels: acme}}
+	worker := docker.Container{ID: "worker", Config: &docker.Config{Labels: acme}}
+	other := docker.Container{ID: "other", Config: &docker.Config{Labels: map[string]string{"com.example.tenant": "globex"}}}
+	system := docker.Container{ID: "system", Config: &docker.Config{}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &web, Data: "a"}
+		logstream <- &router.Message{Container: &other, Data: "b"}
+		logstream <- &router.Message{Container: &worker, Data: "c"}
+		logstream <- &router.Message{Container: &system, Data: "d"}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	if !assert.Len(transport.conns, 3) {
+		return
+	}
+	var messages []interface{}
+	for _, line := range transport.conns[0].Lines() {
+		assert.Equal("acme", line["tenant"])
+		messages = append(messages, line["message"])
+	}
+	assert.Equal([]interface{}{"a", "c"}, messages)
+
+	lines := transport.conns[1].Lines()
+	if assert.Len(lines, 1) {
+		assert.Equal("b", lines[0]["message"])
+		assert.Equal("globex", lines[0]["tenant"])
+	}
+
+	lines = transport.conns[2].Lines()
+	if assert.Len(lines, 1) {
+		assert.Equal("d", lines[0]["message"])
+		assert.Nil(lines[0]["tenant"])
+	}
+}
+
+func TestTenantMetadataField(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:       new(router.Route),
+		conn:        conn,
+		tenantLabel: "tenant",
+		tenantField: hintField{key: "tenant", metadata: true},
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{Labels: map[string]string{"tenant": "acme"}}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: "line", Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 1) {
+		assert.Nil(lines[0]["tenant"])
+		assert.Equal(map[string]interface{}{"tenant": "acme"}, lines[0]["@metadata"])
+	}
+}
@@ -0,0 +1,67 @@
+package logstash
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// throttle is a token bucket that caps the throughput of a route at rate
+// units per second, with bursts of up to burst units. It is shared by the
+// connections of an adapter, so the cap holds for all of them together.
+type throttle struct {
+	mu          sync.Mutex
+	rate, burst float64
+	tokens      float64
+	last        time.Time
+}
+
+// newThrottle returns a throttle of rate units per second, with bursts of
+// burst units, or of one second's worth if burst is 0. It returns nil if
+// rate is 0.
+func newThrottle(rate float64, burst int) *throttle {
+	if rate <= 0 {
+		return nil
+	}
+	b := float64(burst)
+	if b <= 0 {
+		b = math.Max(1, math.Ceil(rate))
+	}
+	return &throttle{rate: rate, burst: b, tokens: b}
+}
+
+// reserve takes n units at now and returns how long to wait before they
+// may be sent. Units beyond the tokens at hand are borrowed from the
+// future, so batches larger than the burst are delayed rather than stuck.
+func (t *throttle) reserve(n int, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.last.IsZero() {
+		t.tokens = math.Min(t.burst, t.tokens+now.Sub(t.last).Seconds()*t.rate)
+	}
+	t.last = now
+	t.tokens -= float64(n)
+	if t.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-t.tokens / t.rate * float64(time.Second))
+}
+
+// throttle waits until a batch of events and bytes is within the
+// throughput caps of the adapter.
+func (a *LogstashAdapter) throttle(events, bytes int) {
+	now := time.Now()
+	var delay time.Duration
+	if a.eventCap != nil {
+		delay = a.eventCap.reserve(events, now)
+	}
+	if a.byteCap != nil {
+		if d := a.byteCap.reserve(bytes, now); d > delay {
+			delay = d
+		}
+	}
+	if delay > 0 {
+		stageMetrics.Add("throttle.nanoseconds", int64(delay))
+		time.Sleep(delay)
+	}
+}
@@ -0,0 +1,59 @@
+package logstash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottleReserve(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(newThrottle(0, 100))
+
+	th := newThrottle(100, 0)
+	assert.Equal(100.0, th.burst)
+
+	th = newThrottle(10, 20)
+	now := time.Now()
+	assert.Equal(time.Duration(0), th.reserve(15, now))
+	assert.Equal(time.Duration(0), th.reserve(5, now))
+	assert.Equal(500*time.Millisecond, th.reserve(5, now))
+	// Waiting pays back what was borrowed.
+	assert.Equal(time.Duration(0), th.reserve(5, now.Add(time.Second)))
+
+	// Batches larger than the burst are delayed, not refused.
+	now = now.Add(time.Hour)
+	assert.Equal(3*time.Second, th.reserve(50, now))
+}
+
+func TestStreamWithThroughputCap(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:    new(router.Route),
+		conn:     conn,
+		eventCap: newThrottle(100, 5),
+		byteCap:  newThrottle(1e9, 0),
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		for i := 0; i < 15; i++ {
+			logstream <- &router.Message{Container: &container, Data: "storm", Time: time.Now()}
+		}
+		close(logstream)
+	}()
+
+	start := time.Now()
+	adapter.Stream(logstream)
+
+	assert.Len(conn.Lines(), 15)
+	assert.True(time.Since(start) >= 90*time.Millisecond, time.Since(start))
+}
@@ -0,0 +1,105 @@
+package logstash
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// timestampParser extracts the time a line was logged at from its content.
+// now is the time Docker read the line at, which fills in what the line
+// leaves out.
+type timestampParser func(line string, now time.Time) (time.Time, bool)
+
+var (
+	iso8601Prefix = regexp.MustCompile(`^\[?(\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?)`)
+	syslogPrefix  = regexp.MustCompile(`^([A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2})`)
+	clfTimestamp  = regexp.MustCompile(`^\S+ \S+ \S+ \[(\d{2}/[A-Z][a-z]{2}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4})\]`)
+)
+
+// timestampParsers are the formats recognized by name. log4j's default
+// ISO8601 layout, with a comma before the milliseconds, is covered by
+// iso8601.
+var timestampParsers = map[string]timestampParser{
+	"iso8601": parseISO8601,
+	"log4j":   parseISO8601,
+	"syslog":  parseSyslog,
+	"clf":     parseCLF,
+}
+
+// parseAnyTimestamp tries every known format.
+func parseAnyTimestamp(line string, now time.Time) (time.Time, bool) {
+	for _, parse := range []timestampParser{parseISO8601, parseSyslog, parseCLF} {
+		if t, ok := parse(line, now); ok {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func parseISO8601(line string, now time.Time) (time.Time, bool) {
+	match := iso8601Prefix.FindStringSubmatch(line)
+	if match == nil {
+		return time.Time{}, false
+	}
+	s := strings.Replace(strings.Replace(match[1], ",", ".", 1), " ", "T", 1)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999Z0700"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	// Without a zone, the time is taken to be UTC.
+	t, err := time.Parse("2006-01-02T15:04:05.999999999", s)
+	return t, err == nil
+}
+
+func parseSyslog(line string, now time.Time) (time.Time, bool) {
+	match := syslogPrefix.FindStringSubmatch(line)
+	if match == nil {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.Stamp, match[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+	// Syslog timestamps have no year: take the one of the read time, or the
+	// one before for lines from late December read in January.
+	t = t.AddDate(now.UTC().Year(), 0, 0)
+	if t.After(now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t, true
+}
+
+func parseCLF(line string, now time.Time) (time.Time, bool) {
+	match := clfTimestamp.FindStringSubmatch(line)
+	if match == nil {
+		return time.Time{}, false
+	}
+	t, err := time.Parse("02/Jan/2006:15:04:05 -0700", match[1])
+	return t, err == nil
+}
+
+// layoutParser returns a parser for lines starting with a timestamp in the
+// given fixed width Go time layout.
+func layoutParser(layout string) timestampParser {
+	return func(line string, now time.Time) (time.Time, bool) {
+		if len(line) < len(layout) {
+			return time.Time{}, false
+		}
+		t, err := time.Parse(layout, line[:len(layout)])
+		return t, err == nil
+	}
+}
+
+// newTimestampParser returns the parser for a format name or Go time layout,
+// or the parser trying all known formats if format is empty or auto.
+func newTimestampParser(format string) timestampParser {
+	if format == "" || format == "auto" {
+		return parseAnyTimestamp
+	}
+	if parse, ok := timestampParsers[strings.ToLower(format)]; ok {
+		return parse
+	}
+	return layoutParser(format)
+}
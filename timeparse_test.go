@@ -0,0 +1,80 @@
+package logstash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTimestamps(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2017, 1, 1, 0, 0, 30, 0, time.UTC)
+	for _, tt := range []struct {
+		format   string
+		line     string
+		expected string
+	}{
+		{"auto", "2016-10-20T13:25:13.627Z GET /", "2016-10-20T13:25:13.627Z"},
+		{"auto", "2016-10-20T15:25:13+02:00 GET /", "2016-10-20T13:25:13Z"},
+		{"auto", "[2016-10-20 13:25:13,627] INFO started", "2016-10-20T13:25:13.627Z"},
+		{"log4j", "2016-10-20 13:25:13,627 INFO [main] Application - started", "2016-10-20T13:25:13.627Z"},
+		{"auto", "Oct 20 13:25:13 host sshd[42]: accepted", "2016-10-20T13:25:13Z"},
+		{"syslog", "Dec 31 23:59:59 host cron[1]: done", "2016-12-31T23:59:59Z"},
+		{"auto", `10.0.0.1 - frank [20/Oct/2016:15:25:13 +0200] "GET / HTTP/1.1" 200 2326`, "2016-10-20T13:25:13Z"},
+		{"2006/01/02 15:04:05", "2016/10/20 13:25:13 listening", "2016-10-20T13:25:13Z"},
+	} {
+		parsed, ok := newTimestampParser(tt.format)(tt.line, now)
+		if assert.True(ok, tt.line) {
+			assert.Equal(tt.expected, parsed.UTC().Format(time.RFC3339Nano), tt.line)
+		}
+	}
+
+	for _, tt := range []struct {
+		format string
+		line   string
+	}{
+		{"auto", "listening on :8080"},
+		{"iso8601", "Oct 20 13:25:13 host sshd[42]: accepted"},
+		{"2006/01/02 15:04:05", "2016-10-20 13:25:13"},
+		{"2006/01/02 15:04:05", "short"},
+	} {
+		_, ok := newTimestampParser(tt.format)(tt.line, now)
+		assert.False(ok, tt.line)
+	}
+}
+
+func TestStreamParsesTimestamps(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:           new(router.Route),
+		conn:            conn,
+		timestamps:      true,
+		parseTimestamps: true,
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{}}
+	at := time.Date(2016, 10, 20, 13, 25, 14, 0, time.UTC)
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: `2016-10-20T13:25:13.627Z GET /`, Time: at}
+		logstream <- &router.Message{Container: &container, Data: `no time here`, Time: at}
+		logstream <- &router.Message{Container: &container, Data: `{"message":"2016-10-20T13:25:13Z json"}`, Time: at}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 3) {
+		assert.Equal("2016-10-20T13:25:13.627Z", lines[0]["@timestamp"])
+		assert.Equal("2016-10-20T13:25:14Z", lines[1]["@timestamp"])
+		assert.Equal("2016-10-20T13:25:14Z", lines[2]["@timestamp"])
+	}
+}
@@ -0,0 +1,43 @@
+package logstash
+
+import "unicode/utf8"
+
+// truncatedTag tags events whose line was cut short to fit the maximum
+// event size.
+const truncatedTag = "truncated"
+
+// truncate encodes e into d again, with its line cut short by as much as
+// the document exceeded the maximum event size, until it fits. Lines are cut
+// at character boundaries. If the metadata of the event alone exceeds the
+// limit, it is shipped with an empty message.
+func (a *LogstashAdapter) truncate(e *event, d *document) error {
+	line := e.line
+	e.origLength = len(line)
+	e.tags = append(e.tags[:len(e.tags):len(e.tags)], truncatedTag)
+
+	cut := len(line)
+	for {
+		cut = shorten(line, 0, cut, d.Len(), a.maxEventSize)
+		e.text = line[:cut]
+		d.reset()
+		if err := a.encode(e, d); err != nil || cut == 0 || d.Len() <= a.maxEventSize {
+			return err
+		}
+	}
+}
+
+// shorten returns where to cut line[start:end] short, at a character
+// boundary, for a document of size bytes holding it to fit max bytes. Every
+// byte cut off shortens the document by at least a byte, but characters
+// escaped in JSON by more, so the cut is at least proportional to the
+// excess; the document is encoded again to see whether it fits.
+func shorten(line string, start, end, size, max int) int {
+	cut := end - (size - max)
+	if scaled := start + (end-start)*max/size; scaled > cut {
+		cut = scaled
+	}
+	for cut > start && !utf8.RuneStart(line[cut]) {
+		cut--
+	}
+	return cut
+}
@@ -0,0 +1,58 @@
+package logstash
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamWithMaxEventSize(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:        new(router.Route),
+		conn:         conn,
+		maxEventSize: 300,
+		layout:       "flat",
+	}
+
+	container := docker.Container{ID: "ID", Name: "/web", Config: &docker.Config{Image: "nginx"}}
+	long := strings.Repeat("é", 400)
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: "short", Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: long, Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: `{"payload":"` + long + `"}`, Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	for _, line := range strings.Split(strings.TrimSpace(conn.buf.String()), "\n") {
+		assert.True(len(line) < 300, line)
+	}
+
+	lines := conn.Lines()
+	if assert.Len(lines, 3) {
+		assert.Equal("short", lines[0]["message"])
+		assert.Nil(lines[0]["original_length"])
+		assert.Equal([]interface{}{}, lines[0]["tags"])
+
+		message := lines[1]["message"].(string)
+		assert.True(strings.HasPrefix(long, message))
+		assert.True(utf8.ValidString(message))
+		assert.NotEmpty(message)
+		assert.Equal(800.0, lines[1]["original_length"])
+		assert.Equal([]interface{}{"truncated"}, lines[1]["tags"])
+
+		assert.True(strings.HasPrefix(lines[2]["message"].(string), `{"payload":"é`))
+		assert.Equal(814.0, lines[2]["original_length"])
+	}
+}
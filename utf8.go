@@ -0,0 +1,32 @@
+package logstash
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// sanitizeUTF8 repairs text that is not valid UTF-8, such as binary output
+// or text in another encoding. Every run of invalid bytes becomes a single
+// replacement character, or with hex set, every invalid byte is written as
+// a \xNN escape so that it can still be told apart.
+func sanitizeUTF8(s string, hex bool) string {
+	if !hex {
+		return strings.ToValidUTF8(s, string(utf8.RuneError))
+	}
+
+	const digits = "0123456789abcdef"
+	var b strings.Builder
+	b.Grow(len(s) + 8)
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b.WriteString(`\x`)
+			b.WriteByte(digits[s[i]>>4])
+			b.WriteByte(digits[s[i]&0xf])
+		} else {
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
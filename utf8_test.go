@@ -0,0 +1,59 @@
+package logstash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+	"github.com/gliderlabs/logspout/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeUTF8(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, c := range []struct {
+		text, replaced, escaped string
+	}{
+		{"h\xc3\xa9llo", "h\xc3\xa9llo", "h\xc3\xa9llo"},
+		{"caf\xe9 ok", "caf� ok", `caf\xe9 ok`},
+		{"\x00\xff\xfe\x01", "\x00�\x01", `` + "\x00" + `\xff\xfe` + "\x01"},
+		{"cut \xe2\x82", "cut �", `cut \xe2\x82`},
+	} {
+		assert.Equal(c.replaced, sanitizeUTF8(c.text, false), c.text)
+		assert.Equal(c.escaped, sanitizeUTF8(c.text, true), c.text)
+	}
+}
+
+func TestStreamWithInvalidUTF8(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &BufferConn{}
+	adapter := LogstashAdapter{
+		route:       new(router.Route),
+		conn:        conn,
+		utf8Escapes: true,
+	}
+
+	container := docker.Container{ID: "ID", Config: &docker.Config{}}
+
+	logstream := make(chan *router.Message)
+	go func() {
+		logstream <- &router.Message{Container: &container, Data: "caf\xe9", Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: "{\"name\":\"caf\xe9\"}", Time: time.Now()}
+		logstream <- &router.Message{Container: &container, Data: "café", Time: time.Now()}
+		close(logstream)
+	}()
+
+	adapter.Stream(logstream)
+
+	lines := conn.Lines()
+	if assert.Len(lines, 3) {
+		assert.Equal(`caf\xe9`, lines[0]["message"])
+		assert.Equal(true, lines[0]["invalid_utf8"])
+		assert.Equal("caf�", lines[1]["name"])
+		assert.Equal(true, lines[1]["invalid_utf8"])
+		assert.Equal("café", lines[2]["message"])
+		assert.Nil(lines[2]["invalid_utf8"])
+	}
+}
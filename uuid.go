@@ -0,0 +1,46 @@
+package logstash
+
+import (
+	"bufio"
+	"crypto/rand"
+	"io"
+	"sync"
+)
+
+// uuidSource buffers random bytes, so generating an event ID does not cost a
+// system call.
+var uuidSource = struct {
+	sync.Mutex
+	*bufio.Reader
+}{Reader: bufio.NewReaderSize(rand.Reader, 4096)}
+
+// randomBytes fills b with random bytes.
+func randomBytes(b []byte) {
+	uuidSource.Lock()
+	_, err := io.ReadFull(uuidSource.Reader, b)
+	uuidSource.Unlock()
+	if err != nil {
+		// crypto/rand does not fail on supported platforms.
+		panic(err)
+	}
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	var u [16]byte
+	randomBytes(u[:])
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+
+	var s [36]byte
+	j := 0
+	for i, b := range u {
+		if i == 4 || i == 6 || i == 8 || i == 10 {
+			s[j] = '-'
+			j++
+		}
+		s[j], s[j+1] = hexDigits[b>>4], hexDigits[b&0xf]
+		j += 2
+	}
+	return string(s[:])
+}
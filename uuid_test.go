@@ -0,0 +1,21 @@
+package logstash
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewUUID(t *testing.T) {
+	assert := assert.New(t)
+
+	v4 := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		u := newUUID()
+		assert.Regexp(v4, u)
+		assert.False(seen[u])
+		seen[u] = true
+	}
+}